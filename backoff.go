@@ -0,0 +1,68 @@
+package lndclient
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// defaultInitialBackoff is the default time we wait before the first
+	// attempt to re-establish a failed stream or registration.
+	defaultInitialBackoff = time.Second
+
+	// defaultMaxBackoff is the default upper bound of the time we wait
+	// between two attempts to re-establish a failed stream or
+	// registration.
+	defaultMaxBackoff = time.Minute
+)
+
+// backoff is the exponential backoff between the attempts to re-establish a
+// failed stream or registration. The first wait takes the initial backoff and
+// every further wait takes twice as long as the one before, up to the maximum
+// backoff.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+
+	// next is the duration of the next wait.
+	next time.Duration
+}
+
+// newBackoff creates a backoff with the given bounds. A zero initial or
+// maximum backoff is replaced with defaultInitialBackoff or defaultMaxBackoff.
+func newBackoff(initial, max time.Duration) *backoff {
+	if initial == 0 {
+		initial = defaultInitialBackoff
+	}
+	if max == 0 {
+		max = defaultMaxBackoff
+	}
+
+	return &backoff{
+		initial: initial,
+		max:     max,
+		next:    initial,
+	}
+}
+
+// wait blocks for the duration of the next wait and doubles it afterwards. It
+// returns false if the context was canceled while waiting.
+func (b *backoff) wait(ctx context.Context) bool {
+	select {
+	case <-time.After(b.next):
+	case <-ctx.Done():
+		return false
+	}
+
+	b.next *= 2
+	if b.next > b.max {
+		b.next = b.max
+	}
+
+	return true
+}
+
+// reset makes the next wait take the initial backoff again.
+func (b *backoff) reset() {
+	b.next = b.initial
+}
@@ -0,0 +1,37 @@
+package lndclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackoff tests that the wait time doubles up to the maximum, starts over
+// after a reset and that zero bounds are replaced with the defaults.
+func TestBackoff(t *testing.T) {
+	ctx := context.Background()
+
+	b := newBackoff(time.Millisecond, 3*time.Millisecond)
+	require.Equal(t, time.Millisecond, b.next)
+
+	require.True(t, b.wait(ctx))
+	require.Equal(t, 2*time.Millisecond, b.next)
+
+	require.True(t, b.wait(ctx))
+	require.Equal(t, 3*time.Millisecond, b.next)
+
+	b.reset()
+	require.Equal(t, time.Millisecond, b.next)
+
+	b = newBackoff(0, 0)
+	require.Equal(t, defaultInitialBackoff, b.next)
+	require.Equal(t, defaultMaxBackoff, b.max)
+
+	// A canceled context ends the wait without growing the backoff.
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.False(t, b.wait(ctx))
+	require.Equal(t, defaultInitialBackoff, b.next)
+}
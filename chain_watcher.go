@@ -0,0 +1,679 @@
+package lndclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/kvdb"
+)
+
+// defaultReorgSafetyDepth is the default number of blocks after which a
+// confirmation or spend is considered final.
+const defaultReorgSafetyDepth = 6
+
+var (
+	// ErrWatchExists is returned when a watch is added with an ID that
+	// is already in use.
+	ErrWatchExists = errors.New("watch already exists")
+
+	// ErrWatchNotFound is returned when a watch that doesn't exist is
+	// removed.
+	ErrWatchNotFound = errors.New("watch not found")
+
+	// ErrChainWatcherStopped is returned when a watch is added to a chain
+	// watcher that isn't running.
+	ErrChainWatcherStopped = errors.New("chain watcher not running")
+
+	// watchBucket is the name of the bucket that stores the persisted
+	// watch requests.
+	watchBucket = []byte("chain-watches")
+)
+
+// WatchEventType is the type of an event emitted by the ChainWatcher.
+type WatchEventType uint8
+
+const (
+	// WatchEventConfirmed indicates that the transaction of a watch
+	// reached the requested number of confirmations.
+	WatchEventConfirmed WatchEventType = iota
+
+	// WatchEventReorged indicates that the confirmed or spending
+	// transaction of a watch was re-organized out of the chain.
+	WatchEventReorged
+
+	// WatchEventSpent indicates that the outpoint or script of a watch was
+	// spent.
+	WatchEventSpent
+
+	// WatchEventError indicates that the notification of a watch failed
+	// with a terminal error. No further events are emitted for the watch.
+	WatchEventError
+
+	// WatchEventDone indicates that the confirmation or spend of a watch
+	// is buried deep enough to be considered final. No further events are
+	// emitted for the watch and it is removed from the store.
+	WatchEventDone
+)
+
+// String returns a human readable representation of the event type.
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchEventConfirmed:
+		return "Confirmed"
+
+	case WatchEventReorged:
+		return "Reorged"
+
+	case WatchEventSpent:
+		return "Spent"
+
+	case WatchEventError:
+		return "Error"
+
+	case WatchEventDone:
+		return "Done"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// WatchRequest describes a single item tracked by the ChainWatcher. A watch
+// either tracks the confirmation of a transaction (Txid and/or PkScript set)
+// or the spend of an output (Outpoint and/or PkScript set with Spend set to
+// true).
+type WatchRequest struct {
+	// ID is the caller chosen unique identifier of the watch. It is part of
+	// every event emitted for the watch.
+	ID string
+
+	// Spend indicates whether the spend of an output should be tracked
+	// instead of the confirmation of a transaction.
+	Spend bool
+
+	// Txid is the hash of the transaction to track the confirmation of.
+	// It may be nil for script-only confirmation watches.
+	Txid *chainhash.Hash
+
+	// Outpoint is the output to track the spend of. It may be nil for
+	// script-only spend watches.
+	Outpoint *wire.OutPoint
+
+	// PkScript is the output script of the transaction or output.
+	PkScript []byte
+
+	// NumConfs is the number of confirmations required for a confirmation
+	// watch.
+	NumConfs int32
+
+	// HeightHint is the height from which lnd starts looking for the
+	// confirmation or spend.
+	HeightHint int32
+}
+
+// WatchEvent is a single event emitted by the ChainWatcher.
+type WatchEvent struct {
+	// ID is the identifier of the watch the event belongs to.
+	ID string
+
+	// Type is the type of the event.
+	Type WatchEventType
+
+	// Conf is the confirmation of the transaction, set for confirmed
+	// events.
+	Conf *chainntnfs.TxConfirmation
+
+	// Spend is the spend of the output, set for spent events.
+	Spend *chainntnfs.SpendDetail
+
+	// Err is the error that terminated the watch, set for error events.
+	Err error
+}
+
+// WatchStore persists the watch requests of a ChainWatcher so they can be
+// registered again after a restart.
+type WatchStore interface {
+	// AddWatch persists the given watch request.
+	AddWatch(req *WatchRequest) error
+
+	// RemoveWatch removes the watch request with the given ID.
+	RemoveWatch(id string) error
+
+	// ListWatches returns all persisted watch requests.
+	ListWatches() ([]*WatchRequest, error)
+}
+
+// boltWatchStore is a WatchStore that persists all watch requests in a kvdb
+// backend.
+type boltWatchStore struct {
+	db kvdb.Backend
+}
+
+// A compile-time constraint to ensure boltWatchStore satisfies the WatchStore
+// interface.
+var _ WatchStore = (*boltWatchStore)(nil)
+
+// NewBoltWatchStore returns a WatchStore that persists all watch requests in
+// the given database. The caller remains responsible for closing the
+// database.
+func NewBoltWatchStore(db kvdb.Backend) (WatchStore, error) {
+	err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		_, err := tx.CreateTopLevelBucket(watchBucket)
+		return err
+	}, func() {})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltWatchStore{
+		db: db,
+	}, nil
+}
+
+// AddWatch persists the given watch request.
+func (b *boltWatchStore) AddWatch(req *WatchRequest) error {
+	value, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(watchBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.Put([]byte(req.ID), value)
+	}, func() {})
+}
+
+// RemoveWatch removes the watch request with the given ID.
+func (b *boltWatchStore) RemoveWatch(id string) error {
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(watchBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.Delete([]byte(id))
+	}, func() {})
+}
+
+// ListWatches returns all persisted watch requests.
+func (b *boltWatchStore) ListWatches() ([]*WatchRequest, error) {
+	var reqs []*WatchRequest
+	err := kvdb.View(b.db, func(tx kvdb.RTx) error {
+		bucket := tx.ReadBucket(watchBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			req := &WatchRequest{}
+			if err := json.Unmarshal(value, req); err != nil {
+				return err
+			}
+
+			reqs = append(reqs, req)
+			return nil
+		})
+	}, func() {
+		reqs = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reqs, nil
+}
+
+// ChainWatcherConfig holds the configuration of a ChainWatcher.
+type ChainWatcherConfig struct {
+	// ChainNotifier is the client used to register the notifications of
+	// all watches.
+	ChainNotifier ChainNotifierClient
+
+	// Store is an optional store the watch requests are persisted in. If
+	// it is nil, the watches are only kept in memory.
+	Store WatchStore
+
+	// EventBufferSize is the size of the buffer of the event channel.
+	EventBufferSize int
+
+	// ReorgSafetyDepth is the number of blocks that must be mined on top
+	// of the block a watch reached its required confirmations or its spend
+	// in before the watch is done. If zero, a default of 6 is used.
+	ReorgSafetyDepth int32
+}
+
+// ChainWatcher tracks an arbitrary set of transactions, outputs and scripts
+// on chain and emits all their confirmation, re-org and spend events on a
+// single channel, in the order they are received from lnd. Watches are
+// registered in resilient mode, so they survive restarts of lnd. If a store is
+// configured, the watches also survive restarts of the ChainWatcher itself.
+// Once the confirmation or spend of a watch is buried under the re-org safety
+// depth, a done event is emitted and the watch is removed.
+type ChainWatcher struct {
+	cfg *ChainWatcherConfig
+
+	events chan *WatchEvent
+
+	// ctx is the context all watches are registered with. It is canceled
+	// when the watcher is stopped.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// watches holds every active watch by its ID.
+	watches map[string]*activeWatch
+
+	// height is the height of the best block we know of.
+	height int32
+
+	mu sync.Mutex
+
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// activeWatch is a watch whose notification is registered with lnd.
+type activeWatch struct {
+	// cancel cancels the notification of the watch.
+	cancel func()
+
+	// blocks is signaled whenever a new block arrives.
+	blocks chan struct{}
+}
+
+// NewChainWatcher creates a new chain watcher. Start must be called before
+// any watches can be added.
+func NewChainWatcher(cfg *ChainWatcherConfig) *ChainWatcher {
+	if cfg.ReorgSafetyDepth == 0 {
+		cfg.ReorgSafetyDepth = defaultReorgSafetyDepth
+	}
+
+	return &ChainWatcher{
+		cfg:     cfg,
+		events:  make(chan *WatchEvent, cfg.EventBufferSize),
+		watches: make(map[string]*activeWatch),
+	}
+}
+
+// Start starts the chain watcher and registers all watches found in the
+// store. The watcher runs until Stop is called or the given context is
+// canceled.
+func (w *ChainWatcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ctx != nil {
+		return errors.New("chain watcher already started")
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+
+	// We follow the chain tip to find out when watches are done. Only the
+	// latest block matters, so we never stall the block stream.
+	notifier := w.cfg.ChainNotifier
+	blockChan, blockErrChan, _, err := notifier.RegisterBlockEpochNtfn(
+		w.ctx, WithLatestBlockOnly(),
+		WithResubscribe(defaultInitialBackoff, defaultMaxBackoff),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to register blocks: %v", err)
+	}
+
+	w.wg.Add(1)
+	go w.trackBlocks(blockChan, blockErrChan)
+
+	if w.cfg.Store == nil {
+		return nil
+	}
+
+	reqs, err := w.cfg.Store.ListWatches()
+	if err != nil {
+		return fmt.Errorf("unable to list watches: %v", err)
+	}
+
+	for _, req := range reqs {
+		if err := w.registerWatch(req); err != nil {
+			return fmt.Errorf("unable to register watch %v: %v",
+				req.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Stop cancels all watches and waits for their goroutines to exit. The watches
+// stay persisted in the store. The event channel is closed once all goroutines
+// have exited.
+func (w *ChainWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.mu.Lock()
+		if w.cancel != nil {
+			w.cancel()
+		}
+		w.mu.Unlock()
+
+		w.wg.Wait()
+		close(w.events)
+	})
+}
+
+// Events returns the channel on which all watch events are emitted.
+func (w *ChainWatcher) Events() <-chan *WatchEvent {
+	return w.events
+}
+
+// Watch adds a new watch to the chain watcher, persisting it in the store if
+// one is configured.
+func (w *ChainWatcher) Watch(req *WatchRequest) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ctx == nil || w.ctx.Err() != nil {
+		return ErrChainWatcherStopped
+	}
+
+	if _, ok := w.watches[req.ID]; ok {
+		return ErrWatchExists
+	}
+
+	if w.cfg.Store != nil {
+		if err := w.cfg.Store.AddWatch(req); err != nil {
+			return err
+		}
+	}
+
+	// A watch that can't be registered is removed from the store again,
+	// otherwise it would fail every future start of the watcher.
+	err := w.registerWatch(req)
+	if err != nil && w.cfg.Store != nil {
+		if err := w.cfg.Store.RemoveWatch(req.ID); err != nil {
+			log.Errorf("Unable to remove watch %v: %v", req.ID,
+				err)
+		}
+	}
+
+	return err
+}
+
+// Unwatch cancels the watch with the given ID and removes it from the store.
+func (w *ChainWatcher) Unwatch(id string) error {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	delete(w.watches, id)
+	w.mu.Unlock()
+
+	if !ok {
+		return ErrWatchNotFound
+	}
+
+	// We cancel outside of the mutex as this waits for the goroutine of
+	// the watch, which might be blocked on delivering an event.
+	watch.cancel()
+
+	if w.cfg.Store != nil {
+		return w.cfg.Store.RemoveWatch(id)
+	}
+
+	return nil
+}
+
+// registerWatch registers the notification of the given watch with lnd and
+// starts forwarding its events. The caller must hold the mutex.
+func (w *ChainWatcher) registerWatch(req *WatchRequest) error {
+	notifier := w.cfg.ChainNotifier
+	resubscribe := WithResubscribe(defaultInitialBackoff, defaultMaxBackoff)
+
+	reOrgChan := make(chan struct{})
+	watch := &activeWatch{
+		blocks: make(chan struct{}, 1),
+	}
+
+	if req.Spend {
+		spendChan, errChan, cancel, err := notifier.RegisterSpendNtfn(
+			w.ctx, req.Outpoint, req.PkScript, req.HeightHint,
+			WithReOrgChan(reOrgChan), resubscribe,
+		)
+		if err != nil {
+			return err
+		}
+		watch.cancel = cancel
+		w.watches[req.ID] = watch
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+
+			// finalHeight is the height at which the spend is
+			// final, zero while the output is unspent.
+			var finalHeight int32
+			for {
+				select {
+				case spend, ok := <-spendChan:
+					if !ok {
+						w.forwardErr(req.ID, errChan)
+						return
+					}
+
+					w.sendEvent(&WatchEvent{
+						ID:    req.ID,
+						Type:  WatchEventSpent,
+						Spend: spend,
+					})
+
+					finalHeight = spend.SpendingHeight +
+						w.cfg.ReorgSafetyDepth
+
+				case <-reOrgChan:
+					w.sendEvent(&WatchEvent{
+						ID:   req.ID,
+						Type: WatchEventReorged,
+					})
+
+					finalHeight = 0
+					continue
+
+				case <-watch.blocks:
+
+				case err := <-errChan:
+					w.sendErr(req.ID, err)
+					return
+				}
+
+				if w.isFinal(finalHeight) {
+					w.finish(req.ID)
+					return
+				}
+			}
+		}()
+
+		return nil
+	}
+
+	confChan, errChan, cancel, err := notifier.RegisterConfirmationsNtfn(
+		w.ctx, req.Txid, req.PkScript, req.NumConfs, req.HeightHint,
+		WithReOrgChan(reOrgChan), resubscribe,
+	)
+	if err != nil {
+		return err
+	}
+	watch.cancel = cancel
+	w.watches[req.ID] = watch
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		// finalHeight is the height at which the confirmation is final,
+		// zero while the transaction isn't confirmed.
+		var finalHeight int32
+		for {
+			select {
+			case conf, ok := <-confChan:
+				if !ok {
+					w.forwardErr(req.ID, errChan)
+					return
+				}
+
+				w.sendEvent(&WatchEvent{
+					ID:   req.ID,
+					Type: WatchEventConfirmed,
+					Conf: conf,
+				})
+
+				// The confirmation is delivered once the block
+				// of the transaction has numConfs - 1 blocks on
+				// top of it.
+				confHeight := int32(conf.BlockHeight) +
+					req.NumConfs - 1
+				finalHeight = confHeight +
+					w.cfg.ReorgSafetyDepth
+
+			case <-reOrgChan:
+				w.sendEvent(&WatchEvent{
+					ID:   req.ID,
+					Type: WatchEventReorged,
+				})
+
+				finalHeight = 0
+				continue
+
+			case <-watch.blocks:
+
+			case err := <-errChan:
+				w.sendErr(req.ID, err)
+				return
+			}
+
+			if w.isFinal(finalHeight) {
+				w.finish(req.ID)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// trackBlocks keeps track of the best block and signals all watches for every
+// new block, until the block stream ends.
+func (w *ChainWatcher) trackBlocks(blockChan chan *BlockEpoch,
+	errChan chan error) {
+
+	defer w.wg.Done()
+
+	for {
+		select {
+		case block, ok := <-blockChan:
+			if !ok {
+				return
+			}
+
+			w.mu.Lock()
+			w.height = block.Height
+			for _, watch := range w.watches {
+				select {
+				case watch.blocks <- struct{}{}:
+				default:
+				}
+			}
+			w.mu.Unlock()
+
+		case err := <-errChan:
+			if w.ctx.Err() == nil {
+				log.Errorf("Chain watcher block "+
+					"notifications failed, watches won't "+
+					"be done: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// isFinal returns whether the best block has reached the given final height
+// of a watch. A final height of zero is never reached.
+func (w *ChainWatcher) isFinal(finalHeight int32) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return finalHeight != 0 && w.height >= finalHeight
+}
+
+// finish emits the done event of a watch, removes it from the store and
+// cancels its notification. It must be called from the goroutine of the
+// watch.
+func (w *ChainWatcher) finish(id string) {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	delete(w.watches, id)
+	w.mu.Unlock()
+
+	// The watch was removed in the meantime.
+	if !ok {
+		return
+	}
+
+	defer watch.cancel()
+
+	// The watch is only removed from the store once the event was
+	// emitted, so that it is emitted again after a restart otherwise.
+	done := w.sendEvent(&WatchEvent{
+		ID:   id,
+		Type: WatchEventDone,
+	})
+	if !done || w.cfg.Store == nil {
+		return
+	}
+
+	if err := w.cfg.Store.RemoveWatch(id); err != nil {
+		log.Errorf("Unable to remove watch %v: %v", id, err)
+	}
+}
+
+// forwardErr is called once the event channel of a watch was closed and
+// forwards the error that caused it, if there is one.
+func (w *ChainWatcher) forwardErr(id string, errChan chan error) {
+	select {
+	case err := <-errChan:
+		w.sendErr(id, err)
+
+	default:
+	}
+}
+
+// sendErr emits an error event for the given watch, unless the error was
+// caused by the watch or the watcher being canceled.
+func (w *ChainWatcher) sendErr(id string, err error) {
+	w.mu.Lock()
+	_, active := w.watches[id]
+	w.mu.Unlock()
+
+	if !active || w.ctx.Err() != nil {
+		return
+	}
+
+	w.sendEvent(&WatchEvent{
+		ID:   id,
+		Type: WatchEventError,
+		Err:  err,
+	})
+}
+
+// sendEvent emits the given event, giving up if the watcher is stopped. It
+// returns whether the event was emitted.
+func (w *ChainWatcher) sendEvent(event *WatchEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+
+	case <-w.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,454 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/stretchr/testify/require"
+)
+
+// confRegistration is a confirmation notification registered with the mock
+// chain notifier. The confirmations sent by the test are forwarded to the
+// watcher until the registration is canceled.
+type confRegistration struct {
+	txid      *chainhash.Hash
+	confs     chan *chainntnfs.TxConfirmation
+	reOrgChan chan struct{}
+	canceled  chan struct{}
+}
+
+// mockWatchNotifier is a chain notifier that hands all confirmation
+// registrations to the test and delivers the blocks sent by the test.
+type mockWatchNotifier struct {
+	ChainNotifierClient
+
+	blocks        chan *BlockEpoch
+	registrations chan *confRegistration
+
+	// registerErr is returned by all confirmation registrations if set.
+	registerErr error
+}
+
+func newMockWatchNotifier() *mockWatchNotifier {
+	return &mockWatchNotifier{
+		blocks:        make(chan *BlockEpoch),
+		registrations: make(chan *confRegistration, 10),
+	}
+}
+
+func (m *mockWatchNotifier) RegisterBlockEpochNtfn(ctx context.Context,
+	_ ...NotifierOption) (chan *BlockEpoch, chan error, func(), error) {
+
+	blockChan := make(chan *BlockEpoch)
+	go func() {
+		defer close(blockChan)
+
+		for {
+			select {
+			case block := <-m.blocks:
+				select {
+				case blockChan <- block:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return blockChan, make(chan error, 1), func() {}, nil
+}
+
+func (m *mockWatchNotifier) RegisterConfirmationsNtfn(ctx context.Context,
+	txid *chainhash.Hash, _ []byte, _, _ int32,
+	optFuncs ...NotifierOption) (chan *chainntnfs.TxConfirmation,
+	chan error, func(), error) {
+
+	if m.registerErr != nil {
+		return nil, nil, nil, m.registerErr
+	}
+
+	opts := defaultNotifierOptions()
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+
+	reg := &confRegistration{
+		txid:      txid,
+		confs:     make(chan *chainntnfs.TxConfirmation),
+		reOrgChan: opts.ReOrgChan,
+		canceled:  make(chan struct{}),
+	}
+	m.registrations <- reg
+
+	ctx, cancel := context.WithCancel(ctx)
+	confChan := make(chan *chainntnfs.TxConfirmation)
+	go func() {
+		defer close(reg.canceled)
+		defer close(confChan)
+
+		for {
+			select {
+			case conf := <-reg.confs:
+				select {
+				case confChan <- conf:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return confChan, make(chan error, 1), cancel, nil
+}
+
+func (m *mockWatchNotifier) nextRegistration(
+	t *testing.T) *confRegistration {
+
+	select {
+	case reg := <-m.registrations:
+		return reg
+
+	case <-time.After(testTimeout):
+		t.Fatal("no confirmation registered")
+		return nil
+	}
+}
+
+func (m *mockWatchNotifier) sendBlock(t *testing.T, height int32) {
+	select {
+	case m.blocks <- &BlockEpoch{Height: height}:
+	case <-time.After(testTimeout):
+		t.Fatal("block not consumed")
+	}
+}
+
+func (r *confRegistration) confirm(t *testing.T, height uint32) {
+	select {
+	case r.confs <- &chainntnfs.TxConfirmation{BlockHeight: height}:
+	case <-time.After(testTimeout):
+		t.Fatal("confirmation not consumed")
+	}
+}
+
+func (r *confRegistration) reorg(t *testing.T) {
+	select {
+	case r.reOrgChan <- struct{}{}:
+	case <-time.After(testTimeout):
+		t.Fatal("re-org not consumed")
+	}
+}
+
+func nextWatchEvent(t *testing.T, watcher *ChainWatcher) *WatchEvent {
+	select {
+	case event := <-watcher.Events():
+		return event
+
+	case <-time.After(testTimeout):
+		t.Fatal("no watch event")
+		return nil
+	}
+}
+
+func newTestWatchStore(t *testing.T) (WatchStore, func()) {
+	tempDirPath, err := ioutil.TempDir("", ".testChainWatches")
+	require.NoError(t, err)
+
+	db, err := kvdb.GetBoltBackend(&kvdb.BoltBackendConfig{
+		DBPath:     tempDirPath,
+		DBFileName: "watches.db",
+		DBTimeout:  defaultDBTimeout,
+	})
+	require.NoError(t, err)
+
+	store, err := NewBoltWatchStore(db)
+	require.NoError(t, err)
+
+	return store, func() {
+		require.NoError(t, db.Close())
+		require.NoError(t, os.RemoveAll(tempDirPath))
+	}
+}
+
+// TestChainWatcherDone tests that a watch is done once its confirmation is
+// buried under the re-org safety depth, and that it is removed from the store
+// afterwards.
+func TestChainWatcherDone(t *testing.T) {
+	store, cleanup := newTestWatchStore(t)
+	defer cleanup()
+
+	notifier := newMockWatchNotifier()
+	watcher := NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier:    notifier,
+		Store:            store,
+		ReorgSafetyDepth: 2,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	notifier.sendBlock(t, 100)
+
+	txid := chainhash.Hash{1}
+	require.NoError(t, watcher.Watch(&WatchRequest{
+		ID:       "tx",
+		Txid:     &txid,
+		NumConfs: 3,
+	}))
+	reg := notifier.nextRegistration(t)
+
+	// The transaction confirms in block 100 and reaches three
+	// confirmations at height 102, so it is final at height 104.
+	reg.confirm(t, 100)
+	event := nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventConfirmed, event.Type)
+	require.Equal(t, "tx", event.ID)
+
+	notifier.sendBlock(t, 102)
+	notifier.sendBlock(t, 103)
+	notifier.sendBlock(t, 104)
+
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventDone, event.Type)
+	require.Equal(t, "tx", event.ID)
+
+	select {
+	case <-reg.canceled:
+	case <-time.After(testTimeout):
+		t.Fatal("notification not canceled")
+	}
+
+	watches, err := store.ListWatches()
+	require.NoError(t, err)
+	require.Empty(t, watches)
+
+	require.Equal(t, ErrWatchNotFound, watcher.Unwatch("tx"))
+}
+
+// TestChainWatcherRegisterFailure tests that a watch whose notification can't
+// be registered isn't kept in the store.
+func TestChainWatcherRegisterFailure(t *testing.T) {
+	store, cleanup := newTestWatchStore(t)
+	defer cleanup()
+
+	notifier := newMockWatchNotifier()
+	watcher := NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier: notifier,
+		Store:         store,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	notifier.sendBlock(t, 100)
+
+	registerErr := errors.New("notifier unavailable")
+	notifier.registerErr = registerErr
+
+	txid := chainhash.Hash{1}
+	req := &WatchRequest{
+		ID:       "tx",
+		Txid:     &txid,
+		NumConfs: 3,
+	}
+	require.Equal(t, registerErr, watcher.Watch(req))
+
+	watches, err := store.ListWatches()
+	require.NoError(t, err)
+	require.Empty(t, watches)
+
+	// The watch can be added again once the notifier is available.
+	notifier.registerErr = nil
+	require.NoError(t, watcher.Watch(req))
+	notifier.nextRegistration(t)
+
+	watches, err = store.ListWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 1)
+}
+
+// TestChainWatcherResume tests that watches are registered again after a
+// restart and are done right away if their confirmation is already final.
+func TestChainWatcherResume(t *testing.T) {
+	store, cleanup := newTestWatchStore(t)
+	defer cleanup()
+
+	notifier := newMockWatchNotifier()
+	watcher := NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier:    notifier,
+		Store:            store,
+		ReorgSafetyDepth: 2,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+
+	txid := chainhash.Hash{2}
+	require.NoError(t, watcher.Watch(&WatchRequest{
+		ID:       "tx",
+		Txid:     &txid,
+		NumConfs: 1,
+	}))
+	notifier.nextRegistration(t)
+
+	// The watch stays in the store when the watcher is stopped before it
+	// is done.
+	watcher.Stop()
+
+	watches, err := store.ListWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 1)
+
+	notifier = newMockWatchNotifier()
+	watcher = NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier:    notifier,
+		Store:            store,
+		ReorgSafetyDepth: 2,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	reg := notifier.nextRegistration(t)
+	require.Equal(t, txid, *reg.txid)
+
+	// The transaction confirmed long ago, so the watch is done as soon as
+	// we learn about the chain tip.
+	notifier.sendBlock(t, 200)
+	reg.confirm(t, 100)
+
+	event := nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventConfirmed, event.Type)
+
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventDone, event.Type)
+
+	// Stop waits for the watch to be removed.
+	watcher.Stop()
+
+	watches, err = store.ListWatches()
+	require.NoError(t, err)
+	require.Empty(t, watches)
+}
+
+// TestChainWatcherReorg tests that a watch isn't done if its confirmation is
+// re-organized out of the chain before it is final.
+func TestChainWatcherReorg(t *testing.T) {
+	notifier := newMockWatchNotifier()
+	watcher := NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier:    notifier,
+		ReorgSafetyDepth: 2,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	notifier.sendBlock(t, 100)
+
+	txid := chainhash.Hash{3}
+	require.NoError(t, watcher.Watch(&WatchRequest{
+		ID:       "tx",
+		Txid:     &txid,
+		NumConfs: 1,
+	}))
+	reg := notifier.nextRegistration(t)
+
+	reg.confirm(t, 100)
+	event := nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventConfirmed, event.Type)
+
+	reg.reorg(t)
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventReorged, event.Type)
+
+	// The original confirmation would be final at height 102, but it was
+	// re-organized out, so the watch isn't done.
+	notifier.sendBlock(t, 110)
+
+	reg.confirm(t, 120)
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventConfirmed, event.Type)
+
+	notifier.sendBlock(t, 121)
+	notifier.sendBlock(t, 122)
+
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventDone, event.Type)
+}
+
+// TestChainWatcherSpendDone tests that a spend watch is done once its spend is
+// final.
+func TestChainWatcherSpendDone(t *testing.T) {
+	spendChan := make(chan *chainntnfs.SpendDetail)
+	notifier := &mockSpendNotifier{
+		mockWatchNotifier: newMockWatchNotifier(),
+		spendChan:         spendChan,
+	}
+
+	watcher := NewChainWatcher(&ChainWatcherConfig{
+		ChainNotifier:    notifier,
+		ReorgSafetyDepth: 2,
+	})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	notifier.sendBlock(t, 100)
+
+	require.NoError(t, watcher.Watch(&WatchRequest{
+		ID:       "output",
+		Spend:    true,
+		Outpoint: &wire.OutPoint{Index: 1},
+	}))
+
+	spendChan <- &chainntnfs.SpendDetail{SpendingHeight: 101}
+	event := nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventSpent, event.Type)
+
+	notifier.sendBlock(t, 103)
+
+	event = nextWatchEvent(t, watcher)
+	require.Equal(t, WatchEventDone, event.Type)
+	require.Equal(t, "output", event.ID)
+}
+
+// mockSpendNotifier is a mock chain notifier that delivers the spends sent on
+// its spend channel.
+type mockSpendNotifier struct {
+	*mockWatchNotifier
+
+	spendChan chan *chainntnfs.SpendDetail
+}
+
+func (m *mockSpendNotifier) RegisterSpendNtfn(ctx context.Context,
+	_ *wire.OutPoint, _ []byte, _ int32, _ ...NotifierOption) (
+	chan *chainntnfs.SpendDetail, chan error, func(), error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	spendChan := make(chan *chainntnfs.SpendDetail)
+	go func() {
+		defer close(spendChan)
+
+		for {
+			select {
+			case spend := <-m.spendChan:
+				select {
+				case spendChan <- spend:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return spendChan, make(chan error, 1), cancel, nil
+}
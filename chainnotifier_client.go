@@ -2,29 +2,356 @@ package lndclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+var (
+	// ErrStreamClosed is returned on the error channel of a notification
+	// if lnd closed its stream or the connection to lnd was lost.
+	ErrStreamClosed = errors.New("notification stream closed")
+
+	// ErrPermissionDenied is returned on the error channel of a
+	// notification if the macaroon used doesn't grant access to the chain
+	// notifier.
+	ErrPermissionDenied = errors.New("chain notifier permission denied")
+
+	// ErrLndShuttingDown is returned on the error channel of a
+	// notification if the chain notifier of lnd is shutting down.
+	ErrLndShuttingDown = errors.New("lnd chain notifier shutting down")
+
+	// ErrChainNotifierStopped is returned when a notification is
+	// registered after the chain notifier client was stopped.
+	ErrChainNotifierStopped = errors.New("chain notifier client stopped")
+
+	// ErrEmptyPkScript is returned when a script-only notification is
+	// registered without a pkScript.
+	ErrEmptyPkScript = errors.New("pkScript must be set")
+
+	// ErrInvalidTaprootScript is returned when a script-only notification
+	// is registered for a version 1 witness program that doesn't have the
+	// 32 byte program of a taproot output.
+	ErrInvalidTaprootScript = errors.New("version 1 witness program must " +
+		"be 32 bytes")
+)
+
+const (
+	// taprootWitnessProgramLen is the length of the witness program of a
+	// version 1 (taproot) output.
+	taprootWitnessProgramLen = 32
+)
+
+// classifyStreamErr wraps the raw error returned by a notification stream into
+// one of the typed notifier errors, if it can be classified. The original
+// error is kept as part of the message.
+func classifyStreamErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+
+	case err == io.EOF:
+		return ErrStreamClosed
+	}
+
+	rpcStatus, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch {
+	case rpcStatus.Code() == codes.PermissionDenied,
+		rpcStatus.Code() == codes.Unauthenticated:
+
+		return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+
+	// The chain notifier sub-server doesn't use a specific code when it
+	// shuts down, so we need to look at the message instead.
+	case strings.Contains(rpcStatus.Message(), "shutting down"):
+		return fmt.Errorf("%w: %v", ErrLndShuttingDown, err)
+
+	case rpcStatus.Code() == codes.Unavailable:
+		return fmt.Errorf("%w: %v", ErrStreamClosed, err)
+
+	default:
+		return err
+	}
+}
+
+// IsTerminal returns true if the given error, as delivered on the error
+// channel of a notification, can't be resolved by registering the
+// notification again. Errors caused by a closed stream or a restarting lnd are
+// not terminal.
+func IsTerminal(err error) bool {
+	switch {
+	case err == nil:
+		return false
+
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+
+		return true
+
+	case errors.Is(err, ErrStreamClosed),
+		errors.Is(err, ErrLndShuttingDown):
+
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.PermissionDenied,
+		codes.Unauthenticated, codes.Unimplemented,
+		codes.InvalidArgument:
+
+		return true
+
+	default:
+		return false
+	}
+}
+
+// NotifierOptions is a set of options that allow callers to further modify
+// the type of chain event notifications they receive.
+type NotifierOptions struct {
+	// ReOrgChan if set, will be sent on if the transaction is re-organized
+	// out of the chain. For spend notifications, this means the spending
+	// transaction was re-organized out and the output is unspent again.
+	// This channel being set will also imply that we don't cancel the
+	// notification listener after having received one confirmation or
+	// spend event. That means the caller manually needs to cancel the
+	// passed in context to cancel being notified once the required number
+	// of confirmations have been reached or the spend is final.
+	ReOrgChan chan struct{}
+
+	// ProgressChan if set, will receive an update for every block that
+	// increases (or, in case of a re-org, resets) the number of
+	// confirmations of a transaction until the requested number of
+	// confirmations is reached.
+	ProgressChan chan *ConfProgress
+
+	// Resubscribe if set, will cause the notification to be transparently
+	// re-registered with lnd if its stream fails, instead of the error
+	// being delivered on the error channel. Only the cancellation of the
+	// passed in context terminates a resilient notification.
+	Resubscribe bool
+
+	// InitialBackoff is the time we wait before the first attempt to
+	// re-register a failed notification.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time we wait between two attempts to
+	// re-register a failed notification.
+	MaxBackoff time.Duration
+
+	// BlockEpochBuffer is the buffer size of the block epoch channel. By
+	// default the channel is unbuffered.
+	BlockEpochBuffer int
+
+	// CoalesceBlocks if set, will cause the block epoch channel to only
+	// hold the latest block. If the caller doesn't read a block before the
+	// next one arrives, the older block is dropped instead of stalling
+	// the stream.
+	CoalesceBlocks bool
+}
+
+// defaultNotifierOptions returns the set of default options for the notifier.
+func defaultNotifierOptions() *NotifierOptions {
+	return &NotifierOptions{
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+	}
+}
+
+// NotifierOption is a functional option that allows a caller to modify the
+// events received from the notifier.
+type NotifierOption func(*NotifierOptions)
+
+// WithReOrgChan configures a channel that will be sent on if the transaction is
+// re-organized out of the chain. This channel being set will also imply that we
+// don't cancel the notification listener after having received one
+// confirmation event. That means the caller manually needs to cancel the
+// passed in context to cancel being notified once the required number of
+// confirmations have been reached.
+func WithReOrgChan(reOrgChan chan struct{}) NotifierOption {
+	return func(o *NotifierOptions) {
+		o.ReOrgChan = reOrgChan
+	}
+}
+
+// WithConfProgress configures a channel that receives the intermediate
+// confirmation count of a transaction for every new block, until the requested
+// number of confirmations is reached.
+func WithConfProgress(progressChan chan *ConfProgress) NotifierOption {
+	return func(o *NotifierOptions) {
+		o.ProgressChan = progressChan
+	}
+}
+
+// WithBlockEpochBuffer configures the buffer size of the block epoch channel,
+// so a slow consumer doesn't immediately stall the notification stream.
+func WithBlockEpochBuffer(size int) NotifierOption {
+	return func(o *NotifierOptions) {
+		o.BlockEpochBuffer = size
+	}
+}
+
+// WithLatestBlockOnly configures the block epoch channel to only hold the
+// latest block. Blocks that aren't read by the caller before the next one
+// arrives are dropped, so the caller always sees the current chain tip but not
+// necessarily every block.
+func WithLatestBlockOnly() NotifierOption {
+	return func(o *NotifierOptions) {
+		o.CoalesceBlocks = true
+	}
+}
+
+// WithResubscribe configures the notification to be transparently
+// re-registered if its stream fails, for example because lnd restarted. The
+// registration is retried with an exponential backoff starting at
+// initialBackoff and capped at maxBackoff.
+func WithResubscribe(initialBackoff, maxBackoff time.Duration) NotifierOption {
+	return func(o *NotifierOptions) {
+		o.Resubscribe = true
+		o.InitialBackoff = initialBackoff
+		o.MaxBackoff = maxBackoff
+	}
+}
+
+// reRegister calls the given register function until it succeeds, waiting
+// with an exponential backoff between the attempts. An error is only returned
+// if the context is canceled or the registration fails with a terminal error.
+func (o *NotifierOptions) reRegister(ctx context.Context,
+	register func() error) error {
+
+	b := newBackoff(o.InitialBackoff, o.MaxBackoff)
+	for {
+		if !b.wait(ctx) {
+			return ctx.Err()
+		}
+
+		err := register()
+		if err == nil {
+			return nil
+		}
+		if IsTerminal(err) {
+			return classifyStreamErr(err)
+		}
+
+		log.Warnf("Unable to re-register chain notification, "+
+			"retrying in %v: %v", b.next, err)
+	}
+}
+
+// ConfProgress describes the number of confirmations a transaction has reached
+// so far.
+type ConfProgress struct {
+	// NumConfs is the current number of confirmations of the transaction.
+	// A value of zero means the transaction was re-organized out of the
+	// chain.
+	NumConfs int32
+
+	// TargetConfs is the number of confirmations that was requested when
+	// registering the notification.
+	TargetConfs int32
+}
+
+// BlockEpoch describes a block that was connected to the main chain.
+type BlockEpoch struct {
+	// Height is the height of the block.
+	Height int32
+
+	// Hash is the hash of the block.
+	Hash chainhash.Hash
+}
+
 // ChainNotifierClient exposes base lightning functionality.
+//
+// Errors caused by the notification stream are delivered as ErrStreamClosed,
+// ErrPermissionDenied or ErrLndShuttingDown where possible, IsTerminal can be
+// used to decide whether it makes sense to register a notification again.
+//
+// Every registration returns a cancel function next to its channels. Calling
+// it tears down only that registration: the stream to lnd is closed and the
+// call blocks until the goroutine serving it has exited. It is safe to call
+// the cancel function more than once.
+//
+// Once a registration ends, either because its context was canceled, it was
+// canceled explicitly or it delivered its final event, the event channel is
+// closed. If the registration ended because of an error, that error is sent on
+// the error channel before the event channel is closed. The error channel
+// itself is never closed.
 type ChainNotifierClient interface {
-	RegisterBlockEpochNtfn(ctx context.Context) (
-		chan int32, chan error, error)
+	// RawClientWithMacAuth returns a context with the proper macaroon
+	// authentication, the default RPC timeout, and the raw client.
+	RawClientWithMacAuth(parentCtx context.Context) (context.Context,
+		time.Duration, chainrpc.ChainNotifierClient)
+
+	// RegisterBlockEpochNtfn registers a notification that is dispatched
+	// for every new block connected to the main chain, delivering both its
+	// height and hash.
+	RegisterBlockEpochNtfn(ctx context.Context, opts ...NotifierOption) (
+		chan *BlockEpoch, chan error, func(), error)
 
+	// RegisterConfirmationsNtfn registers a notification for the given
+	// txid or pkScript that is dispatched once it reaches numConfs
+	// confirmations. If a re-org channel is passed in as an option, the
+	// stream is kept open after the first confirmation and re-org events
+	// are delivered on that channel.
 	RegisterConfirmationsNtfn(ctx context.Context, txid *chainhash.Hash,
-		pkScript []byte, numConfs, heightHint int32) (
-		chan *chainntnfs.TxConfirmation, chan error, error)
+		pkScript []byte, numConfs, heightHint int32,
+		opts ...NotifierOption) (chan *chainntnfs.TxConfirmation,
+		chan error, func(), error)
 
+	// RegisterSpendNtfn registers a notification for the spend of the
+	// given outpoint or pkScript. Note that the chain notifier of the lnd
+	// versions supported by this library only dispatches spends once the
+	// spending transaction has confirmed, unconfirmed (mempool) spends
+	// are not reported. If a re-org channel is passed in as an option, the
+	// stream is kept open after the spend and the re-org of the spending
+	// transaction is delivered on that channel, after which the spend can
+	// be delivered again.
 	RegisterSpendNtfn(ctx context.Context,
-		outpoint *wire.OutPoint, pkScript []byte, heightHint int32) (
-		chan *chainntnfs.SpendDetail, chan error, error)
+		outpoint *wire.OutPoint, pkScript []byte, heightHint int32,
+		opts ...NotifierOption) (chan *chainntnfs.SpendDetail,
+		chan error, func(), error)
+
+	// RegisterScriptConfirmationsNtfn registers a confirmation
+	// notification for the first transaction that creates an output with
+	// the given pkScript. This is useful for outputs like taproot outputs
+	// where the txid isn't known in advance.
+	RegisterScriptConfirmationsNtfn(ctx context.Context, pkScript []byte,
+		numConfs, heightHint int32, opts ...NotifierOption) (
+		chan *chainntnfs.TxConfirmation, chan error, func(), error)
+
+	// RegisterScriptSpendNtfn registers a spend notification for the first
+	// transaction that spends an output with the given pkScript.
+	RegisterScriptSpendNtfn(ctx context.Context, pkScript []byte,
+		heightHint int32, opts ...NotifierOption) (
+		chan *chainntnfs.SpendDetail, chan error, func(), error)
+
+	// Stop cancels all active registrations, which closes their streams
+	// and event channels, and waits for all goroutines of the client to
+	// exit. If the passed context is canceled before that, its error is
+	// returned. No new notifications can be registered once Stop was
+	// called.
+	Stop(ctx context.Context) error
+
+	// WaitForFinished blocks until all goroutines of the client have
+	// exited. It doesn't cancel any registrations itself.
+	WaitForFinished()
 }
 
 type chainNotifierClient struct {
@@ -32,26 +359,246 @@ type chainNotifierClient struct {
 	chainMac serializedMacaroon
 	timeout  time.Duration
 
+	// hintCache is an optional cache of height hints. If it is nil, the
+	// height hints passed in by the caller are always used as is.
+	hintCache HeightHintCache
+
+	// metrics is informed about the health of all notifications.
+	metrics NotifierMetrics
+
+	// blockArrivals tracks the arrival times of the most recent blocks
+	// to measure the delivery latency of events.
+	blockArrivals *blockArrivals
+
+	// registrations is the set of all active registrations, so they can
+	// be canceled when the client is stopped.
+	registrations map[*registration]struct{}
+	stopped       bool
+	regMtx        sync.Mutex
+
 	wg sync.WaitGroup
 }
 
 func newChainNotifierClient(conn grpc.ClientConnInterface,
-	chainMac serializedMacaroon, timeout time.Duration) *chainNotifierClient {
+	chainMac serializedMacaroon, timeout time.Duration,
+	hintCache HeightHintCache, metrics NotifierMetrics) *chainNotifierClient {
+
+	if metrics == nil {
+		metrics = &noopNotifierMetrics{}
+	}
 
 	return &chainNotifierClient{
-		client:   chainrpc.NewChainNotifierClient(conn),
-		chainMac: chainMac,
-		timeout:  timeout,
+		client:        chainrpc.NewChainNotifierClient(conn),
+		chainMac:      chainMac,
+		timeout:       timeout,
+		hintCache:     hintCache,
+		metrics:       metrics,
+		blockArrivals: newBlockArrivals(),
+		registrations: make(map[*registration]struct{}),
 	}
 }
 
+// RawClientWithMacAuth returns a context with the proper macaroon
+// authentication, the default RPC timeout, and the raw client.
+func (s *chainNotifierClient) RawClientWithMacAuth(
+	parentCtx context.Context) (context.Context, time.Duration,
+	chainrpc.ChainNotifierClient) {
+
+	return s.chainMac.WithMacaroonAuth(parentCtx), s.timeout, s.client
+}
+
+// observeDelivery records the latency of an event that happened in the block
+// with the given height and that was received from lnd at the given time.
+func (s *chainNotifierClient) observeDelivery(ntfnType NotificationType,
+	height int32, received time.Time) {
+
+	arrival := s.blockArrivals.get(height, received)
+	s.metrics.ObserveDeliveryLatency(ntfnType, time.Since(arrival))
+}
+
+// WaitForFinished blocks until all goroutines of the client have exited.
 func (s *chainNotifierClient) WaitForFinished() {
 	s.wg.Wait()
 }
 
+// Stop cancels all active registrations and waits for all goroutines of the
+// client to exit, or until the passed context is canceled.
+func (s *chainNotifierClient) Stop(ctx context.Context) error {
+	s.regMtx.Lock()
+	s.stopped = true
+	for reg := range s.registrations {
+		reg.cancel()
+	}
+	s.regMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startRegistration creates a new registration derived from the given context
+// and adds it to the set of active registrations.
+func (s *chainNotifierClient) startRegistration(
+	ctx context.Context) (*registration, error) {
+
+	s.regMtx.Lock()
+	defer s.regMtx.Unlock()
+
+	if s.stopped {
+		return nil, ErrChainNotifierStopped
+	}
+
+	reg := newRegistration(ctx)
+	s.registrations[reg] = struct{}{}
+
+	return reg, nil
+}
+
+// endRegistration cancels the context of the given registration and removes it
+// from the set of active registrations.
+func (s *chainNotifierClient) endRegistration(reg *registration) {
+	reg.cancel()
+
+	s.regMtx.Lock()
+	delete(s.registrations, reg)
+	s.regMtx.Unlock()
+}
+
+// confirmHint returns the height hint to use for the confirmation notification
+// identified by the given key. The cached hint is only used if it is higher
+// than the hint supplied by the caller.
+func (s *chainNotifierClient) confirmHint(key []byte, heightHint int32) int32 {
+	if s.hintCache == nil {
+		return heightHint
+	}
+
+	cachedHint, err := s.hintCache.QueryConfirmHint(key)
+	switch {
+	case err == ErrHintNotFound:
+		return heightHint
+
+	case err != nil:
+		log.Warnf("Unable to query confirm hint: %v", err)
+		return heightHint
+
+	case cachedHint > heightHint:
+		return cachedHint
+
+	default:
+		return heightHint
+	}
+}
+
+// commitConfirmHint stores the height hint of the confirmation notification
+// identified by the given key, if a cache is configured.
+func (s *chainNotifierClient) commitConfirmHint(key []byte, height int32) {
+	if s.hintCache == nil {
+		return
+	}
+
+	if err := s.hintCache.CommitConfirmHint(key, height); err != nil {
+		log.Warnf("Unable to commit confirm hint: %v", err)
+	}
+}
+
+// spendHint returns the height hint to use for the spend notification
+// identified by the given key. The cached hint is only used if it is higher
+// than the hint supplied by the caller.
+func (s *chainNotifierClient) spendHint(key []byte, heightHint int32) int32 {
+	if s.hintCache == nil {
+		return heightHint
+	}
+
+	cachedHint, err := s.hintCache.QuerySpendHint(key)
+	switch {
+	case err == ErrHintNotFound:
+		return heightHint
+
+	case err != nil:
+		log.Warnf("Unable to query spend hint: %v", err)
+		return heightHint
+
+	case cachedHint > heightHint:
+		return cachedHint
+
+	default:
+		return heightHint
+	}
+}
+
+// commitSpendHint stores the height hint of the spend notification identified
+// by the given key, if a cache is configured.
+func (s *chainNotifierClient) commitSpendHint(key []byte, height int32) {
+	if s.hintCache == nil {
+		return
+	}
+
+	if err := s.hintCache.CommitSpendHint(key, height); err != nil {
+		log.Warnf("Unable to commit spend hint: %v", err)
+	}
+}
+
+// registration tracks the lifetime of a single notification registration so
+// it can be torn down independently of all others.
+type registration struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newRegistration derives a cancelable context for a single notification
+// registration from the given parent context.
+func newRegistration(ctx context.Context) *registration {
+	ctx, cancel := context.WithCancel(ctx)
+	return &registration{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Cancel closes the stream of the registration and blocks until the goroutine
+// serving it has exited.
+func (r *registration) Cancel() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// RegisterSpendNtfn registers a notification for the spend of the given
+// outpoint or pkScript. The notification is only dispatched once the spending
+// transaction has confirmed. If a re-org channel is passed in as an option,
+// the stream is kept open after the spend and re-org events are delivered on
+// that channel.
 func (s *chainNotifierClient) RegisterSpendNtfn(ctx context.Context,
-	outpoint *wire.OutPoint, pkScript []byte, heightHint int32) (
-	chan *chainntnfs.SpendDetail, chan error, error) {
+	outpoint *wire.OutPoint, pkScript []byte, heightHint int32,
+	optFuncs ...NotifierOption) (chan *chainntnfs.SpendDetail,
+	chan error, func(), error) {
+
+	opts := defaultNotifierOptions()
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+
+	reg, err := s.startRegistration(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = reg.ctx
+
+	// If we've seen this spend before, we can start scanning from the
+	// height it was last seen at.
+	hintKey := spendHintKey(outpoint, pkScript)
+	origHeightHint := heightHint
+	heightHint = s.spendHint(hintKey, heightHint)
 
 	var rpcOutpoint *chainrpc.Outpoint
 	if outpoint != nil {
@@ -61,20 +608,31 @@ func (s *chainNotifierClient) RegisterSpendNtfn(ctx context.Context,
 		}
 	}
 
-	macaroonAuth := s.chainMac.WithMacaroonAuth(ctx)
-	resp, err := s.client.RegisterSpendNtfn(macaroonAuth, &chainrpc.SpendRequest{
-		HeightHint: uint32(heightHint),
-		Outpoint:   rpcOutpoint,
-		Script:     pkScript,
-	})
+	register := func(heightHint int32) (
+		chainrpc.ChainNotifier_RegisterSpendNtfnClient, error) {
+
+		macaroonAuth := s.chainMac.WithMacaroonAuth(ctx)
+		return s.client.RegisterSpendNtfn(
+			macaroonAuth, &chainrpc.SpendRequest{
+				HeightHint: uint32(heightHint),
+				Outpoint:   rpcOutpoint,
+				Script:     pkScript,
+			},
+		)
+	}
+
+	resp, err := register(heightHint)
 	if err != nil {
-		return nil, nil, err
+		s.endRegistration(reg)
+		return nil, nil, nil, err
 	}
 
 	spendChan := make(chan *chainntnfs.SpendDetail, 1)
 	errChan := make(chan error, 1)
 
-	processSpendDetail := func(d *chainrpc.SpendDetails) error {
+	processSpendDetail := func(d *chainrpc.SpendDetails,
+		received time.Time) error {
+
 		outpointHash, err := chainhash.NewHash(d.SpendingOutpoint.Hash)
 		if err != nil {
 			return err
@@ -87,7 +645,7 @@ func (s *chainNotifierClient) RegisterSpendNtfn(ctx context.Context,
 		if err != nil {
 			return err
 		}
-		spendChan <- &chainntnfs.SpendDetail{
+		spend := &chainntnfs.SpendDetail{
 			SpentOutPoint: &wire.OutPoint{
 				Hash:  *outpointHash,
 				Index: d.SpendingOutpoint.Index,
@@ -97,67 +655,244 @@ func (s *chainNotifierClient) RegisterSpendNtfn(ctx context.Context,
 			SpendingTx:        tx,
 			SpendingHeight:    int32(d.SpendingHeight),
 		}
+		s.commitSpendHint(hintKey, spend.SpendingHeight)
+
+		select {
+		case spendChan <- spend:
+			s.observeDelivery(
+				NotificationSpend, spend.SpendingHeight,
+				received,
+			)
+
+		case <-ctx.Done():
+			s.metrics.IncDroppedEvents(NotificationSpend)
+		}
 
 		return nil
 	}
 
 	s.wg.Add(1)
+	reg.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		defer reg.wg.Done()
+		defer s.endRegistration(reg)
+		defer close(spendChan)
+
+		// lastHeight is the height of the last block the stream told
+		// us about. It is used as the height hint of a re-registered
+		// notification, so lnd doesn't rescan blocks we've already
+		// seen.
+		lastHeight := heightHint
+
 		for {
 			spendEvent, err := resp.Recv()
+			if err != nil && opts.Resubscribe && !IsTerminal(err) {
+				err = opts.reRegister(ctx, func() error {
+					var err error
+					resp, err = register(lastHeight)
+					return err
+				})
+				if err == nil {
+					s.metrics.IncReconnects(
+						NotificationSpend,
+					)
+					continue
+				}
+			}
 			if err != nil {
-				errChan <- err
+				errChan <- classifyStreamErr(err)
 				return
 			}
 
-			c, ok := spendEvent.Event.(*chainrpc.SpendEvent_Spend)
-			if ok {
-				err := processSpendDetail(c.Spend)
+			switch c := spendEvent.Event.(type) {
+			case *chainrpc.SpendEvent_Spend:
+				err := processSpendDetail(c.Spend, time.Now())
 				if err != nil {
 					errChan <- err
+					return
+				}
+				lastHeight = int32(c.Spend.SpendingHeight)
+
+				// If we're watching for re-orgs, we keep the
+				// stream open so we can be notified if the
+				// spending transaction is re-organized out of
+				// the chain.
+				if opts.ReOrgChan == nil {
+					return
+				}
+
+			// The spending transaction was re-organized out of the
+			// chain. Only notify the caller if they asked for it,
+			// otherwise the event is ignored.
+			case *chainrpc.SpendEvent_Reorg:
+				// The spend height we cached is no longer
+				// valid, the transaction may be spent again
+				// in any block after the original hint.
+				s.commitSpendHint(hintKey, origHeightHint)
+				lastHeight = origHeightHint
+
+				if opts.ReOrgChan == nil {
+					continue
+				}
+
+				select {
+				case opts.ReOrgChan <- struct{}{}:
+				case <-ctx.Done():
+					return
 				}
-				return
 			}
 		}
 	}()
 
-	return spendChan, errChan, nil
+	return spendChan, errChan, reg.Cancel, nil
+}
+
+// validateScriptOnly checks that the given pkScript can be used to register a
+// script-only notification. If the script is a version 1 witness program, its
+// program must have the length of a taproot output key.
+func validateScriptOnly(pkScript []byte) error {
+	if len(pkScript) == 0 {
+		return ErrEmptyPkScript
+	}
+
+	// A witness program consists of a version opcode followed by a single
+	// data push of 2 to 40 bytes. Version 1 is represented by OP_1.
+	if len(pkScript) < 4 || pkScript[0] != txscript.OP_1 {
+		return nil
+	}
+
+	pushLen := int(pkScript[1])
+	if pushLen < 2 || pushLen > 40 || len(pkScript) != pushLen+2 {
+		return nil
+	}
+
+	if pushLen != taprootWitnessProgramLen {
+		return ErrInvalidTaprootScript
+	}
+
+	return nil
+}
+
+// RegisterScriptConfirmationsNtfn registers a confirmation notification for
+// the first transaction that creates an output with the given pkScript.
+func (s *chainNotifierClient) RegisterScriptConfirmationsNtfn(
+	ctx context.Context, pkScript []byte, numConfs, heightHint int32,
+	opts ...NotifierOption) (chan *chainntnfs.TxConfirmation, chan error,
+	func(), error) {
+
+	if err := validateScriptOnly(pkScript); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return s.RegisterConfirmationsNtfn(
+		ctx, nil, pkScript, numConfs, heightHint, opts...,
+	)
+}
+
+// RegisterScriptSpendNtfn registers a spend notification for the first
+// transaction that spends an output with the given pkScript.
+func (s *chainNotifierClient) RegisterScriptSpendNtfn(ctx context.Context,
+	pkScript []byte, heightHint int32, opts ...NotifierOption) (
+	chan *chainntnfs.SpendDetail, chan error, func(), error) {
+
+	if err := validateScriptOnly(pkScript); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return s.RegisterSpendNtfn(ctx, nil, pkScript, heightHint, opts...)
 }
 
+// RegisterConfirmationsNtfn registers a notification for the given txid or
+// pkScript that is dispatched once it reaches numConfs confirmations. If a
+// re-org channel is passed in as an option, the stream is kept open after the
+// first confirmation and re-org events are delivered on that channel. If a
+// progress channel is passed in as an option, intermediate confirmation counts
+// are delivered on that channel for every new block.
 func (s *chainNotifierClient) RegisterConfirmationsNtfn(ctx context.Context,
-	txid *chainhash.Hash, pkScript []byte, numConfs, heightHint int32) (
-	chan *chainntnfs.TxConfirmation, chan error, error) {
+	txid *chainhash.Hash, pkScript []byte, numConfs, heightHint int32,
+	optFuncs ...NotifierOption) (chan *chainntnfs.TxConfirmation,
+	chan error, func(), error) {
+
+	opts := defaultNotifierOptions()
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+
+	reg, err := s.startRegistration(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = reg.ctx
 
 	var txidSlice []byte
 	if txid != nil {
 		txidSlice = txid[:]
 	}
-	confStream, err := s.client.RegisterConfirmationsNtfn(
-		s.chainMac.WithMacaroonAuth(ctx),
-		&chainrpc.ConfRequest{
-			Script:     pkScript,
-			NumConfs:   uint32(numConfs),
-			HeightHint: uint32(heightHint),
-			Txid:       txidSlice,
-		},
-	)
+
+	// If we've seen this confirmation before, we can start scanning from
+	// the height it was last seen at. We keep the original hint around in
+	// case the confirmation is re-organized out of the chain.
+	hintKey := confirmHintKey(txid, pkScript)
+	origHeightHint := heightHint
+	heightHint = s.confirmHint(hintKey, heightHint)
+
+	register := func(heightHint int32) (
+		chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient, error) {
+
+		return s.client.RegisterConfirmationsNtfn(
+			s.chainMac.WithMacaroonAuth(ctx),
+			&chainrpc.ConfRequest{
+				Script:     pkScript,
+				NumConfs:   uint32(numConfs),
+				HeightHint: uint32(heightHint),
+				Txid:       txidSlice,
+			},
+		)
+	}
+
+	confStream, err := register(heightHint)
 	if err != nil {
-		return nil, nil, err
+		s.endRegistration(reg)
+		return nil, nil, nil, err
 	}
 
 	confChan := make(chan *chainntnfs.TxConfirmation, 1)
 	errChan := make(chan error, 1)
 
 	s.wg.Add(1)
+	reg.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		defer reg.wg.Done()
+		defer s.endRegistration(reg)
+		defer close(confChan)
+
+		// lastHeight is the height of the last block the stream told
+		// us about. It is used as the height hint of a re-registered
+		// notification, so lnd doesn't rescan blocks we've already
+		// seen.
+		lastHeight := heightHint
 
 		for {
 			var confEvent *chainrpc.ConfEvent
 			confEvent, err := confStream.Recv()
+			received := time.Now()
+			if err != nil && opts.Resubscribe && !IsTerminal(err) {
+				err = opts.reRegister(ctx, func() error {
+					var err error
+					confStream, err = register(lastHeight)
+					return err
+				})
+				if err == nil {
+					s.metrics.IncReconnects(
+						NotificationConfirmation,
+					)
+					continue
+				}
+			}
 			if err != nil {
-				errChan <- err
+				errChan <- classifyStreamErr(err)
 				return
 			}
 
@@ -177,17 +912,62 @@ func (s *chainNotifierClient) RegisterConfirmationsNtfn(ctx context.Context,
 					errChan <- err
 					return
 				}
-				confChan <- &chainntnfs.TxConfirmation{
+				conf := &chainntnfs.TxConfirmation{
 					BlockHeight: c.Conf.BlockHeight,
 					BlockHash:   blockHash,
 					Tx:          tx,
 					TxIndex:     c.Conf.TxIndex,
 				}
-				return
+				s.commitConfirmHint(
+					hintKey, int32(conf.BlockHeight),
+				)
+				lastHeight = int32(conf.BlockHeight)
 
-			// Ignore reorg events, not supported.
+				// The transaction may confirm again after a
+				// re-org, so we can't rely on the buffer of
+				// the conf channel alone.
+				select {
+				case confChan <- conf:
+					s.observeDelivery(
+						NotificationConfirmation,
+						int32(conf.BlockHeight),
+						received,
+					)
+
+				case <-ctx.Done():
+					s.metrics.IncDroppedEvents(
+						NotificationConfirmation,
+					)
+					return
+				}
+
+				// If we're watching for re-orgs, we keep the
+				// stream open so we can be notified if the
+				// confirmation is re-organized out of the
+				// chain.
+				if opts.ReOrgChan == nil {
+					return
+				}
+
+			// The transaction was re-organized out of the chain.
+			// Only notify the caller if they asked for it,
+			// otherwise the event is ignored.
 			case *chainrpc.ConfEvent_Reorg:
-				continue
+				// The confirmation height we cached is no
+				// longer valid, the transaction may confirm
+				// again in any block after the original hint.
+				s.commitConfirmHint(hintKey, origHeightHint)
+				lastHeight = origHeightHint
+
+				if opts.ReOrgChan == nil {
+					continue
+				}
+
+				select {
+				case opts.ReOrgChan <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
 
 			// Nil event, should never happen.
 			case nil:
@@ -204,40 +984,266 @@ func (s *chainNotifierClient) RegisterConfirmationsNtfn(ctx context.Context,
 		}
 	}()
 
-	return confChan, errChan, nil
+	// If the caller is interested in intermediate confirmation counts, we
+	// track them separately. All progress updates are sent from that
+	// single goroutine, so they are delivered in order.
+	if opts.ProgressChan != nil {
+		s.wg.Add(1)
+		reg.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer reg.wg.Done()
+
+			err := s.trackConfProgress(
+				ctx, txid, pkScript, numConfs, heightHint,
+				opts.ProgressChan,
+			)
+			if err != nil && ctx.Err() == nil {
+				log.Warnf("Unable to track confirmation "+
+					"progress: %v", err)
+			}
+		}()
+	}
+
+	return confChan, errChan, reg.Cancel, nil
 }
 
-func (s *chainNotifierClient) RegisterBlockEpochNtfn(ctx context.Context) (
-	chan int32, chan error, error) {
+// trackConfProgress delivers the confirmation count of a transaction on the
+// given progress channel for every new block that changes it, including the
+// final count. This function blocks until the transaction has reached
+// numConfs confirmations or the context is canceled.
+func (s *chainNotifierClient) trackConfProgress(ctx context.Context,
+	txid *chainhash.Hash, pkScript []byte, numConfs, heightHint int32,
+	progressChan chan *ConfProgress) error {
+
+	// We learn the height the transaction first confirmed in by
+	// registering for a single confirmation, and derive the number of
+	// confirmations from the height of every new block.
+	reOrgChan := make(chan struct{})
+	confChan, confErrChan, cancelConf, err := s.RegisterConfirmationsNtfn(
+		ctx, txid, pkScript, 1, heightHint, WithReOrgChan(reOrgChan),
+	)
+	if err != nil {
+		return err
+	}
+	defer cancelConf()
 
-	blockEpochClient, err := s.client.RegisterBlockEpochNtfn(
-		s.chainMac.WithMacaroonAuth(ctx), &chainrpc.BlockEpoch{},
+	blockChan, blockErrChan, cancelBlocks, err := s.RegisterBlockEpochNtfn(
+		ctx,
 	)
 	if err != nil {
-		return nil, nil, err
+		return err
+	}
+	defer cancelBlocks()
+
+	var (
+		bestHeight int32
+		confHeight int32
+		lastConfs  int32
+	)
+
+	// sendProgress delivers the number of confirmations at the best
+	// height if it changed. It returns true once the final count was
+	// delivered.
+	sendProgress := func() (bool, error) {
+		var confs int32
+		if confHeight != 0 {
+			// The block of the confirmation may arrive after the
+			// confirmation itself.
+			confs = bestHeight - confHeight + 1
+			if confs < 1 {
+				confs = 1
+			}
+			if confs > numConfs {
+				confs = numConfs
+			}
+		}
+
+		if confs == lastConfs {
+			return false, nil
+		}
+
+		progress := &ConfProgress{
+			NumConfs:    confs,
+			TargetConfs: numConfs,
+		}
+		select {
+		case progressChan <- progress:
+			lastConfs = confs
+			return confs == numConfs, nil
+
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	for {
+		select {
+		// A closed channel means the registration has ended, its
+		// error channel tells us why.
+		case conf, ok := <-confChan:
+			if !ok {
+				confChan = nil
+				continue
+			}
+
+			confHeight = int32(conf.BlockHeight)
+
+		case <-reOrgChan:
+			confHeight = 0
+
+		case block, ok := <-blockChan:
+			if !ok {
+				blockChan = nil
+				continue
+			}
+
+			bestHeight = block.Height
+
+		case err := <-confErrChan:
+			return err
+
+		case err := <-blockErrChan:
+			return err
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		done, err := sendProgress()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// RegisterBlockEpochNtfn registers a notification that is dispatched for every
+// new block connected to the main chain, delivering both its height and hash.
+// If the notification is re-registered after a stream failure, lnd is asked
+// to deliver all blocks that were connected after the last one we've seen.
+func (s *chainNotifierClient) RegisterBlockEpochNtfn(ctx context.Context,
+	optFuncs ...NotifierOption) (chan *BlockEpoch, chan error, func(),
+	error) {
+
+	opts := defaultNotifierOptions()
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+
+	reg, err := s.startRegistration(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = reg.ctx
+
+	// bestBlock is the last block we've delivered to the caller. It is
+	// used as the starting point of a re-registered notification.
+	bestBlock := &chainrpc.BlockEpoch{}
+	register := func() (chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient,
+		error) {
+
+		return s.client.RegisterBlockEpochNtfn(
+			s.chainMac.WithMacaroonAuth(ctx), bestBlock,
+		)
+	}
+
+	blockEpochClient, err := register()
+	if err != nil {
+		s.endRegistration(reg)
+		return nil, nil, nil, err
 	}
 
 	blockErrorChan := make(chan error, 1)
-	blockEpochChan := make(chan int32)
+	bufferSize := opts.BlockEpochBuffer
+	if opts.CoalesceBlocks {
+		bufferSize = 1
+	}
+	blockEpochChan := make(chan *BlockEpoch, bufferSize)
 
 	// Start block epoch goroutine.
 	s.wg.Add(1)
+	reg.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		defer reg.wg.Done()
+		defer s.endRegistration(reg)
+		defer close(blockEpochChan)
+
 		for {
 			epoch, err := blockEpochClient.Recv()
+			received := time.Now()
+			if err != nil && opts.Resubscribe && !IsTerminal(err) {
+				err = opts.reRegister(ctx, func() error {
+					var err error
+					blockEpochClient, err = register()
+					return err
+				})
+				if err == nil {
+					s.metrics.IncReconnects(
+						NotificationBlockEpoch,
+					)
+					continue
+				}
+			}
+			if err != nil {
+				blockErrorChan <- classifyStreamErr(err)
+				return
+			}
+
+			hash, err := chainhash.NewHash(epoch.Hash)
 			if err != nil {
 				blockErrorChan <- err
 				return
 			}
 
+			blockEpoch := &BlockEpoch{
+				Height: int32(epoch.Height),
+				Hash:   *hash,
+			}
+
+			s.blockArrivals.add(blockEpoch.Height, received)
+
+			// In coalescing mode we replace a block the caller
+			// hasn't read yet. As we're the only sender, the
+			// channel is guaranteed to have space afterwards.
+			if opts.CoalesceBlocks {
+				select {
+				case <-blockEpochChan:
+					s.metrics.IncDroppedEvents(
+						NotificationBlockEpoch,
+					)
+
+				default:
+				}
+
+				blockEpochChan <- blockEpoch
+				s.observeDelivery(
+					NotificationBlockEpoch,
+					blockEpoch.Height, received,
+				)
+				bestBlock = epoch
+
+				continue
+			}
+
 			select {
-			case blockEpochChan <- int32(epoch.Height):
+			case blockEpochChan <- blockEpoch:
+				s.observeDelivery(
+					NotificationBlockEpoch,
+					blockEpoch.Height, received,
+				)
+
 			case <-ctx.Done():
+				s.metrics.IncDroppedEvents(NotificationBlockEpoch)
 				return
 			}
+
+			bestBlock = epoch
 		}
 	}()
 
-	return blockEpochChan, blockErrorChan, nil
+	return blockEpochChan, blockErrorChan, reg.Cancel, nil
 }
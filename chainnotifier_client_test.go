@@ -0,0 +1,313 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// mockConfStream is a confirmation stream opened on the mock chain notifier.
+type mockConfStream struct {
+	chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient
+
+	ctx    context.Context
+	req    *chainrpc.ConfRequest
+	events chan *chainrpc.ConfEvent
+	errs   chan error
+}
+
+func (m *mockConfStream) Recv() (*chainrpc.ConfEvent, error) {
+	select {
+	case event := <-m.events:
+		return event, nil
+
+	case err := <-m.errs:
+		return nil, err
+
+	case <-m.ctx.Done():
+		return nil, m.ctx.Err()
+	}
+}
+
+// mockBlockStream is a block stream opened on the mock chain notifier.
+type mockBlockStream struct {
+	chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient
+
+	ctx    context.Context
+	req    *chainrpc.BlockEpoch
+	events chan *chainrpc.BlockEpoch
+}
+
+func (m *mockBlockStream) Recv() (*chainrpc.BlockEpoch, error) {
+	select {
+	case event := <-m.events:
+		return event, nil
+
+	case <-m.ctx.Done():
+		return nil, m.ctx.Err()
+	}
+}
+
+// mockChainNotifierRPC is a chain notifier rpc client that hands all streams
+// to the test.
+type mockChainNotifierRPC struct {
+	chainrpc.ChainNotifierClient
+
+	confStreams  chan *mockConfStream
+	blockStreams chan *mockBlockStream
+}
+
+func (m *mockChainNotifierRPC) RegisterConfirmationsNtfn(ctx context.Context,
+	req *chainrpc.ConfRequest, _ ...grpc.CallOption) (
+	chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient, error) {
+
+	stream := &mockConfStream{
+		ctx:    ctx,
+		req:    req,
+		events: make(chan *chainrpc.ConfEvent),
+		errs:   make(chan error, 1),
+	}
+	m.confStreams <- stream
+
+	return stream, nil
+}
+
+func (m *mockChainNotifierRPC) RegisterBlockEpochNtfn(ctx context.Context,
+	req *chainrpc.BlockEpoch, _ ...grpc.CallOption) (
+	chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient, error) {
+
+	stream := &mockBlockStream{
+		ctx:    ctx,
+		req:    req,
+		events: make(chan *chainrpc.BlockEpoch),
+	}
+	m.blockStreams <- stream
+
+	return stream, nil
+}
+
+// newMockChainNotifier creates a chain notifier client backed by a mock rpc
+// client.
+func newMockChainNotifier() (*chainNotifierClient, *mockChainNotifierRPC) {
+	rpc := &mockChainNotifierRPC{
+		confStreams:  make(chan *mockConfStream, 10),
+		blockStreams: make(chan *mockBlockStream, 10),
+	}
+
+	client := newChainNotifierClient(nil, "", time.Second, nil, nil)
+	client.client = rpc
+
+	return client, rpc
+}
+
+func (m *mockChainNotifierRPC) nextConfStream(t *testing.T) *mockConfStream {
+	select {
+	case stream := <-m.confStreams:
+		return stream
+
+	case <-time.After(testTimeout):
+		t.Fatal("no confirmation stream opened")
+		return nil
+	}
+}
+
+func (m *mockChainNotifierRPC) nextBlockStream(
+	t *testing.T) *mockBlockStream {
+
+	select {
+	case stream := <-m.blockStreams:
+		return stream
+
+	case <-time.After(testTimeout):
+		t.Fatal("no block stream opened")
+		return nil
+	}
+}
+
+func (m *mockConfStream) send(t *testing.T, event *chainrpc.ConfEvent) {
+	select {
+	case m.events <- event:
+	case <-time.After(testTimeout):
+		t.Fatal("confirmation event not consumed")
+	}
+}
+
+func (m *mockBlockStream) send(t *testing.T, height uint32) {
+	select {
+	case m.events <- &chainrpc.BlockEpoch{
+		Height: height,
+		Hash:   make([]byte, chainhash.HashSize),
+	}:
+
+	case <-time.After(testTimeout):
+		t.Fatal("block not consumed")
+	}
+}
+
+// newConfEvent creates a confirmation event for a dummy transaction in the
+// block with the given height.
+func newConfEvent(t *testing.T, height uint32) *chainrpc.ConfEvent {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{})
+	tx.AddTxOut(&wire.TxOut{Value: 1000})
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+
+	return &chainrpc.ConfEvent{
+		Event: &chainrpc.ConfEvent_Conf{
+			Conf: &chainrpc.ConfDetails{
+				RawTx:       buf.Bytes(),
+				BlockHash:   make([]byte, chainhash.HashSize),
+				BlockHeight: height,
+			},
+		},
+	}
+}
+
+// TestConfProgressOrder tests that all confirmation progress updates,
+// including the final one and the resets caused by re-orgs, are delivered in
+// the order of the blocks they were caused by.
+func TestConfProgressOrder(t *testing.T) {
+	client, rpc := newMockChainNotifier()
+	defer func() {
+		require.NoError(t, client.Stop(context.Background()))
+	}()
+
+	progressChan := make(chan *ConfProgress)
+	txid := chainhash.Hash{1}
+	confChan, _, cancel, err := client.RegisterConfirmationsNtfn(
+		context.Background(), &txid, nil, 3, 100,
+		WithConfProgress(progressChan),
+	)
+	require.NoError(t, err)
+	defer cancel()
+
+	// The notification itself is registered first, the progress is
+	// tracked with a single confirmation and the blocks.
+	confStream := rpc.nextConfStream(t)
+	require.EqualValues(t, 3, confStream.req.NumConfs)
+
+	progressStream := rpc.nextConfStream(t)
+	require.EqualValues(t, 1, progressStream.req.NumConfs)
+
+	blockStream := rpc.nextBlockStream(t)
+
+	expectProgress := func(numConfs int32) {
+		t.Helper()
+
+		select {
+		case progress := <-progressChan:
+			require.Equal(t, &ConfProgress{
+				NumConfs:    numConfs,
+				TargetConfs: 3,
+			}, progress)
+
+		case <-time.After(testTimeout):
+			t.Fatalf("no progress update for %v confs", numConfs)
+		}
+	}
+
+	progressStream.send(t, newConfEvent(t, 100))
+	expectProgress(1)
+
+	blockStream.send(t, 101)
+	expectProgress(2)
+
+	// A re-org resets the progress until the transaction confirms again.
+	progressStream.send(t, &chainrpc.ConfEvent{
+		Event: &chainrpc.ConfEvent_Reorg{
+			Reorg: &chainrpc.Reorg{},
+		},
+	})
+	expectProgress(0)
+
+	progressStream.send(t, newConfEvent(t, 101))
+	expectProgress(1)
+
+	blockStream.send(t, 102)
+	expectProgress(2)
+
+	// The final update is delivered by the same goroutine as all others.
+	blockStream.send(t, 103)
+	expectProgress(3)
+
+	confStream.send(t, newConfEvent(t, 101))
+	select {
+	case conf := <-confChan:
+		require.EqualValues(t, 101, conf.BlockHeight)
+
+	case <-time.After(testTimeout):
+		t.Fatal("no confirmation")
+	}
+
+	// No further progress is delivered after the final update.
+	select {
+	case progress := <-progressChan:
+		t.Fatalf("unexpected progress update: %v", progress)
+
+	default:
+	}
+}
+
+// TestConfReRegisterHeightHint tests that a re-registered confirmation
+// notification uses the height of the last block the stream saw as its height
+// hint, and falls back to the original hint after a re-org.
+func TestConfReRegisterHeightHint(t *testing.T) {
+	client, rpc := newMockChainNotifier()
+	defer func() {
+		require.NoError(t, client.Stop(context.Background()))
+	}()
+
+	reOrgChan := make(chan struct{})
+	txid := chainhash.Hash{2}
+	confChan, _, cancel, err := client.RegisterConfirmationsNtfn(
+		context.Background(), &txid, nil, 1, 100,
+		WithReOrgChan(reOrgChan),
+		WithResubscribe(time.Millisecond, time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer cancel()
+
+	stream := rpc.nextConfStream(t)
+	require.EqualValues(t, 100, stream.req.HeightHint)
+
+	stream.send(t, newConfEvent(t, 150))
+	select {
+	case conf := <-confChan:
+		require.EqualValues(t, 150, conf.BlockHeight)
+
+	case <-time.After(testTimeout):
+		t.Fatal("no confirmation")
+	}
+
+	// The stream fails after the confirmation, so lnd only needs to scan
+	// from the block the transaction confirmed in.
+	stream.errs <- ErrStreamClosed
+	stream = rpc.nextConfStream(t)
+	require.EqualValues(t, 150, stream.req.HeightHint)
+
+	// After a re-org, the transaction may confirm again in any block after
+	// the original hint.
+	stream.send(t, &chainrpc.ConfEvent{
+		Event: &chainrpc.ConfEvent_Reorg{
+			Reorg: &chainrpc.Reorg{},
+		},
+	})
+	select {
+	case <-reOrgChan:
+	case <-time.After(testTimeout):
+		t.Fatal("no re-org")
+	}
+
+	stream.errs <- ErrStreamClosed
+	stream = rpc.nextConfStream(t)
+	require.EqualValues(t, 100, stream.req.HeightHint)
+}
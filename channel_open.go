@@ -0,0 +1,272 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// errChannelEventsClosed is returned if the channel event subscription ends
+// before the channel became active.
+var errChannelEventsClosed = errors.New("channel event subscription closed")
+
+// ChannelOpenFailure is the reason a channel didn't become active after it was
+// opened with OpenChannelAndWait.
+type ChannelOpenFailure uint8
+
+const (
+	// ChannelOpenFailureFunding indicates that lnd couldn't open the
+	// channel, for example because the peer rejected it or our wallet
+	// couldn't fund it.
+	ChannelOpenFailureFunding ChannelOpenFailure = iota
+
+	// ChannelOpenFailureClosed indicates that the channel was closed
+	// before it became active, for example because the funding
+	// transaction never confirmed.
+	ChannelOpenFailureClosed
+
+	// ChannelOpenFailureCanceled indicates that the context was canceled
+	// before the channel became active.
+	ChannelOpenFailureCanceled
+
+	// ChannelOpenFailureSubscription indicates that one of the
+	// subscriptions used to track the channel failed.
+	ChannelOpenFailureSubscription
+)
+
+// String returns a human readable representation of the failure.
+func (f ChannelOpenFailure) String() string {
+	switch f {
+	case ChannelOpenFailureFunding:
+		return "Funding"
+
+	case ChannelOpenFailureClosed:
+		return "Closed"
+
+	case ChannelOpenFailureCanceled:
+		return "Canceled"
+
+	case ChannelOpenFailureSubscription:
+		return "Subscription"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// ChannelOpenError is returned by OpenChannelAndWait if the channel didn't
+// become active.
+type ChannelOpenError struct {
+	// Reason is the reason the channel didn't become active.
+	Reason ChannelOpenFailure
+
+	// ChannelPoint is the funding outpoint of the channel. It is nil if
+	// the channel couldn't be opened at all.
+	ChannelPoint *wire.OutPoint
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *ChannelOpenError) Error() string {
+	if e.ChannelPoint == nil {
+		return fmt.Sprintf("channel open failed (%v): %v", e.Reason,
+			e.Err)
+	}
+
+	return fmt.Sprintf("channel %v failed to become active (%v): %v",
+		e.ChannelPoint, e.Reason, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ChannelOpenError) Unwrap() error {
+	return e.Err
+}
+
+// OpenChannelAndWaitRequest holds the parameters of OpenChannelAndWait.
+type OpenChannelAndWaitRequest struct {
+	// Peer is the node to open the channel with.
+	Peer route.Vertex
+
+	// LocalAmount is the amount we commit to the channel.
+	LocalAmount btcutil.Amount
+
+	// PushAmount is the amount that is pushed to the remote side as part
+	// of the initial commitment state.
+	PushAmount btcutil.Amount
+
+	// Private indicates that the channel should not be announced to the
+	// network.
+	Private bool
+
+	// OnConfirmed is an optional callback that is invoked once the
+	// funding transaction has its first confirmation.
+	OnConfirmed func(*chainntnfs.TxConfirmation)
+}
+
+// OpenChannelAndWait opens a channel and waits until it is active. The
+// confirmation of the funding transaction is tracked with the chain notifier,
+// the opening and activation of the channel with a channel event
+// subscription. If the channel doesn't become active, a *ChannelOpenError is
+// returned.
+func OpenChannelAndWait(ctx context.Context, lnd LightningClient,
+	notifier ChainNotifierClient, req OpenChannelAndWaitRequest) (
+	*ChannelInfo, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fail := func(reason ChannelOpenFailure, chanPoint *wire.OutPoint,
+		err error) (*ChannelInfo, error) {
+
+		return nil, &ChannelOpenError{
+			Reason:       reason,
+			ChannelPoint: chanPoint,
+			Err:          err,
+		}
+	}
+
+	// We subscribe to channel events before opening the channel, so we
+	// can't miss any of its events.
+	events, eventErrs, err := lnd.SubscribeChannelEvents(ctx)
+	if err != nil {
+		return fail(ChannelOpenFailureSubscription, nil, err)
+	}
+
+	info, err := lnd.GetInfo(ctx)
+	if err != nil {
+		return fail(ChannelOpenFailureFunding, nil, err)
+	}
+
+	heightHint := int32(info.BlockHeight)
+	chanPoint, err := lnd.OpenChannel(
+		ctx, req.Peer, req.LocalAmount, req.PushAmount, req.Private,
+	)
+	if err != nil {
+		return fail(ChannelOpenFailureFunding, nil, err)
+	}
+
+	// The chain notifier needs the funding output's script, which we
+	// take from the funding transaction in our wallet.
+	pkScript, err := fundingScript(ctx, lnd, chanPoint, heightHint)
+	if err != nil {
+		return fail(ChannelOpenFailureSubscription, chanPoint, err)
+	}
+
+	notify := notifier.RegisterConfirmationsNtfn
+	confChan, confErrs, cancelConf, err := notify(
+		ctx, &chanPoint.Hash, pkScript, 1, heightHint,
+	)
+	if err != nil {
+		return fail(ChannelOpenFailureSubscription, chanPoint, err)
+	}
+	defer cancelConf()
+
+	var (
+		opened *ChannelInfo
+		active bool
+	)
+	for {
+		select {
+		// We only need the first confirmation, so we stop listening
+		// after it was delivered.
+		case conf, ok := <-confChan:
+			confChan = nil
+			if ok && req.OnConfirmed != nil {
+				req.OnConfirmed(conf)
+			}
+
+		case update, ok := <-events:
+			if !ok {
+				return fail(
+					ChannelOpenFailureSubscription,
+					chanPoint, errChannelEventsClosed,
+				)
+			}
+
+			if update.Event == nil ||
+				update.Event.ChanPoint() != *chanPoint {
+
+				continue
+			}
+
+			switch event := update.Event.(type) {
+			case *OpenChannelEvent:
+				opened = event.Channel
+
+			case *ActiveChannelEvent:
+				active = true
+
+			case *ClosedChannelEvent:
+				return fail(
+					ChannelOpenFailureClosed, chanPoint,
+					fmt.Errorf("channel closed: %v",
+						event.Channel.CloseType),
+				)
+			}
+
+			if opened != nil && active {
+				return opened, nil
+			}
+
+		case err, ok := <-eventErrs:
+			if !ok {
+				eventErrs = nil
+				continue
+			}
+
+			return fail(
+				ChannelOpenFailureSubscription, chanPoint, err,
+			)
+
+		case err, ok := <-confErrs:
+			if !ok {
+				confErrs = nil
+				continue
+			}
+
+			return fail(
+				ChannelOpenFailureSubscription, chanPoint, err,
+			)
+
+		case <-ctx.Done():
+			return fail(
+				ChannelOpenFailureCanceled, chanPoint,
+				ctx.Err(),
+			)
+		}
+	}
+}
+
+// fundingScript looks up the pkScript of a channel's funding output in the
+// transactions of our wallet, starting at the given height.
+func fundingScript(ctx context.Context, lnd LightningClient,
+	chanPoint *wire.OutPoint, startHeight int32) ([]byte, error) {
+
+	txs, err := lnd.ListTransactions(ctx, startHeight, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range txs {
+		if tx.Tx.TxHash() != chanPoint.Hash {
+			continue
+		}
+
+		if int(chanPoint.Index) >= len(tx.Tx.TxOut) {
+			return nil, fmt.Errorf("funding output %v not found",
+				chanPoint)
+		}
+
+		return tx.Tx.TxOut[chanPoint.Index].PkScript, nil
+	}
+
+	return nil, fmt.Errorf("funding transaction %v not found",
+		chanPoint.Hash)
+}
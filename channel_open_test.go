@@ -0,0 +1,293 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// mockChannelOpenClient is a lightning client that opens a channel with a
+// fixed funding transaction and delivers the channel events sent by the test.
+type mockChannelOpenClient struct {
+	LightningClient
+
+	fundingTx *wire.MsgTx
+	openErr   error
+	events    chan *ChannelEventUpdate
+	eventErrs chan error
+}
+
+func newMockChannelOpenClient() *mockChannelOpenClient {
+	fundingTx := wire.NewMsgTx(2)
+	fundingTx.AddTxIn(&wire.TxIn{})
+	fundingTx.AddTxOut(&wire.TxOut{
+		Value:    100000,
+		PkScript: []byte{0, 32, 1},
+	})
+
+	return &mockChannelOpenClient{
+		fundingTx: fundingTx,
+		events:    make(chan *ChannelEventUpdate),
+		eventErrs: make(chan error, 1),
+	}
+}
+
+func (m *mockChannelOpenClient) chanPoint() wire.OutPoint {
+	return wire.OutPoint{Hash: m.fundingTx.TxHash()}
+}
+
+func (m *mockChannelOpenClient) SubscribeChannelEvents(context.Context) (
+	<-chan *ChannelEventUpdate, <-chan error, error) {
+
+	return m.events, m.eventErrs, nil
+}
+
+func (m *mockChannelOpenClient) GetInfo(context.Context) (*Info, error) {
+	return &Info{BlockHeight: 100}, nil
+}
+
+func (m *mockChannelOpenClient) OpenChannel(context.Context, route.Vertex,
+	btcutil.Amount, btcutil.Amount, bool) (*wire.OutPoint, error) {
+
+	if m.openErr != nil {
+		return nil, m.openErr
+	}
+
+	chanPoint := m.chanPoint()
+	return &chanPoint, nil
+}
+
+func (m *mockChannelOpenClient) ListTransactions(context.Context, int32,
+	int32, ...ListTransactionsOption) ([]Transaction, error) {
+
+	return []Transaction{{Tx: m.fundingTx}}, nil
+}
+
+func (m *mockChannelOpenClient) sendEvent(t *testing.T, event ChannelEvent) {
+	select {
+	case m.events <- &ChannelEventUpdate{Event: event}:
+	case <-time.After(testTimeout):
+		t.Fatal("channel event not consumed")
+	}
+}
+
+// openResult is the result of a call to OpenChannelAndWait.
+type openResult struct {
+	channel *ChannelInfo
+	err     error
+}
+
+// openChannelAndWait calls OpenChannelAndWait in a goroutine and returns a
+// channel that delivers its result.
+func openChannelAndWait(ctx context.Context, lnd *mockChannelOpenClient,
+	notifier *mockWatchNotifier,
+	onConfirmed func(*chainntnfs.TxConfirmation)) chan *openResult {
+
+	result := make(chan *openResult, 1)
+	go func() {
+		channel, err := OpenChannelAndWait(
+			ctx, lnd, notifier, OpenChannelAndWaitRequest{
+				Peer:        route.Vertex{1},
+				LocalAmount: 100000,
+				OnConfirmed: onConfirmed,
+			},
+		)
+		result <- &openResult{channel: channel, err: err}
+	}()
+
+	return result
+}
+
+func waitForOpenResult(t *testing.T, result chan *openResult) *openResult {
+	select {
+	case res := <-result:
+		return res
+
+	case <-time.After(testTimeout):
+		t.Fatal("channel open didn't return")
+		return nil
+	}
+}
+
+// requireOpenError asserts that the channel open failed with the given reason.
+func requireOpenError(t *testing.T, res *openResult,
+	reason ChannelOpenFailure, chanPoint *wire.OutPoint) {
+
+	t.Helper()
+
+	require.Nil(t, res.channel)
+
+	var openErr *ChannelOpenError
+	require.True(t, errors.As(res.err, &openErr))
+	require.Equal(t, reason, openErr.Reason)
+	require.Equal(t, chanPoint, openErr.ChannelPoint)
+}
+
+// TestOpenChannelAndWait tests that the channel is only returned once it is
+// both open and active, and that the updates of other channels are ignored.
+func TestOpenChannelAndWait(t *testing.T) {
+	lnd := newMockChannelOpenClient()
+	notifier := newMockWatchNotifier()
+	chanPoint := lnd.chanPoint()
+
+	confirmed := make(chan *chainntnfs.TxConfirmation, 1)
+	result := openChannelAndWait(
+		context.Background(), lnd, notifier,
+		func(conf *chainntnfs.TxConfirmation) {
+			confirmed <- conf
+		},
+	)
+
+	// The confirmation of the funding transaction is watched.
+	reg := notifier.nextRegistration(t)
+	require.Equal(t, chanPoint.Hash, *reg.txid)
+
+	lnd.sendEvent(t, &PendingOpenChannelEvent{ChannelPoint: chanPoint})
+
+	reg.confirm(t, 101)
+	select {
+	case conf := <-confirmed:
+		require.EqualValues(t, 101, conf.BlockHeight)
+
+	case <-time.After(testTimeout):
+		t.Fatal("confirmation not delivered")
+	}
+
+	// The same events of another channel don't complete the open.
+	otherChanPoint := wire.OutPoint{Index: 1}
+	lnd.sendEvent(t, &OpenChannelEvent{
+		ChannelPoint: otherChanPoint,
+		Channel:      &ChannelInfo{ChannelID: 2},
+	})
+	lnd.sendEvent(t, &ActiveChannelEvent{ChannelPoint: otherChanPoint})
+
+	channel := &ChannelInfo{ChannelID: 1}
+	lnd.sendEvent(t, &OpenChannelEvent{
+		ChannelPoint: chanPoint,
+		Channel:      channel,
+	})
+
+	select {
+	case res := <-result:
+		t.Fatalf("channel open returned before activation: %v", res)
+
+	default:
+	}
+
+	lnd.sendEvent(t, &ActiveChannelEvent{ChannelPoint: chanPoint})
+
+	res := waitForOpenResult(t, result)
+	require.NoError(t, res.err)
+	require.Equal(t, channel, res.channel)
+
+	// The confirmation notification is canceled once we return.
+	select {
+	case <-reg.canceled:
+	case <-time.After(testTimeout):
+		t.Fatal("confirmation notification not canceled")
+	}
+}
+
+// TestOpenChannelAndWaitFailures tests that every way the channel can fail to
+// become active is reported with the right reason.
+func TestOpenChannelAndWaitFailures(t *testing.T) {
+	chanPoint := newMockChannelOpenClient().chanPoint()
+
+	tests := []struct {
+		name string
+
+		// openErr is the error returned when opening the channel.
+		openErr error
+
+		// fail makes the channel open fail once the funding
+		// transaction is watched.
+		fail func(*testing.T, *mockChannelOpenClient,
+			context.CancelFunc)
+
+		reason    ChannelOpenFailure
+		chanPoint *wire.OutPoint
+	}{
+		{
+			name:    "funding",
+			openErr: errors.New("peer rejected channel"),
+			reason:  ChannelOpenFailureFunding,
+		},
+		{
+			name: "closed",
+			fail: func(t *testing.T, lnd *mockChannelOpenClient,
+				_ context.CancelFunc) {
+
+				lnd.sendEvent(t, &ClosedChannelEvent{
+					ChannelPoint: chanPoint,
+					Channel: &ClosedChannel{
+						CloseType: CloseTypeCooperative,
+					},
+				})
+			},
+			reason:    ChannelOpenFailureClosed,
+			chanPoint: &chanPoint,
+		},
+		{
+			name: "subscription error",
+			fail: func(_ *testing.T, lnd *mockChannelOpenClient,
+				_ context.CancelFunc) {
+
+				lnd.eventErrs <- errors.New("stream failed")
+			},
+			reason:    ChannelOpenFailureSubscription,
+			chanPoint: &chanPoint,
+		},
+		{
+			name: "subscription closed",
+			fail: func(_ *testing.T, lnd *mockChannelOpenClient,
+				_ context.CancelFunc) {
+
+				close(lnd.events)
+			},
+			reason:    ChannelOpenFailureSubscription,
+			chanPoint: &chanPoint,
+		},
+		{
+			name: "canceled",
+			fail: func(_ *testing.T, _ *mockChannelOpenClient,
+				cancel context.CancelFunc) {
+
+				cancel()
+			},
+			reason:    ChannelOpenFailureCanceled,
+			chanPoint: &chanPoint,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			lnd := newMockChannelOpenClient()
+			lnd.openErr = test.openErr
+			notifier := newMockWatchNotifier()
+
+			result := openChannelAndWait(ctx, lnd, notifier, nil)
+
+			if test.fail != nil {
+				notifier.nextRegistration(t)
+				test.fail(t, lnd, cancel)
+			}
+
+			requireOpenError(
+				t, waitForOpenResult(t, result), test.reason,
+				test.chanPoint,
+			)
+		})
+	}
+}
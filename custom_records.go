@@ -0,0 +1,88 @@
+package lndclient
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/record"
+)
+
+const (
+	// KeysendMessageType is the custom record type that wallets commonly
+	// use to attach a text message to a spontaneous payment.
+	KeysendMessageType uint64 = 34349334
+
+	// PodcastType is the custom record type that podcasting apps use to
+	// attach boostagram metadata to a payment.
+	PodcastType uint64 = 7629169
+)
+
+// ValidateCustomRecords checks that all custom records of a payment are in the
+// range reserved for custom records.
+func ValidateCustomRecords(records map[uint64][]byte) error {
+	for key := range records {
+		if key < record.CustomTypeStart {
+			return fmt.Errorf("custom record type %v is below %v",
+				key, record.CustomTypeStart)
+		}
+	}
+
+	return nil
+}
+
+// WithKeysendPreimage adds the keysend record holding the given preimage to
+// the custom records and returns them. A new map is created if records is nil.
+func WithKeysendPreimage(records map[uint64][]byte,
+	preimage lntypes.Preimage) map[uint64][]byte {
+
+	return withRecord(records, record.KeySendType, preimage[:])
+}
+
+// WithMessage adds a text message record to the custom records and returns
+// them. A new map is created if records is nil.
+func WithMessage(records map[uint64][]byte, msg string) map[uint64][]byte {
+	return withRecord(records, KeysendMessageType, []byte(msg))
+}
+
+// WithPodcastMetadata adds a podcast record with the given serialized
+// metadata to the custom records and returns them. A new map is created if
+// records is nil.
+func WithPodcastMetadata(records map[uint64][]byte,
+	metadata []byte) map[uint64][]byte {
+
+	return withRecord(records, PodcastType, metadata)
+}
+
+// KeysendPreimage returns the preimage of the keysend record, if the custom
+// records contain one.
+func KeysendPreimage(records map[uint64][]byte) (*lntypes.Preimage, error) {
+	value, ok := records[record.KeySendType]
+	if !ok {
+		return nil, nil
+	}
+
+	preimage, err := lntypes.MakePreimage(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preimage, nil
+}
+
+// Message returns the text message record, if the custom records contain one.
+func Message(records map[uint64][]byte) (string, bool) {
+	value, ok := records[KeysendMessageType]
+	return string(value), ok
+}
+
+// withRecord sets a single custom record, creating the map if needed.
+func withRecord(records map[uint64][]byte, key uint64,
+	value []byte) map[uint64][]byte {
+
+	if records == nil {
+		records = make(map[uint64][]byte)
+	}
+	records[key] = value
+
+	return records
+}
@@ -0,0 +1,227 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ChannelFeePolicy is the forwarding fee policy of one of our channels.
+type ChannelFeePolicy struct {
+	// BaseFeeMsat is the base fee charged regardless of the number of
+	// milli-satoshis sent.
+	BaseFeeMsat lnwire.MilliSatoshi
+
+	// FeeRatePPM is the fee rate charged per million satoshis forwarded.
+	FeeRatePPM int64
+
+	// TimeLockDelta is the required timelock delta for HTLCs forwarded
+	// over the channel. If it is zero in a target policy, the current
+	// value of the channel is kept.
+	TimeLockDelta uint32
+}
+
+// FeePolicyConfig is a declarative description of the fee policies of all our
+// channels.
+type FeePolicyConfig struct {
+	// Default is the policy applied to all channels whose peer doesn't
+	// have an override. If it is nil, those channels are left unchanged.
+	Default *ChannelFeePolicy
+
+	// PeerOverrides holds the policy applied to all channels with a
+	// specific peer.
+	PeerOverrides map[route.Vertex]ChannelFeePolicy
+
+	// DryRun only computes the changes that would be made without
+	// updating any channel.
+	DryRun bool
+}
+
+// ChannelPolicyDiff describes the change of the fee policy of a channel.
+type ChannelPolicyDiff struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Peer is the remote node of the channel.
+	Peer route.Vertex
+
+	// Old is the policy of the channel before the update.
+	Old ChannelFeePolicy
+
+	// New is the policy of the channel after the update.
+	New ChannelFeePolicy
+}
+
+// FeePolicyResult is the result of applying a fee policy configuration.
+type FeePolicyResult struct {
+	// Updated holds the channels whose policy was changed, or would be
+	// changed in a dry run.
+	Updated []ChannelPolicyDiff
+
+	// Failed holds the channels whose policy couldn't be changed.
+	Failed []FailedPolicyUpdate
+}
+
+// ApplyFeePolicy brings the fee policies of all our open channels in line with
+// the given configuration. The current policies are read from the fee report
+// and the channel graph, and only channels whose policy differs from their
+// target are updated. Failures to look up or update single channels don't
+// abort the process, they are reported in the result instead.
+func ApplyFeePolicy(ctx context.Context, client LightningClient,
+	cfg FeePolicyConfig) (*FeePolicyResult, error) {
+
+	info, err := client.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	self := route.Vertex(info.IdentityPubkey)
+
+	channels, err := client.ListChannels(ctx, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := client.FeeReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fees := make(map[string]ChannelFeeReport, len(report.Channels))
+	for _, channel := range report.Channels {
+		fees[channel.ChannelPoint] = channel
+	}
+
+	result := &FeePolicyResult{}
+	for _, channel := range channels {
+		target, ok := cfg.PeerOverrides[channel.PubKeyBytes]
+		switch {
+		case ok:
+
+		case cfg.Default != nil:
+			target = *cfg.Default
+
+		default:
+			continue
+		}
+
+		chanPoint, err := NewOutpointFromStr(channel.ChannelPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		diff, err := getPolicyDiff(
+			ctx, client, self, channel, *chanPoint, fees, target,
+		)
+		if err != nil {
+			result.Failed = append(
+				result.Failed, failedUpdate(*chanPoint, err),
+			)
+			continue
+		}
+
+		if diff.Old == diff.New {
+			continue
+		}
+
+		if !cfg.DryRun {
+			failed := updateFeePolicy(ctx, client, diff)
+			if len(failed) != 0 {
+				result.Failed = append(result.Failed, failed...)
+				continue
+			}
+		}
+
+		result.Updated = append(result.Updated, *diff)
+	}
+
+	return result, nil
+}
+
+// getPolicyDiff looks up the current policy of a channel and returns the
+// change needed to reach the target policy.
+func getPolicyDiff(ctx context.Context, client LightningClient,
+	self route.Vertex, channel ChannelInfo, chanPoint wire.OutPoint,
+	fees map[string]ChannelFeeReport,
+	target ChannelFeePolicy) (*ChannelPolicyDiff, error) {
+
+	fee, ok := fees[channel.ChannelPoint]
+	if !ok {
+		return nil, fmt.Errorf("no fee report for channel %v",
+			channel.ChannelPoint)
+	}
+
+	// The fee report doesn't include the timelock delta, so we take it
+	// from our side of the channel edge.
+	edge, err := client.GetChanInfo(ctx, channel.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := edge.Node1Policy
+	if edge.Node2 == self {
+		policy = edge.Node2Policy
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("no local policy for channel %v",
+			channel.ChannelPoint)
+	}
+
+	old := ChannelFeePolicy{
+		BaseFeeMsat:   fee.BaseFeeMsat,
+		FeeRatePPM:    fee.FeePerMil,
+		TimeLockDelta: policy.TimeLockDelta,
+	}
+
+	if target.TimeLockDelta == 0 {
+		target.TimeLockDelta = old.TimeLockDelta
+	}
+
+	return &ChannelPolicyDiff{
+		ChannelID:    channel.ChannelID,
+		ChannelPoint: chanPoint,
+		Peer:         channel.PubKeyBytes,
+		Old:          old,
+		New:          target,
+	}, nil
+}
+
+// updateFeePolicy applies the new policy of a diff to its channel and returns
+// the failures, if any.
+func updateFeePolicy(ctx context.Context, client LightningClient,
+	diff *ChannelPolicyDiff) []FailedPolicyUpdate {
+
+	req := PolicyUpdateRequest{
+		BaseFeeMsat:   int64(diff.New.BaseFeeMsat),
+		FeeRatePPM:    uint32(diff.New.FeeRatePPM),
+		TimeLockDelta: diff.New.TimeLockDelta,
+	}
+
+	err := client.UpdateChanPolicy(ctx, req, &diff.ChannelPoint)
+	if err == nil {
+		return nil
+	}
+
+	var policyErr *PolicyUpdateError
+	if errors.As(err, &policyErr) {
+		return policyErr.FailedUpdates
+	}
+
+	return []FailedPolicyUpdate{failedUpdate(diff.ChannelPoint, err)}
+}
+
+// failedUpdate reports an error that prevented the update of a channel.
+func failedUpdate(chanPoint wire.OutPoint, err error) FailedPolicyUpdate {
+	return FailedPolicyUpdate{
+		ChannelPoint: chanPoint,
+		Reason:       lnrpc.UpdateFailure_UPDATE_FAILURE_INTERNAL_ERR,
+		Error:        err.Error(),
+	}
+}
@@ -0,0 +1,189 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// mockFeePolicyClient is a lightning client that serves the channel state
+// needed to apply a fee policy and records all policy updates.
+type mockFeePolicyClient struct {
+	LightningClient
+
+	self     route.Vertex
+	channels []ChannelInfo
+	fees     []ChannelFeeReport
+	edges    map[uint64]*ChannelEdge
+	updates  map[wire.OutPoint]PolicyUpdateRequest
+}
+
+func (m *mockFeePolicyClient) GetInfo(context.Context) (*Info, error) {
+	return &Info{IdentityPubkey: [33]byte(m.self)}, nil
+}
+
+func (m *mockFeePolicyClient) ListChannels(context.Context, bool, bool,
+	...ListChannelsOption) ([]ChannelInfo, error) {
+
+	return m.channels, nil
+}
+
+func (m *mockFeePolicyClient) FeeReport(context.Context) (*FeeReport,
+	error) {
+
+	return &FeeReport{Channels: m.fees}, nil
+}
+
+func (m *mockFeePolicyClient) GetChanInfo(_ context.Context,
+	chanID uint64) (*ChannelEdge, error) {
+
+	edge, ok := m.edges[chanID]
+	if !ok {
+		return nil, errors.New("edge not found")
+	}
+
+	return edge, nil
+}
+
+func (m *mockFeePolicyClient) UpdateChanPolicy(_ context.Context,
+	req PolicyUpdateRequest, chanPoint *wire.OutPoint) error {
+
+	m.updates[*chanPoint] = req
+	return nil
+}
+
+// addChannel adds a channel with the given policy. If withFee is false, the
+// channel is missing from the fee report, and if withEdge is false, its edge
+// can't be found in the graph.
+func (m *mockFeePolicyClient) addChannel(t *testing.T, peer route.Vertex,
+	policy ChannelFeePolicy, withFee, withEdge bool) wire.OutPoint {
+
+	chanID := uint64(len(m.channels) + 1)
+	chanPointStr := fmt.Sprintf("%064x:0", chanID)
+
+	m.channels = append(m.channels, ChannelInfo{
+		ChannelPoint: chanPointStr,
+		ChannelID:    chanID,
+		PubKeyBytes:  peer,
+	})
+
+	if withFee {
+		m.fees = append(m.fees, ChannelFeeReport{
+			ChannelID:    chanID,
+			ChannelPoint: chanPointStr,
+			BaseFeeMsat:  policy.BaseFeeMsat,
+			FeePerMil:    policy.FeeRatePPM,
+		})
+	}
+
+	if withEdge {
+		m.edges[chanID] = &ChannelEdge{
+			ChannelID: chanID,
+			Node1:     peer,
+			Node2:     m.self,
+			Node1Policy: &RoutingPolicy{
+				TimeLockDelta: 144,
+			},
+			Node2Policy: &RoutingPolicy{
+				TimeLockDelta: policy.TimeLockDelta,
+			},
+		}
+	}
+
+	chanPoint, err := NewOutpointFromStr(chanPointStr)
+	require.NoError(t, err)
+
+	return *chanPoint
+}
+
+// TestApplyFeePolicy tests that only channels whose policy differs from their
+// target are updated, and that channels that can't be looked up are reported
+// as failed without aborting the update of the other channels.
+func TestApplyFeePolicy(t *testing.T) {
+	var (
+		peer1 = route.Vertex{1}
+		peer2 = route.Vertex{2}
+
+		current = ChannelFeePolicy{
+			BaseFeeMsat:   1000,
+			FeeRatePPM:    100,
+			TimeLockDelta: 40,
+		}
+
+		// The default policy lowers the fee rate to the smallest
+		// non-zero rate and keeps the timelock delta.
+		target = ChannelFeePolicy{
+			BaseFeeMsat: 1000,
+			FeeRatePPM:  1,
+		}
+	)
+
+	client := &mockFeePolicyClient{
+		self:    route.Vertex{9},
+		edges:   make(map[uint64]*ChannelEdge),
+		updates: make(map[wire.OutPoint]PolicyUpdateRequest),
+	}
+
+	updated := client.addChannel(t, peer1, current, true, true)
+	client.addChannel(t, peer2, current, true, true)
+	noFee := client.addChannel(t, peer1, current, false, true)
+	noEdge := client.addChannel(t, peer1, current, true, false)
+
+	cfg := FeePolicyConfig{
+		Default: &target,
+		PeerOverrides: map[route.Vertex]ChannelFeePolicy{
+			peer2: current,
+		},
+		DryRun: true,
+	}
+
+	// A dry run reports the changes without updating any channel.
+	result, err := ApplyFeePolicy(context.Background(), client, cfg)
+	require.NoError(t, err)
+	require.Empty(t, client.updates)
+
+	expectedDiff := ChannelPolicyDiff{
+		ChannelID:    1,
+		ChannelPoint: updated,
+		Peer:         peer1,
+		Old:          current,
+		New: ChannelFeePolicy{
+			BaseFeeMsat:   1000,
+			FeeRatePPM:    1,
+			TimeLockDelta: 40,
+		},
+	}
+	require.Equal(t, []ChannelPolicyDiff{expectedDiff}, result.Updated)
+
+	require.Len(t, result.Failed, 2)
+	require.Equal(t, noFee, result.Failed[0].ChannelPoint)
+	require.Equal(t, noEdge, result.Failed[1].ChannelPoint)
+	for _, failed := range result.Failed {
+		require.Equal(
+			t, lnrpc.UpdateFailure_UPDATE_FAILURE_INTERNAL_ERR,
+			failed.Reason,
+		)
+	}
+
+	// Without the dry run, the fee rate is sent in parts per million, so
+	// that lnd applies it exactly.
+	cfg.DryRun = false
+	result, err = ApplyFeePolicy(context.Background(), client, cfg)
+	require.NoError(t, err)
+	require.Equal(t, []ChannelPolicyDiff{expectedDiff}, result.Updated)
+	require.Len(t, result.Failed, 2)
+
+	require.Equal(t, map[wire.OutPoint]PolicyUpdateRequest{
+		updated: {
+			BaseFeeMsat:   1000,
+			FeeRatePPM:    1,
+			TimeLockDelta: 40,
+		},
+	}, client.updates)
+}
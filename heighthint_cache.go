@@ -0,0 +1,255 @@
+package lndclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/kvdb"
+)
+
+var (
+	// ErrHintNotFound is returned by a HeightHintCache if no height hint
+	// is known for the requested key.
+	ErrHintNotFound = errors.New("height hint not found")
+
+	// errBucketNotFound is returned if the height hint database was not
+	// initialized properly.
+	errBucketNotFound = errors.New("height hint bucket not found")
+
+	// confirmHintBucket is the name of the bucket that stores the height
+	// hints of confirmation notifications.
+	confirmHintBucket = []byte("confirm-hints")
+
+	// spendHintBucket is the name of the bucket that stores the height
+	// hints of spend notifications.
+	spendHintBucket = []byte("spend-hints")
+)
+
+// HeightHintCache is a persistent store of the height hints used when
+// registering chain notifications. The chain notifier client consults the
+// cache when a notification is registered and updates it with the heights of
+// the confirmations and spends it observes, so that a registration made after
+// a restart doesn't need to start scanning from the height hint originally
+// supplied by the caller.
+type HeightHintCache interface {
+	// CommitConfirmHint stores the height hint for the confirmation
+	// notification identified by the given key.
+	CommitConfirmHint(key []byte, height int32) error
+
+	// QueryConfirmHint returns the height hint for the confirmation
+	// notification identified by the given key. ErrHintNotFound is
+	// returned if no hint is known.
+	QueryConfirmHint(key []byte) (int32, error)
+
+	// CommitSpendHint stores the height hint for the spend notification
+	// identified by the given key.
+	CommitSpendHint(key []byte, height int32) error
+
+	// QuerySpendHint returns the height hint for the spend notification
+	// identified by the given key. ErrHintNotFound is returned if no hint
+	// is known.
+	QuerySpendHint(key []byte) (int32, error)
+}
+
+// confirmHintKey returns the key that identifies a confirmation notification
+// for the given txid and pkScript in the height hint cache.
+func confirmHintKey(txid *chainhash.Hash, pkScript []byte) []byte {
+	var b bytes.Buffer
+	if txid != nil {
+		b.Write(txid[:])
+	}
+	b.Write(pkScript)
+
+	return b.Bytes()
+}
+
+// spendHintKey returns the key that identifies a spend notification for the
+// given outpoint and pkScript in the height hint cache.
+func spendHintKey(outpoint *wire.OutPoint, pkScript []byte) []byte {
+	var b bytes.Buffer
+	if outpoint != nil {
+		var index [4]byte
+		binary.BigEndian.PutUint32(index[:], outpoint.Index)
+
+		b.Write(outpoint.Hash[:])
+		b.Write(index[:])
+	}
+	b.Write(pkScript)
+
+	return b.Bytes()
+}
+
+// memHeightHintCache is a HeightHintCache that keeps all hints in memory.
+type memHeightHintCache struct {
+	confirmHints map[string]int32
+	spendHints   map[string]int32
+
+	sync.Mutex
+}
+
+// A compile-time constraint to ensure memHeightHintCache satisfies the
+// HeightHintCache interface.
+var _ HeightHintCache = (*memHeightHintCache)(nil)
+
+// NewMemHeightHintCache returns a HeightHintCache that keeps all hints in
+// memory. The hints are lost when the process exits.
+func NewMemHeightHintCache() HeightHintCache {
+	return &memHeightHintCache{
+		confirmHints: make(map[string]int32),
+		spendHints:   make(map[string]int32),
+	}
+}
+
+// CommitConfirmHint stores the height hint for the confirmation notification
+// identified by the given key.
+func (m *memHeightHintCache) CommitConfirmHint(key []byte, height int32) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.confirmHints[string(key)] = height
+	return nil
+}
+
+// QueryConfirmHint returns the height hint for the confirmation notification
+// identified by the given key.
+func (m *memHeightHintCache) QueryConfirmHint(key []byte) (int32, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	height, ok := m.confirmHints[string(key)]
+	if !ok {
+		return 0, ErrHintNotFound
+	}
+
+	return height, nil
+}
+
+// CommitSpendHint stores the height hint for the spend notification identified
+// by the given key.
+func (m *memHeightHintCache) CommitSpendHint(key []byte, height int32) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.spendHints[string(key)] = height
+	return nil
+}
+
+// QuerySpendHint returns the height hint for the spend notification identified
+// by the given key.
+func (m *memHeightHintCache) QuerySpendHint(key []byte) (int32, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	height, ok := m.spendHints[string(key)]
+	if !ok {
+		return 0, ErrHintNotFound
+	}
+
+	return height, nil
+}
+
+// boltHeightHintCache is a HeightHintCache that persists all hints in a kvdb
+// backend.
+type boltHeightHintCache struct {
+	db kvdb.Backend
+}
+
+// A compile-time constraint to ensure boltHeightHintCache satisfies the
+// HeightHintCache interface.
+var _ HeightHintCache = (*boltHeightHintCache)(nil)
+
+// NewBoltHeightHintCache returns a HeightHintCache that persists all hints in
+// the given database, which can for example be opened with
+// kvdb.GetBoltBackend. The caller remains responsible for closing the
+// database.
+func NewBoltHeightHintCache(db kvdb.Backend) (HeightHintCache, error) {
+	err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		_, err := tx.CreateTopLevelBucket(confirmHintBucket)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateTopLevelBucket(spendHintBucket)
+		return err
+	}, func() {})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltHeightHintCache{
+		db: db,
+	}, nil
+}
+
+// CommitConfirmHint stores the height hint for the confirmation notification
+// identified by the given key.
+func (b *boltHeightHintCache) CommitConfirmHint(key []byte, height int32) error {
+	return b.commitHint(confirmHintBucket, key, height)
+}
+
+// QueryConfirmHint returns the height hint for the confirmation notification
+// identified by the given key.
+func (b *boltHeightHintCache) QueryConfirmHint(key []byte) (int32, error) {
+	return b.queryHint(confirmHintBucket, key)
+}
+
+// CommitSpendHint stores the height hint for the spend notification identified
+// by the given key.
+func (b *boltHeightHintCache) CommitSpendHint(key []byte, height int32) error {
+	return b.commitHint(spendHintBucket, key, height)
+}
+
+// QuerySpendHint returns the height hint for the spend notification identified
+// by the given key.
+func (b *boltHeightHintCache) QuerySpendHint(key []byte) (int32, error) {
+	return b.queryHint(spendHintBucket, key)
+}
+
+// commitHint stores the given height under the key in the given bucket.
+func (b *boltHeightHintCache) commitHint(bucketName, key []byte,
+	height int32) error {
+
+	var value [4]byte
+	binary.BigEndian.PutUint32(value[:], uint32(height))
+
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(bucketName)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.Put(key, value[:])
+	}, func() {})
+}
+
+// queryHint returns the height stored under the key in the given bucket.
+func (b *boltHeightHintCache) queryHint(bucketName, key []byte) (int32,
+	error) {
+
+	var height int32
+	err := kvdb.View(b.db, func(tx kvdb.RTx) error {
+		bucket := tx.ReadBucket(bucketName)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		value := bucket.Get(key)
+		if len(value) != 4 {
+			return ErrHintNotFound
+		}
+
+		height = int32(binary.BigEndian.Uint32(value))
+		return nil
+	}, func() {
+		height = 0
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
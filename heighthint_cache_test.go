@@ -0,0 +1,83 @@
+package lndclient
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHeightHintCache tests that both height hint cache implementations
+// store and return confirm and spend hints independently of each other.
+func TestHeightHintCache(t *testing.T) {
+	// Create a temporary directory where we can store the height hint db
+	// we are about to create.
+	tempDirPath, err := ioutil.TempDir("", ".testHeightHints")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDirPath)
+
+	db, err := kvdb.GetBoltBackend(&kvdb.BoltBackendConfig{
+		DBPath:     tempDirPath,
+		DBFileName: "heighthints.db",
+		DBTimeout:  defaultDBTimeout,
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	boltCache, err := NewBoltHeightHintCache(db)
+	require.NoError(t, err)
+
+	caches := map[string]HeightHintCache{
+		"memory": NewMemHeightHintCache(),
+		"bolt":   boltCache,
+	}
+
+	txid := chainhash.Hash{1, 2, 3}
+	confKey := confirmHintKey(&txid, []byte{4, 5, 6})
+	spendKey := spendHintKey(
+		&wire.OutPoint{Hash: txid, Index: 1}, []byte{4, 5, 6},
+	)
+
+	for name, cache := range caches {
+		cache := cache
+
+		t.Run(name, func(t *testing.T) {
+			_, err := cache.QueryConfirmHint(confKey)
+			require.Equal(t, ErrHintNotFound, err)
+
+			_, err = cache.QuerySpendHint(spendKey)
+			require.Equal(t, ErrHintNotFound, err)
+
+			require.NoError(t, cache.CommitConfirmHint(confKey, 100))
+
+			hint, err := cache.QueryConfirmHint(confKey)
+			require.NoError(t, err)
+			require.Equal(t, int32(100), hint)
+
+			// A confirm hint must not be returned as a spend
+			// hint.
+			_, err = cache.QuerySpendHint(confKey)
+			require.Equal(t, ErrHintNotFound, err)
+
+			require.NoError(t, cache.CommitSpendHint(spendKey, 200))
+
+			hint, err = cache.QuerySpendHint(spendKey)
+			require.NoError(t, err)
+			require.Equal(t, int32(200), hint)
+
+			// Committing a hint again overwrites the previous
+			// one.
+			require.NoError(t, cache.CommitConfirmHint(confKey, 50))
+
+			hint, err = cache.QueryConfirmHint(confKey)
+			require.NoError(t, err)
+			require.Equal(t, int32(50), hint)
+		})
+	}
+}
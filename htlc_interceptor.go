@@ -0,0 +1,220 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// defaultReplayWindow is the default time for which the resolution of an
+// intercepted htlc is remembered, so that it can be replayed if lnd delivers
+// the htlc again.
+const defaultReplayWindow = 10 * time.Minute
+
+// HtlcInterceptorConfig holds the configuration of an HtlcInterceptor.
+type HtlcInterceptorConfig struct {
+	// Router is the client used to intercept htlcs.
+	Router RouterClient
+
+	// Handler decides about all intercepted htlcs. It is called at most
+	// once per htlc, even if lnd delivers the htlc again after the
+	// interception stream was re-established. The context passed to the
+	// handler is only canceled when the interceptor is stopped.
+	Handler HtlcInterceptHandler
+
+	// ReplayWindow is the time for which the resolution of an htlc is
+	// remembered. If lnd delivers the htlc again within this time, the
+	// same resolution is sent without calling the handler. If zero, a
+	// default is used.
+	ReplayWindow time.Duration
+
+	// InitialBackoff is the time to wait before re-establishing the
+	// interception stream after it failed. If zero, a default is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper bound of the time to wait between attempts
+	// to re-establish the stream. If zero, a default is used.
+	MaxBackoff time.Duration
+}
+
+// interceptedResolution is the resolution of an intercepted htlc, shared by
+// all deliveries of the same htlc.
+type interceptedResolution struct {
+	// done is closed once the handler returned.
+	done chan struct{}
+
+	// resolvedAt is the time the handler returned.
+	resolvedAt time.Time
+
+	resp *InterceptedHtlcResponse
+	err  error
+}
+
+// HtlcInterceptor keeps an htlc interceptor registered with lnd. If the
+// interception stream fails, for example because lnd restarted, it is
+// re-established with an exponential backoff until the interceptor is
+// stopped.
+//
+// Htlcs that are held while the stream is down are released by lnd. lnd
+// versions that hold them instead deliver them again once the stream is
+// re-established. The interceptor deduplicates these deliveries by the htlc's
+// incoming circuit key, so that a handler that is still deciding isn't called
+// a second time and a decision that was lost with the stream is sent again.
+type HtlcInterceptor struct {
+	cfg *HtlcInterceptorConfig
+
+	resolutions   map[channeldb.CircuitKey]*interceptedResolution
+	resolutionsMu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewHtlcInterceptor creates a new htlc interceptor. Start must be called to
+// register it with lnd.
+func NewHtlcInterceptor(cfg *HtlcInterceptorConfig) *HtlcInterceptor {
+	if cfg.ReplayWindow == 0 {
+		cfg.ReplayWindow = defaultReplayWindow
+	}
+
+	return &HtlcInterceptor{
+		cfg: cfg,
+		resolutions: make(
+			map[channeldb.CircuitKey]*interceptedResolution,
+		),
+	}
+}
+
+// Start registers the interceptor with lnd and keeps it registered until Stop
+// is called or the given context is canceled.
+func (i *HtlcInterceptor) Start(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.cancel != nil {
+		return errors.New("htlc interceptor already started")
+	}
+
+	i.ctx, i.cancel = context.WithCancel(ctx)
+
+	i.wg.Add(1)
+	go i.run()
+
+	return nil
+}
+
+// Stop deregisters the interceptor and waits for its goroutine to exit. lnd
+// releases all htlcs that are still held.
+func (i *HtlcInterceptor) Stop() {
+	i.stopOnce.Do(func() {
+		i.mu.Lock()
+		if i.cancel != nil {
+			i.cancel()
+		}
+		i.mu.Unlock()
+
+		i.wg.Wait()
+	})
+}
+
+// run intercepts htlcs and re-establishes the interception stream whenever it
+// fails, until the context is canceled or the router shuts down.
+func (i *HtlcInterceptor) run() {
+	defer i.wg.Done()
+
+	b := newBackoff(i.cfg.InitialBackoff, i.cfg.MaxBackoff)
+	for {
+		start := time.Now()
+		err := i.cfg.Router.InterceptHtlcs(i.ctx, i.intercept)
+		if i.ctx.Err() != nil || err == ErrRouterShuttingDown {
+			return
+		}
+
+		// If the stream was up for longer than our current backoff,
+		// this isn't a repeated failure and we start over with the
+		// initial backoff.
+		if time.Since(start) > b.next {
+			b.reset()
+		}
+
+		log.Warnf("HTLC interceptor failed, re-registering in %v: %v",
+			b.next, err)
+
+		if !b.wait(i.ctx) {
+			return
+		}
+	}
+}
+
+// intercept hands an intercepted htlc to the handler, unless it was delivered
+// before. In that case, the resolution of the first delivery is returned.
+func (i *HtlcInterceptor) intercept(ctx context.Context,
+	htlc InterceptedHtlc) (*InterceptedHtlcResponse, error) {
+
+	key := htlc.IncomingCircuitKey
+
+	i.resolutionsMu.Lock()
+	i.pruneResolutions()
+
+	resolution, ok := i.resolutions[key]
+	if !ok {
+		resolution = &interceptedResolution{
+			done: make(chan struct{}),
+		}
+		i.resolutions[key] = resolution
+	}
+	i.resolutionsMu.Unlock()
+
+	if ok {
+		log.Debugf("Replaying resolution of htlc %v", key)
+
+		select {
+		case <-resolution.done:
+			return resolution.resp, resolution.err
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The handler gets our long-lived context, so that it isn't canceled
+	// if the stream the htlc was delivered on fails.
+	resp, err := i.cfg.Handler(i.ctx, htlc)
+
+	i.resolutionsMu.Lock()
+	resolution.resp = resp
+	resolution.err = err
+	resolution.resolvedAt = time.Now()
+
+	// We don't replay errors, a new delivery of the htlc gives the
+	// handler another chance.
+	if err != nil {
+		delete(i.resolutions, key)
+	}
+	i.resolutionsMu.Unlock()
+
+	close(resolution.done)
+
+	return resp, err
+}
+
+// pruneResolutions removes all resolutions that are older than the replay
+// window. The caller must hold the resolutions mutex.
+func (i *HtlcInterceptor) pruneResolutions() {
+	for key, resolution := range i.resolutions {
+		if resolution.resolvedAt.IsZero() {
+			continue
+		}
+
+		if time.Since(resolution.resolvedAt) > i.cfg.ReplayWindow {
+			delete(i.resolutions, key)
+		}
+	}
+}
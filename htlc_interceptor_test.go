@@ -0,0 +1,272 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// mockInterceptStream is an htlc interception stream opened on the mock
+// router.
+type mockInterceptStream struct {
+	routerrpc.Router_HtlcInterceptorClient
+
+	ctx       context.Context
+	requests  chan *routerrpc.ForwardHtlcInterceptRequest
+	errs      chan error
+	responses chan *routerrpc.ForwardHtlcInterceptResponse
+
+	// sendAttempts counts all calls to Send, including the ones made
+	// after the stream was closed. It must be accessed atomically.
+	sendAttempts int32
+}
+
+func (m *mockInterceptStream) Recv() (
+	*routerrpc.ForwardHtlcInterceptRequest, error) {
+
+	select {
+	case request := <-m.requests:
+		return request, nil
+
+	case err := <-m.errs:
+		return nil, err
+
+	case <-m.ctx.Done():
+		return nil, m.ctx.Err()
+	}
+}
+
+func (m *mockInterceptStream) Send(
+	resp *routerrpc.ForwardHtlcInterceptResponse) error {
+
+	atomic.AddInt32(&m.sendAttempts, 1)
+
+	select {
+	case m.responses <- resp:
+		return nil
+
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	}
+}
+
+// intercept delivers an htlc with the given id on the stream.
+func (m *mockInterceptStream) intercept(t *testing.T, htlcID uint64) {
+	select {
+	case m.requests <- &routerrpc.ForwardHtlcInterceptRequest{
+		IncomingCircuitKey: &routerrpc.CircuitKey{
+			ChanId: 1,
+			HtlcId: htlcID,
+		},
+		PaymentHash: make([]byte, lntypes.HashSize),
+	}:
+
+	case <-time.After(testTimeout):
+		t.Fatal("htlc not consumed")
+	}
+}
+
+func (m *mockInterceptStream) nextResponse(
+	t *testing.T) *routerrpc.ForwardHtlcInterceptResponse {
+
+	select {
+	case resp := <-m.responses:
+		return resp
+
+	case <-time.After(testTimeout):
+		t.Fatal("no interception response")
+		return nil
+	}
+}
+
+// mockInterceptorRPC is a router rpc client that hands all interception
+// streams to the test.
+type mockInterceptorRPC struct {
+	routerrpc.RouterClient
+
+	streams chan *mockInterceptStream
+}
+
+func (m *mockInterceptorRPC) HtlcInterceptor(ctx context.Context,
+	_ ...grpc.CallOption) (routerrpc.Router_HtlcInterceptorClient, error) {
+
+	stream := &mockInterceptStream{
+		ctx:      ctx,
+		requests: make(chan *routerrpc.ForwardHtlcInterceptRequest),
+		errs:     make(chan error, 1),
+		responses: make(
+			chan *routerrpc.ForwardHtlcInterceptResponse, 10,
+		),
+	}
+	m.streams <- stream
+
+	return stream, nil
+}
+
+func (m *mockInterceptorRPC) nextStream(t *testing.T) *mockInterceptStream {
+	select {
+	case stream := <-m.streams:
+		return stream
+
+	case <-time.After(testTimeout):
+		t.Fatal("no interception stream opened")
+		return nil
+	}
+}
+
+// newTestHtlcInterceptor creates and starts an htlc interceptor that uses a
+// router client backed by a mock rpc client.
+func newTestHtlcInterceptor(t *testing.T, handler HtlcInterceptHandler) (
+	*HtlcInterceptor, *routerClient, *mockInterceptorRPC) {
+
+	rpc := &mockInterceptorRPC{
+		streams: make(chan *mockInterceptStream, 10),
+	}
+	router := &routerClient{
+		client: rpc,
+		quit:   make(chan struct{}),
+	}
+
+	interceptor := NewHtlcInterceptor(&HtlcInterceptorConfig{
+		Router:         router,
+		Handler:        handler,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+	require.NoError(t, interceptor.Start(context.Background()))
+
+	return interceptor, router, rpc
+}
+
+// TestHtlcInterceptorResponses tests that the decisions of the handler are
+// sent to lnd for the htlc they were made for.
+func TestHtlcInterceptorResponses(t *testing.T) {
+	preimage := lntypes.Preimage{1}
+	decisions := map[uint64]*InterceptedHtlcResponse{
+		1: {Action: InterceptorActionResume},
+		2: {Action: InterceptorActionFail},
+		3: {
+			Action:   InterceptorActionSettle,
+			Preimage: &preimage,
+		},
+
+		// A nil response resumes the htlc.
+		4: nil,
+	}
+
+	handler := func(_ context.Context,
+		htlc InterceptedHtlc) (*InterceptedHtlcResponse, error) {
+
+		return decisions[htlc.IncomingCircuitKey.HtlcID], nil
+	}
+
+	interceptor, _, rpc := newTestHtlcInterceptor(t, handler)
+	defer interceptor.Stop()
+
+	stream := rpc.nextStream(t)
+
+	tests := []struct {
+		htlcID   uint64
+		action   routerrpc.ResolveHoldForwardAction
+		preimage []byte
+	}{
+		{
+			htlcID: 1,
+			action: routerrpc.ResolveHoldForwardAction_RESUME,
+		},
+		{
+			htlcID: 2,
+			action: routerrpc.ResolveHoldForwardAction_FAIL,
+		},
+		{
+			htlcID:   3,
+			action:   routerrpc.ResolveHoldForwardAction_SETTLE,
+			preimage: preimage[:],
+		},
+		{
+			htlcID: 4,
+			action: routerrpc.ResolveHoldForwardAction_RESUME,
+		},
+	}
+
+	for _, test := range tests {
+		stream.intercept(t, test.htlcID)
+
+		resp := stream.nextResponse(t)
+		require.Equal(t, &routerrpc.CircuitKey{
+			ChanId: 1,
+			HtlcId: test.htlcID,
+		}, resp.IncomingCircuitKey)
+		require.Equal(t, test.action, resp.Action)
+		require.Equal(t, test.preimage, resp.Preimage)
+	}
+}
+
+// TestHtlcInterceptorStreamFailure tests that the interception stream is
+// re-established after it failed, that the resolution of an htlc that is
+// delivered again is replayed without calling the handler a second time and
+// that stopping the interceptor closes the stream.
+func TestHtlcInterceptorStreamFailure(t *testing.T) {
+	handled := make(chan InterceptedHtlc, 10)
+	handler := func(_ context.Context,
+		htlc InterceptedHtlc) (*InterceptedHtlcResponse, error) {
+
+		handled <- htlc
+		return &InterceptedHtlcResponse{
+			Action: InterceptorActionFail,
+		}, nil
+	}
+
+	interceptor, _, rpc := newTestHtlcInterceptor(t, handler)
+
+	stream := rpc.nextStream(t)
+	stream.intercept(t, 1)
+	require.Equal(
+		t, routerrpc.ResolveHoldForwardAction_FAIL,
+		stream.nextResponse(t).Action,
+	)
+
+	stream.errs <- errors.New("lnd restarted")
+
+	stream = rpc.nextStream(t)
+	stream.intercept(t, 1)
+	require.Equal(
+		t, routerrpc.ResolveHoldForwardAction_FAIL,
+		stream.nextResponse(t).Action,
+	)
+	require.Len(t, handled, 1)
+
+	interceptor.Stop()
+	require.Error(t, stream.ctx.Err())
+}
+
+// TestHtlcInterceptorRouterShutdown tests that the interceptor doesn't try to
+// re-establish its stream once the router is shut down.
+func TestHtlcInterceptorRouterShutdown(t *testing.T) {
+	interceptor, router, rpc := newTestHtlcInterceptor(
+		t, func(context.Context,
+			InterceptedHtlc) (*InterceptedHtlcResponse, error) {
+
+			return nil, nil
+		},
+	)
+	defer interceptor.Stop()
+
+	stream := rpc.nextStream(t)
+	router.WaitForFinished()
+	require.Error(t, stream.ctx.Err())
+
+	select {
+	case <-rpc.streams:
+		t.Fatal("interception stream re-established")
+
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -21,6 +23,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/zpay32"
 	"google.golang.org/grpc"
@@ -36,15 +39,47 @@ type LightningClient interface {
 
 	GetInfo(ctx context.Context) (*Info, error)
 
+	// GetRecoveryInfo returns the state of the wallet recovery of the
+	// backing lnd node.
+	GetRecoveryInfo(ctx context.Context) (*RecoveryInfo, error)
+
+	// SetDebugLevel changes the log levels of lnd at runtime. The level
+	// spec is either a single level for all subsystems or a comma
+	// separated list of <subsystem>=<level> pairs. If show is true, the
+	// level spec is ignored and the list of available subsystems is
+	// returned instead.
+	SetDebugLevel(ctx context.Context, show bool, levelSpec string) (
+		string, error)
+
+	// StopDaemon requests a graceful shutdown of the backing lnd node.
+	StopDaemon(ctx context.Context) error
+
 	EstimateFeeToP2WSH(ctx context.Context, amt btcutil.Amount,
 		confTarget int32) (btcutil.Amount, error)
 
+	// EstimateFee estimates the fee of a transaction paying to the given
+	// outputs, which map encoded addresses to amounts. The options
+	// restrict the outputs that may be selected to fund the transaction.
+	EstimateFee(ctx context.Context, outputs map[string]btcutil.Amount,
+		confTarget int32, opts ...SendOption) (*FeeEstimate, error)
+
 	// WalletBalance returns a summary of the node's wallet balance.
 	WalletBalance(ctx context.Context) (*WalletBalance, error)
 
+	// NewAddress generates a new address of the given type. If account is
+	// empty, the address is derived from the default wallet account.
+	NewAddress(ctx context.Context, addrType AddressType,
+		account string) (btcutil.Address, error)
+
 	AddInvoice(ctx context.Context, in *invoicesrpc.AddInvoiceData) (
 		lntypes.Hash, string, error)
 
+	// CreateInvoice adds an invoice to lnd with the full set of options
+	// of an invoice request. Unlike AddInvoice, the invoice is only made
+	// private if requested.
+	CreateInvoice(ctx context.Context, in *InvoiceRequest) (
+		*AddInvoiceResult, error)
+
 	// LookupInvoice looks up an invoice by hash.
 	LookupInvoice(ctx context.Context, hash lntypes.Hash) (*Invoice, error)
 
@@ -52,18 +87,30 @@ type LightningClient interface {
 	// node. It takes a start and end block height which can be used to
 	// limit the block range that we query over. These values can be left
 	// as zero to include all blocks. To include unconfirmed transactions
-	// in the query, endHeight must be set to -1.
-	ListTransactions(ctx context.Context, startHeight,
-		endHeight int32) ([]Transaction, error)
+	// in the query, endHeight must be set to -1. The options can be used
+	// to restrict the query to a single wallet account.
+	ListTransactions(ctx context.Context, startHeight, endHeight int32,
+		opts ...ListTransactionsOption) ([]Transaction, error)
+
+	// SubscribeTransactions subscribes to on chain transactions relevant
+	// to our wallet. An update is sent when a transaction is first seen
+	// and again when it confirms.
+	SubscribeTransactions(ctx context.Context) (<-chan *Transaction,
+		<-chan error, error)
 
-	// ListChannels retrieves all channels of the backing lnd node.
-	ListChannels(ctx context.Context, activeOnly, publicOnly bool) ([]ChannelInfo, error)
+	// ListChannels retrieves all channels of the backing lnd node. The
+	// channels can be further filtered server-side with the given options.
+	ListChannels(ctx context.Context, activeOnly, publicOnly bool,
+		opts ...ListChannelsOption) ([]ChannelInfo, error)
 
 	// PendingChannels returns a list of lnd's pending channels.
 	PendingChannels(ctx context.Context) (*PendingChannels, error)
 
 	// ClosedChannels returns all closed channels of the backing lnd node.
-	ClosedChannels(ctx context.Context) ([]ClosedChannel, error)
+	// The channels can be filtered by their close type with the given
+	// options.
+	ClosedChannels(ctx context.Context,
+		opts ...ClosedChannelsOption) ([]ClosedChannel, error)
 
 	// ForwardingHistory makes a paginated call to our forwarding history
 	// endpoint.
@@ -87,6 +134,17 @@ type LightningClient interface {
 	// chanbackup.Multi payload.
 	ChannelBackups(ctx context.Context) ([]byte, error)
 
+	// VerifyChannelBackup verifies that the given channel backups can be
+	// decrypted and parsed by the backing lnd node.
+	VerifyChannelBackup(ctx context.Context, backups ChannelBackupSet) error
+
+	// RestoreChannelBackups restores the given channel backups. The
+	// backing lnd node will connect to the channel peers and ask them to
+	// force close the channels, after which the funds are swept back to
+	// our wallet.
+	RestoreChannelBackups(ctx context.Context,
+		backups ChannelBackupSet) error
+
 	// SubscribeChannelBackups allows a client to subscribe to the
 	// most up to date information concerning the state of all channel
 	// backups.
@@ -109,17 +167,37 @@ type LightningClient interface {
 		localSat, pushSat btcutil.Amount, private bool) (
 		*wire.OutPoint, error)
 
-	// CloseChannel closes the channel provided.
+	// BatchOpenChannel opens multiple channels to the peers provided with
+	// a single funding transaction.
+	BatchOpenChannel(ctx context.Context, req *BatchOpenChannelRequest) (
+		*BatchOpenChannelResult, error)
+
+	// CloseChannel closes the channel provided. The fee of the closing
+	// transaction can be further configured with the given options.
 	CloseChannel(ctx context.Context, channel *wire.OutPoint,
-		force bool, confTarget int32, deliveryAddr btcutil.Address) (
-		chan CloseChannelUpdate, chan error, error)
+		force bool, confTarget int32, deliveryAddr btcutil.Address,
+		opts ...CloseChannelOption) (chan CloseChannelUpdate,
+		chan error, error)
+
+	// AbandonChannel removes all channel state from the database of the
+	// backing lnd node without attempting to close the channel on chain.
+	// This is meant for channels whose funding transaction will never
+	// confirm and should only be used as a last resort.
+	AbandonChannel(ctx context.Context, channel *wire.OutPoint,
+		opts ...AbandonChannelOption) error
 
 	// UpdateChanPolicy updates the channel policy for the passed chanPoint.
 	// If the chanPoint is nil, then the policy is applied for all existing
-	// channels.
+	// channels. If the policy couldn't be applied to some channels, a
+	// *PolicyUpdateError describing each failure is returned.
 	UpdateChanPolicy(ctx context.Context, req PolicyUpdateRequest,
 		chanPoint *wire.OutPoint) error
 
+	// FeeReport returns the current fee policies of all our channels and
+	// the fees we earned from forwarding over the last day, week and
+	// month.
+	FeeReport(ctx context.Context) (*FeeReport, error)
+
 	// GetChanInfo returns the channel info for the passed channel,
 	// including the routing policy for both end.
 	GetChanInfo(ctx context.Context, chanID uint64) (*ChannelEdge, error)
@@ -127,12 +205,21 @@ type LightningClient interface {
 	// ListPeers gets a list the peers we are currently connected to.
 	ListPeers(ctx context.Context) ([]Peer, error)
 
+	// SubscribePeerEvents allows a client to subscribe to peers coming
+	// online and going offline.
+	SubscribePeerEvents(ctx context.Context) (<-chan *PeerEvent,
+		<-chan error, error)
+
 	// Connect attempts to connect to a peer at the host specified. If
 	// permanent is true then we'll attempt to connect to the peer
 	// permanently, meaning that the connection is maintained even if no
-	// channels exist between us and the peer.
+	// channels exist between us and the peer. The connection attempt can
+	// be further configured with the given options.
 	Connect(ctx context.Context, peer route.Vertex, host string,
-		permanent bool) error
+		permanent bool, opts ...ConnectOption) error
+
+	// Disconnect disconnects from the given peer.
+	Disconnect(ctx context.Context, peer route.Vertex) error
 
 	// SendCoins sends the passed amount of (or all) coins to the passed
 	// address. Either amount or sendAll must be specified, while
@@ -141,7 +228,17 @@ type LightningClient interface {
 	// upon success.
 	SendCoins(ctx context.Context, addr btcutil.Address,
 		amount btcutil.Amount, sendAll bool, confTarget int32,
-		satsPerByte int64, label string) (string, error)
+		satsPerByte int64, label string, opts ...SendOption) (string,
+		error)
+
+	// SendMany sends coins to multiple addresses in a single transaction.
+	// The outputs map the encoded addresses to the amounts to send to
+	// them. Either confTarget or satPerVbyte may be set, or both left zero
+	// to use the automatic conf target and fee. Returns the tx id upon
+	// success.
+	SendMany(ctx context.Context, outputs map[string]btcutil.Amount,
+		confTarget int32, satPerVbyte uint64, label string,
+		opts ...SendOption) (*chainhash.Hash, error)
 
 	// ChannelBalance returns a summary of our channel balances.
 	ChannelBalance(ctx context.Context) (*ChannelBalance, error)
@@ -153,6 +250,12 @@ type LightningClient interface {
 	// DescribeGraph returns our view of the graph.
 	DescribeGraph(ctx context.Context, includeUnannounced bool) (*Graph, error)
 
+	// WalkGraph queries our view of the graph and passes each node and
+	// edge to the given callbacks one by one, instead of building the
+	// full typed graph in memory. Either callback may be nil.
+	WalkGraph(ctx context.Context, includeUnannounced bool,
+		nodeCb func(*Node) error, edgeCb func(*ChannelEdge) error) error
+
 	// SubscribeGraph allows a client to subscribe to gaph topology updates.
 	SubscribeGraph(ctx context.Context) (<-chan *GraphTopologyUpdate,
 		<-chan error, error)
@@ -206,6 +309,18 @@ type LightningClient interface {
 	// received from our peers.
 	SubscribeCustomMessages(ctx context.Context) (<-chan CustomMessage,
 		<-chan error, error)
+
+	// SignMessage signs a message with the identity key of the backing
+	// lnd node and returns the zbase32 encoded signature.
+	SignMessage(ctx context.Context, msg []byte) (string, error)
+
+	// VerifyMessage verifies a zbase32 encoded signature over a message.
+	// The signature is only reported as valid if the signing key belongs
+	// to a node in the channel graph of the backing lnd node. The public
+	// key recovered from the signature is returned if there is one. If no
+	// key can be recovered, an empty key is returned without an error.
+	VerifyMessage(ctx context.Context, msg []byte, signature string) (
+		route.Vertex, bool, error)
 }
 
 // Info contains info about the connected lnd node.
@@ -247,6 +362,21 @@ type Info struct {
 
 	// PendingChannels is the number of pending channels we have.
 	PendingChannels uint32
+
+	// Chains is the list of chains and networks lnd is operating on.
+	Chains []Chain
+
+	// Features is the set of features our node advertises.
+	Features []lnwire.FeatureBit
+}
+
+// Chain describes a chain and the network of that chain lnd is operating on.
+type Chain struct {
+	// Chain is the name of the chain, for example bitcoin.
+	Chain string
+
+	// Network is the network of the chain, for example mainnet.
+	Network string
 }
 
 // ChannelInfo stores unpacked per-channel info.
@@ -330,6 +460,10 @@ type ChannelInfo struct {
 	// CloseAddr is the optional upfront shutdown address set for a
 	// channel.
 	CloseAddr btcutil.Address
+
+	// CommitmentType is the commitment type of the channel. It is nil if
+	// lnd reports an unknown commitment type.
+	CommitmentType *lnwallet.CommitmentType
 }
 
 func (s *lightningClient) newChannelInfo(channel *lnrpc.Channel) (*ChannelInfo,
@@ -371,6 +505,10 @@ func (s *lightningClient) newChannelInfo(channel *lnrpc.Channel) (*ChannelInfo,
 		),
 	}
 
+	chanInfo.CommitmentType = unmarshallCommitmentType(
+		channel.CommitmentType,
+	)
+
 	chanInfo.PendingHtlcs = make([]PendingHtlc, len(channel.PendingHtlcs))
 	for i, rpcHtlc := range channel.PendingHtlcs {
 		htlc, err := newPendingHtlc(rpcHtlc)
@@ -479,6 +617,110 @@ type ChannelEventUpdate struct {
 
 	// ClosedChannelInfo holds the channel info for a newly closed channel.
 	ClosedChannelInfo *ClosedChannel
+
+	// Event is the typed representation of the update. Consumers can
+	// switch over its concrete type instead of the update type.
+	Event ChannelEvent
+}
+
+// ChannelEvent is an interface implemented by all channel events. The events
+// delivered by SubscribeChannelEvents and the closed channels returned by
+// ClosedChannels share these types, so a single handler can process both.
+type ChannelEvent interface {
+	// ChanPoint returns the funding outpoint of the channel the event
+	// belongs to.
+	ChanPoint() wire.OutPoint
+}
+
+// PendingOpenChannelEvent indicates that a channel funding transaction has
+// been broadcast, but the channel isn't confirmed yet.
+type PendingOpenChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *PendingOpenChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
+}
+
+// OpenChannelEvent indicates that a channel has been opened.
+type OpenChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Channel holds the channel info of the opened channel.
+	Channel *ChannelInfo
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *OpenChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
+}
+
+// ActiveChannelEvent indicates that a channel became active.
+type ActiveChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *ActiveChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
+}
+
+// InactiveChannelEvent indicates that a channel became inactive.
+type InactiveChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *InactiveChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
+}
+
+// ClosedChannelEvent indicates that a channel has been closed. The close
+// summary holds the close type and the resolutions of the channel's outputs.
+type ClosedChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Channel holds the close summary of the channel.
+	Channel *ClosedChannel
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *ClosedChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
+}
+
+// NewClosedChannelEvent creates a channel event for a closed channel, for
+// example one returned by ClosedChannels.
+func NewClosedChannelEvent(channel *ClosedChannel) (*ClosedChannelEvent,
+	error) {
+
+	chanPoint, err := NewOutpointFromStr(channel.ChannelPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClosedChannelEvent{
+		ChannelPoint: *chanPoint,
+		Channel:      channel,
+	}, nil
+}
+
+// FullyResolvedChannelEvent indicates that all outputs of a closed channel
+// have been resolved on chain.
+type FullyResolvedChannelEvent struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+}
+
+// ChanPoint returns the funding outpoint of the channel.
+func (e *FullyResolvedChannelEvent) ChanPoint() wire.OutPoint {
+	return e.ChannelPoint
 }
 
 // ClosedChannel represents a channel that has been closed.
@@ -519,6 +761,124 @@ type ClosedChannel struct {
 	// channel close. Note that this does not include cases where we need to
 	// sweep our commitment or htlcs.
 	SettledBalance btcutil.Amount
+
+	// Resolutions holds the resolutions of the outputs of the channel
+	// that lnd had to claim on chain. It is not available for channels
+	// that were closed before lnd started tracking resolutions.
+	Resolutions []Resolution
+}
+
+// ResolutionType is the type of an output resolved on chain after a channel
+// close.
+type ResolutionType uint8
+
+const (
+	// ResolutionTypeUnknown is the type of resolutions lnd didn't report a
+	// type for.
+	ResolutionTypeUnknown ResolutionType = iota
+
+	// ResolutionTypeAnchor is the resolution of our anchor output.
+	ResolutionTypeAnchor
+
+	// ResolutionTypeIncomingHtlc is the resolution of an incoming htlc.
+	ResolutionTypeIncomingHtlc
+
+	// ResolutionTypeOutgoingHtlc is the resolution of an outgoing htlc.
+	ResolutionTypeOutgoingHtlc
+
+	// ResolutionTypeCommit is the resolution of our time locked commitment
+	// output.
+	ResolutionTypeCommit
+)
+
+// String provides the string representation of a resolution type.
+func (r ResolutionType) String() string {
+	switch r {
+	case ResolutionTypeAnchor:
+		return "Anchor"
+
+	case ResolutionTypeIncomingHtlc:
+		return "Incoming Htlc"
+
+	case ResolutionTypeOutgoingHtlc:
+		return "Outgoing Htlc"
+
+	case ResolutionTypeCommit:
+		return "Commit"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// ResolutionOutcome is the outcome of an output resolved on chain.
+type ResolutionOutcome uint8
+
+const (
+	// ResolutionOutcomeUnknown is the outcome of resolutions lnd didn't
+	// report an outcome for.
+	ResolutionOutcomeUnknown ResolutionOutcome = iota
+
+	// ResolutionOutcomeClaimed indicates that we claimed the output.
+	ResolutionOutcomeClaimed
+
+	// ResolutionOutcomeUnclaimed indicates that we didn't claim the output,
+	// for example because the remote party swept it first.
+	ResolutionOutcomeUnclaimed
+
+	// ResolutionOutcomeAbandoned indicates that the output was abandoned
+	// because it wasn't economical to claim.
+	ResolutionOutcomeAbandoned
+
+	// ResolutionOutcomeFirstStage indicates that the first stage of a two
+	// stage htlc resolution has confirmed.
+	ResolutionOutcomeFirstStage
+
+	// ResolutionOutcomeTimeout indicates that an htlc timed out.
+	ResolutionOutcomeTimeout
+)
+
+// String provides the string representation of a resolution outcome.
+func (r ResolutionOutcome) String() string {
+	switch r {
+	case ResolutionOutcomeClaimed:
+		return "Claimed"
+
+	case ResolutionOutcomeUnclaimed:
+		return "Unclaimed"
+
+	case ResolutionOutcomeAbandoned:
+		return "Abandoned"
+
+	case ResolutionOutcomeFirstStage:
+		return "First Stage"
+
+	case ResolutionOutcomeTimeout:
+		return "Timeout"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// Resolution describes how an output of a closed channel was resolved on
+// chain.
+type Resolution struct {
+	// Type is the type of the resolved output.
+	Type ResolutionType
+
+	// Outcome is the outcome of the resolution.
+	Outcome ResolutionOutcome
+
+	// Outpoint is the output that was resolved.
+	Outpoint wire.OutPoint
+
+	// Amount is the value of the resolved output.
+	Amount btcutil.Amount
+
+	// SweepTxid is the hash of the transaction that swept the output, if
+	// one exists.
+	SweepTxid string
 }
 
 // CloseType is an enum which represents the types of closes our channels may
@@ -643,6 +1003,17 @@ type Transaction struct {
 
 	// Label is an optional label set for on chain transactions.
 	Label string
+
+	// BlockHeight is the height of the block the transaction was
+	// confirmed in. It is zero for unconfirmed transactions.
+	BlockHeight int32
+
+	// BlockHash is the hash of the block the transaction was confirmed
+	// in, if it is confirmed.
+	BlockHash *chainhash.Hash
+
+	// DestAddresses is the list of addresses the transaction pays to.
+	DestAddresses []string
 }
 
 // Peer contains information about a peer we are connected to.
@@ -671,6 +1042,32 @@ type Peer struct {
 
 	// Received is the total amount we have received from this peer.
 	Received btcutil.Amount
+
+	// Features is the set of features the peer advertised.
+	Features []lnwire.FeatureBit
+
+	// SyncType is the type of graph sync we are performing with the peer.
+	SyncType lnrpc.Peer_SyncType
+
+	// Errors is the list of the most recent errors we received from or
+	// encountered with the peer.
+	Errors []PeerError
+
+	// FlapCount is the number of times the peer has disconnected and
+	// reconnected.
+	FlapCount int32
+
+	// LastFlap is the time of the peer's last flap, if it flapped.
+	LastFlap time.Time
+}
+
+// PeerError is an error that occurred with a peer.
+type PeerError struct {
+	// Timestamp is the time the error occurred.
+	Timestamp time.Time
+
+	// Error is the error message.
+	Error string
 }
 
 // ChannelBalance contains information about our channel balances.
@@ -680,6 +1077,39 @@ type ChannelBalance struct {
 
 	// PendingBalance is the sum of all pending channel balances.
 	PendingBalance btcutil.Amount
+
+	// LocalBalance is the sum of our balances in all open channels.
+	LocalBalance lnwire.MilliSatoshi
+
+	// RemoteBalance is the sum of our peers' balances in all open
+	// channels.
+	RemoteBalance lnwire.MilliSatoshi
+
+	// UnsettledLocalBalance is the sum of all our unsettled outgoing
+	// HTLCs.
+	UnsettledLocalBalance lnwire.MilliSatoshi
+
+	// UnsettledRemoteBalance is the sum of all our unsettled incoming
+	// HTLCs.
+	UnsettledRemoteBalance lnwire.MilliSatoshi
+
+	// PendingOpenLocalBalance is the sum of our balances in all pending
+	// open channels.
+	PendingOpenLocalBalance lnwire.MilliSatoshi
+
+	// PendingOpenRemoteBalance is the sum of our peers' balances in all
+	// pending open channels.
+	PendingOpenRemoteBalance lnwire.MilliSatoshi
+}
+
+// amountMsat returns the millisatoshi value of an rpc amount, which may be
+// nil.
+func amountMsat(amount *lnrpc.Amount) lnwire.MilliSatoshi {
+	if amount == nil {
+		return 0
+	}
+
+	return lnwire.MilliSatoshi(amount.Msat)
 }
 
 // Node describes a node in the network.
@@ -864,6 +1294,19 @@ type NetworkInfo struct {
 	NumZombieChans uint64
 }
 
+// RecoveryInfo describes the state of the wallet recovery of lnd.
+type RecoveryInfo struct {
+	// RecoveryMode is true if lnd was started in recovery mode.
+	RecoveryMode bool
+
+	// RecoveryFinished is true once the rescan of the wallet recovery has
+	// completed. It is always false if lnd isn't in recovery mode.
+	RecoveryFinished bool
+
+	// Progress is the progress of the recovery rescan, between 0 and 1.
+	Progress float64
+}
+
 // AcceptorRequest contains the details of an incoming channel that has been
 // proposed to our node.
 type AcceptorRequest struct {
@@ -930,28 +1373,11 @@ func newAcceptorRequest(req *lnrpc.ChannelAcceptRequest) (*AcceptorRequest,
 	var pending [32]byte
 	copy(pending[:], req.PendingChanId)
 
-	var commitmentType *lnwallet.CommitmentType
-	switch req.CommitmentType {
-	case lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE:
-		break
-
-	case lnrpc.CommitmentType_LEGACY:
-		commitmentType = new(lnwallet.CommitmentType)
-		*commitmentType = lnwallet.CommitmentTypeLegacy
-
-	case lnrpc.CommitmentType_STATIC_REMOTE_KEY:
-		commitmentType = new(lnwallet.CommitmentType)
-		*commitmentType = lnwallet.CommitmentTypeTweakless
-
-	case lnrpc.CommitmentType_ANCHORS:
-		commitmentType = new(lnwallet.CommitmentType)
-		*commitmentType = lnwallet.CommitmentTypeAnchorsZeroFeeHtlcTx
-
-	case lnrpc.CommitmentType_SCRIPT_ENFORCED_LEASE:
-		commitmentType = new(lnwallet.CommitmentType)
-		*commitmentType = lnwallet.CommitmentTypeScriptEnforcedLease
-
-	default:
+	// Unlike for existing channels, we don't accept requests with a
+	// commitment type we don't know, as the acceptor couldn't judge them.
+	unknown := lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE
+	commitmentType := unmarshallCommitmentType(req.CommitmentType)
+	if commitmentType == nil && req.CommitmentType != unknown {
 		return nil, fmt.Errorf("unhandled commitment type %v",
 			req.CommitmentType)
 	}
@@ -974,6 +1400,34 @@ func newAcceptorRequest(req *lnrpc.ChannelAcceptRequest) (*AcceptorRequest,
 	}, nil
 }
 
+// unmarshallCommitmentType converts an rpc commitment type into the
+// corresponding lnwallet commitment type. Nil is returned for an unknown
+// commitment type.
+func unmarshallCommitmentType(
+	rpcType lnrpc.CommitmentType) *lnwallet.CommitmentType {
+
+	var commitmentType *lnwallet.CommitmentType
+	switch rpcType {
+	case lnrpc.CommitmentType_LEGACY:
+		commitmentType = new(lnwallet.CommitmentType)
+		*commitmentType = lnwallet.CommitmentTypeLegacy
+
+	case lnrpc.CommitmentType_STATIC_REMOTE_KEY:
+		commitmentType = new(lnwallet.CommitmentType)
+		*commitmentType = lnwallet.CommitmentTypeTweakless
+
+	case lnrpc.CommitmentType_ANCHORS:
+		commitmentType = new(lnwallet.CommitmentType)
+		*commitmentType = lnwallet.CommitmentTypeAnchorsZeroFeeHtlcTx
+
+	case lnrpc.CommitmentType_SCRIPT_ENFORCED_LEASE:
+		commitmentType = new(lnwallet.CommitmentType)
+		*commitmentType = lnwallet.CommitmentTypeScriptEnforcedLease
+	}
+
+	return commitmentType
+}
+
 // AcceptorResponse contains the response to a channel acceptor request.
 type AcceptorResponse struct {
 	// Accept indicates whether to accept the channel.
@@ -1042,6 +1496,30 @@ type QueryRoutesRequest struct {
 
 	// FeeLimitMsat is the fee limit to use in millisatoshis.
 	FeeLimitMsat lnwire.MilliSatoshi
+
+	// IgnoredNodes is a list of nodes that the route must not go through.
+	IgnoredNodes []route.Vertex
+
+	// IgnoredPairs is a list of directed node pairs that the route must
+	// not use.
+	IgnoredPairs []NodePair
+
+	// OutgoingChanID is the optional channel the route must leave our node
+	// through.
+	OutgoingChanID *uint64
+
+	// DestCustomRecords holds the custom TLV records that will be added to
+	// the payload of the final hop.
+	DestCustomRecords map[uint64][]byte
+}
+
+// NodePair is a directed pair of nodes.
+type NodePair struct {
+	// From is the node the pair starts at.
+	From route.Vertex
+
+	// To is the node the pair ends at.
+	To route.Vertex
 }
 
 // Hop holds details about a single hop along a route.
@@ -1082,6 +1560,12 @@ type QueryRoutesResponse struct {
 
 	// TotalAmtMsat is the total amount in millisatoshis.
 	TotalAmtMsat lnwire.MilliSatoshi
+
+	// Route is the full route, including the custom records of each hop,
+	// which can be passed to SendToRoute. Its source is only set if a
+	// source was given in the request, otherwise the route starts at our
+	// node.
+	Route *route.Route
 }
 
 // CustomMessage describes custom messages exchanged with peers.
@@ -1166,9 +1650,21 @@ func (s *lightningClient) WalletBalance(ctx context.Context) (
 		return nil, err
 	}
 
+	accountBalances := make(
+		map[string]*AccountBalance, len(resp.AccountBalance),
+	)
+	for account, balance := range resp.AccountBalance {
+		accountBalances[account] = &AccountBalance{
+			Confirmed:   btcutil.Amount(balance.ConfirmedBalance),
+			Unconfirmed: btcutil.Amount(balance.UnconfirmedBalance),
+		}
+	}
+
 	return &WalletBalance{
-		Confirmed:   btcutil.Amount(resp.ConfirmedBalance),
-		Unconfirmed: btcutil.Amount(resp.UnconfirmedBalance),
+		Confirmed:       btcutil.Amount(resp.ConfirmedBalance),
+		Unconfirmed:     btcutil.Amount(resp.UnconfirmedBalance),
+		Locked:          btcutil.Amount(resp.LockedBalance),
+		AccountBalances: accountBalances,
 	}, nil
 }
 
@@ -1185,6 +1681,62 @@ func (s *lightningClient) GetInfo(ctx context.Context) (*Info, error) {
 	return newInfo(resp)
 }
 
+// GetRecoveryInfo returns the state of the wallet recovery of the backing lnd
+// node.
+func (s *lightningClient) GetRecoveryInfo(ctx context.Context) (*RecoveryInfo,
+	error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.GetRecoveryInfo(
+		rpcCtx, &lnrpc.GetRecoveryInfoRequest{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryInfo{
+		RecoveryMode:     resp.RecoveryMode,
+		RecoveryFinished: resp.RecoveryFinished,
+		Progress:         resp.Progress,
+	}, nil
+}
+
+// SetDebugLevel changes the log levels of lnd at runtime. The level spec is
+// either a single level for all subsystems or a comma separated list of
+// <subsystem>=<level> pairs. If show is true, the level spec is ignored and the
+// list of available subsystems is returned instead.
+func (s *lightningClient) SetDebugLevel(ctx context.Context, show bool,
+	levelSpec string) (string, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.DebugLevel(rpcCtx, &lnrpc.DebugLevelRequest{
+		Show:      show,
+		LevelSpec: levelSpec,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.SubSystems, nil
+}
+
+// StopDaemon requests a graceful shutdown of the backing lnd node. The call
+// returns once lnd has started shutting down, not once it has exited.
+func (s *lightningClient) StopDaemon(ctx context.Context) error {
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	_, err := s.client.StopDaemon(rpcCtx, &lnrpc.StopRequest{})
+	return err
+}
+
 func newInfo(resp *lnrpc.GetInfoResponse) (*Info, error) {
 	pubKey, err := hex.DecodeString(resp.IdentityPubkey)
 	if err != nil {
@@ -1194,6 +1746,19 @@ func newInfo(resp *lnrpc.GetInfoResponse) (*Info, error) {
 	var pubKeyArray [33]byte
 	copy(pubKeyArray[:], pubKey)
 
+	chains := make([]Chain, len(resp.Chains))
+	for i, chain := range resp.Chains {
+		chains[i] = Chain{
+			Chain:   chain.Chain,
+			Network: chain.Network,
+		}
+	}
+
+	features := make([]lnwire.FeatureBit, 0, len(resp.Features))
+	for featureBit := range resp.Features {
+		features = append(features, lnwire.FeatureBit(featureBit))
+	}
+
 	return &Info{
 		Version:             resp.Version,
 		BlockHeight:         resp.BlockHeight,
@@ -1207,6 +1772,8 @@ func newInfo(resp *lnrpc.GetInfoResponse) (*Info, error) {
 		ActiveChannels:      resp.NumActiveChannels,
 		InactiveChannels:    resp.NumInactiveChannels,
 		PendingChannels:     resp.NumPendingChannels,
+		Chains:              chains,
+		Features:            features,
 	}, nil
 }
 
@@ -1242,17 +1809,62 @@ func (s *lightningClient) EstimateFeeToP2WSH(ctx context.Context,
 	return btcutil.Amount(resp.FeeSat), nil
 }
 
-// PayInvoice pays an invoice.
-func (s *lightningClient) PayInvoice(ctx context.Context, invoice string,
-	maxFee btcutil.Amount, outgoingChannel *uint64) chan PaymentResult {
+// FeeEstimate is the estimated fee of an on chain transaction.
+type FeeEstimate struct {
+	// Fee is the total fee of the transaction.
+	Fee btcutil.Amount
 
-	// Use buffer to prevent blocking.
-	paymentChan := make(chan PaymentResult, 1)
+	// SatPerVbyte is the fee rate of the transaction in sat/vbyte.
+	SatPerVbyte uint64
+}
 
-	// Execute payment in parallel, because it will block until server
-	// discovers preimage.
-	s.wg.Add(1)
-	go func() {
+// EstimateFee estimates the fee of a transaction paying to the given outputs.
+func (s *lightningClient) EstimateFee(ctx context.Context,
+	outputs map[string]btcutil.Amount, confTarget int32,
+	opts ...SendOption) (*FeeEstimate, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	addrToAmount := make(map[string]int64, len(outputs))
+	for addr, amount := range outputs {
+		addrToAmount[addr] = int64(amount)
+	}
+
+	resp, err := s.client.EstimateFee(rpcCtx, &lnrpc.EstimateFeeRequest{
+		AddrToAmount:     addrToAmount,
+		TargetConf:       confTarget,
+		MinConfs:         options.minConfs,
+		SpendUnconfirmed: options.spendUnconfirmed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FeeEstimate{
+		Fee:         btcutil.Amount(resp.FeeSat),
+		SatPerVbyte: resp.SatPerVbyte,
+	}, nil
+}
+
+// PayInvoice pays an invoice.
+func (s *lightningClient) PayInvoice(ctx context.Context, invoice string,
+	maxFee btcutil.Amount, outgoingChannel *uint64) chan PaymentResult {
+
+	// Use buffer to prevent blocking.
+	paymentChan := make(chan PaymentResult, 1)
+
+	// Execute payment in parallel, because it will block until server
+	// discovers preimage.
+	s.wg.Add(1)
+	go func() {
 		defer s.wg.Done()
 
 		result := s.payInvoice(ctx, invoice, maxFee, outgoingChannel)
@@ -1413,6 +2025,187 @@ func (s *lightningClient) AddInvoice(ctx context.Context,
 	return hash, resp.PaymentRequest, nil
 }
 
+// InvoiceRequest contains the parameters of an invoice to be added to lnd.
+type InvoiceRequest struct {
+	// Memo is an optional description of the invoice that is included in
+	// the payment request.
+	Memo string
+
+	// DescriptionHash is the hash of a description that is too long to be
+	// included in the payment request itself.
+	DescriptionHash []byte
+
+	// Value is the amount of the invoice. Zero creates an invoice that
+	// can be paid with any amount.
+	Value lnwire.MilliSatoshi
+
+	// Expiry is the number of seconds the invoice is valid for. If zero,
+	// lnd's default expiry is used.
+	Expiry int64
+
+	// CltvExpiry is the final cltv delta of the invoice. If zero, lnd's
+	// default delta is used.
+	CltvExpiry uint64
+
+	// Preimage optionally overrides the preimage of the invoice. If nil,
+	// lnd generates a random preimage.
+	Preimage *lntypes.Preimage
+
+	// FallbackAddr is an optional on-chain address that can be used as a
+	// fallback if the payment can't be made off-chain.
+	FallbackAddr string
+
+	// Private includes route hints for our private channels in the
+	// payment request.
+	Private bool
+
+	// RouteHints is an optional set of additional route hints to include
+	// in the payment request.
+	RouteHints [][]zpay32.HopHint
+
+	// Amp creates an AMP invoice. The preimage must not be set for AMP
+	// invoices, as each AMP payment uses its own preimages.
+	Amp bool
+}
+
+// AddInvoiceResult is the result of adding an invoice to lnd.
+type AddInvoiceResult struct {
+	// Hash is the payment hash of the invoice.
+	Hash lntypes.Hash
+
+	// PaymentAddr is the payment address of the invoice, which must be
+	// included in payments to it.
+	PaymentAddr [32]byte
+
+	// PaymentRequest is the encoded payment request of the invoice.
+	PaymentRequest string
+
+	// AddIndex is the index of the invoice in lnd's invoice database. It
+	// can be used to resume invoice subscriptions and queries.
+	AddIndex uint64
+}
+
+// CreateInvoice adds an invoice to lnd with the full set of options of an
+// invoice request.
+func (s *lightningClient) CreateInvoice(ctx context.Context,
+	in *InvoiceRequest) (*AddInvoiceResult, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	routeHints, err := marshallRouteHints(in.RouteHints)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcIn := &lnrpc.Invoice{
+		Memo:            in.Memo,
+		DescriptionHash: in.DescriptionHash,
+		ValueMsat:       int64(in.Value),
+		Expiry:          in.Expiry,
+		CltvExpiry:      in.CltvExpiry,
+		FallbackAddr:    in.FallbackAddr,
+		Private:         in.Private,
+		RouteHints:      routeHints,
+		IsAmp:           in.Amp,
+	}
+
+	if in.Preimage != nil {
+		rpcIn.RPreimage = in.Preimage[:]
+	}
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.AddInvoice(rpcCtx, rpcIn)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := lntypes.MakeHash(resp.RHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AddInvoiceResult{
+		Hash:           hash,
+		PaymentRequest: resp.PaymentRequest,
+		AddIndex:       resp.AddIndex,
+	}
+
+	// The invoice has already been added at this point, so we don't fail
+	// the call if lnd didn't return a payment address.
+	if len(resp.PaymentAddr) == len(result.PaymentAddr) {
+		copy(result.PaymentAddr[:], resp.PaymentAddr)
+	}
+
+	return result, nil
+}
+
+// AddressType is the type of address to generate.
+type AddressType uint8
+
+const (
+	// AddressTypeWitnessPubkeyHash is a native segwit (p2wkh) address.
+	AddressTypeWitnessPubkeyHash AddressType = iota
+
+	// AddressTypeNestedPubkeyHash is a segwit address nested in a p2sh
+	// (np2wkh) address.
+	AddressTypeNestedPubkeyHash
+
+	// AddressTypeUnusedWitnessPubkeyHash is a native segwit address that
+	// is only freshly derived if the last one returned hasn't been used
+	// yet.
+	AddressTypeUnusedWitnessPubkeyHash
+
+	// AddressTypeUnusedNestedPubkeyHash is a nested segwit address that is
+	// only freshly derived if the last one returned hasn't been used yet.
+	AddressTypeUnusedNestedPubkeyHash
+)
+
+// rpcAddressType maps an address type to its rpc counterpart.
+func rpcAddressType(addrType AddressType) (lnrpc.AddressType, error) {
+	switch addrType {
+	case AddressTypeWitnessPubkeyHash:
+		return lnrpc.AddressType_WITNESS_PUBKEY_HASH, nil
+
+	case AddressTypeNestedPubkeyHash:
+		return lnrpc.AddressType_NESTED_PUBKEY_HASH, nil
+
+	case AddressTypeUnusedWitnessPubkeyHash:
+		return lnrpc.AddressType_UNUSED_WITNESS_PUBKEY_HASH, nil
+
+	case AddressTypeUnusedNestedPubkeyHash:
+		return lnrpc.AddressType_UNUSED_NESTED_PUBKEY_HASH, nil
+
+	default:
+		return 0, fmt.Errorf("unknown address type: %v", addrType)
+	}
+}
+
+// NewAddress generates a new address of the given type.
+func (s *lightningClient) NewAddress(ctx context.Context,
+	addrType AddressType, account string) (btcutil.Address, error) {
+
+	rpcAddrType, err := rpcAddressType(addrType)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	resp, err := s.client.NewAddress(rpcCtx, &lnrpc.NewAddressRequest{
+		Type:    rpcAddrType,
+		Account: account,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return btcutil.DecodeAddress(resp.Address, s.params)
+}
+
 // WalletBalance describes our wallet's current balance.
 type WalletBalance struct {
 	// Confirmed is our total confirmed balance.
@@ -1420,6 +2213,23 @@ type WalletBalance struct {
 
 	// Unconfirmed is our total unconfirmed balance.
 	Unconfirmed btcutil.Amount
+
+	// Locked is the part of our confirmed balance that is locked by
+	// output leases.
+	Locked btcutil.Amount
+
+	// AccountBalances is the balance of each of our wallet accounts,
+	// keyed by account name.
+	AccountBalances map[string]*AccountBalance
+}
+
+// AccountBalance describes the balance of a single wallet account.
+type AccountBalance struct {
+	// Confirmed is the confirmed balance of the account.
+	Confirmed btcutil.Amount
+
+	// Unconfirmed is the unconfirmed balance of the account.
+	Unconfirmed btcutil.Amount
 }
 
 // Invoice represents an invoice in lnd.
@@ -1464,6 +2274,45 @@ type Invoice struct {
 
 	// SettleIndex is the index at which the invoice was settled.
 	SettleIndex uint64
+
+	// DescriptionHash is the hash of the invoice's description, if the
+	// description was committed to by hash rather than as a memo.
+	DescriptionHash []byte
+
+	// Expiry is the duration after its creation for which the invoice is
+	// valid.
+	Expiry time.Duration
+
+	// CltvExpiry is the final cltv delta of the invoice.
+	CltvExpiry uint64
+
+	// Private indicates whether route hints for private channels were
+	// included in the payment request.
+	Private bool
+
+	// IsAmp indicates whether the invoice is an AMP invoice.
+	IsAmp bool
+
+	// PaymentAddr is the payment address of the invoice.
+	PaymentAddr [32]byte
+}
+
+// CustomRecords returns the custom records of all htlcs that were accepted or
+// settled for the invoice. If several htlcs carry the same record type, the
+// value of the last one is returned.
+func (i *Invoice) CustomRecords() map[uint64][]byte {
+	records := make(map[uint64][]byte)
+	for _, htlc := range i.Htlcs {
+		if htlc.State == lnrpc.InvoiceHTLCState_CANCELED {
+			continue
+		}
+
+		for key, value := range htlc.CustomRecords {
+			records[key] = value
+		}
+	}
+
+	return records
 }
 
 // InvoiceHtlc represents a htlc that was used to pay an invoice.
@@ -1489,6 +2338,16 @@ type InvoiceHtlc struct {
 
 	// CustomRecords is list of the custom tlv records.
 	CustomRecords map[uint64][]byte
+
+	// HtlcIndex is the index of the htlc on the incoming channel.
+	HtlcIndex uint64
+
+	// ExpiryHeight is the block height at which the htlc expires.
+	ExpiryHeight int32
+
+	// MppTotalAmount is the total amount of the mpp payment the htlc is
+	// part of.
+	MppTotalAmount lnwire.MilliSatoshi
 }
 
 // PendingHtlc represents a HTLC that is currently pending on some channel.
@@ -1583,14 +2442,27 @@ func unmarshalInvoice(resp *lnrpc.Invoice) (*Invoice, error) {
 		Htlcs:          make([]InvoiceHtlc, len(resp.Htlcs)),
 		AddIndex:       resp.AddIndex,
 		SettleIndex:    resp.SettleIndex,
+
+		DescriptionHash: resp.DescriptionHash,
+		Expiry:          time.Duration(resp.Expiry) * time.Second,
+		CltvExpiry:      resp.CltvExpiry,
+		Private:         resp.Private,
+		IsAmp:           resp.IsAmp,
+	}
+
+	if len(resp.PaymentAddr) == len(invoice.PaymentAddr) {
+		copy(invoice.PaymentAddr[:], resp.PaymentAddr)
 	}
 
 	for i, htlc := range resp.Htlcs {
 		invoiceHtlc := InvoiceHtlc{
-			ChannelID:     lnwire.NewShortChanIDFromInt(htlc.ChanId),
-			Amount:        lnwire.MilliSatoshi(htlc.AmtMsat),
-			CustomRecords: htlc.CustomRecords,
-			State:         htlc.State,
+			ChannelID:      lnwire.NewShortChanIDFromInt(htlc.ChanId),
+			Amount:         lnwire.MilliSatoshi(htlc.AmtMsat),
+			CustomRecords:  htlc.CustomRecords,
+			State:          htlc.State,
+			HtlcIndex:      htlc.HtlcIndex,
+			ExpiryHeight:   htlc.ExpiryHeight,
+			MppTotalAmount: lnwire.MilliSatoshi(htlc.MppTotalAmtMsat),
 		}
 
 		if htlc.AcceptTime != 0 {
@@ -1638,9 +2510,21 @@ func unmarshalInvoice(resp *lnrpc.Invoice) (*Invoice, error) {
 	return invoice, nil
 }
 
+// ListTransactionsOption is a functional option that adds a server-side filter
+// to a ListTransactions request.
+type ListTransactionsOption func(r *lnrpc.GetTransactionsRequest)
+
+// WithTransactionsAccount is an option for only listing the transactions that
+// are relevant to the given wallet account.
+func WithTransactionsAccount(account string) ListTransactionsOption {
+	return func(r *lnrpc.GetTransactionsRequest) {
+		r.Account = account
+	}
+}
+
 // ListTransactions returns all known transactions of the backing lnd node.
 func (s *lightningClient) ListTransactions(ctx context.Context, startHeight,
-	endHeight int32) ([]Transaction, error) {
+	endHeight int32, opts ...ListTransactionsOption) ([]Transaction, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
@@ -1650,6 +2534,9 @@ func (s *lightningClient) ListTransactions(ctx context.Context, startHeight,
 		StartHeight: startHeight,
 		EndHeight:   endHeight,
 	}
+	for _, opt := range opts {
+		opt(rpcIn)
+	}
 
 	resp, err := s.client.GetTransactions(rpcCtx, rpcIn)
 	if err != nil {
@@ -1658,43 +2545,140 @@ func (s *lightningClient) ListTransactions(ctx context.Context, startHeight,
 
 	txs := make([]Transaction, len(resp.Transactions))
 	for i, respTx := range resp.Transactions {
-		rawTx, err := hex.DecodeString(respTx.RawTxHex)
+		tx, err := unmarshallTransaction(respTx)
 		if err != nil {
 			return nil, err
 		}
 
-		var tx wire.MsgTx
-		if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		txs[i] = *tx
+	}
+
+	return txs, nil
+}
+
+// unmarshallTransaction creates a transaction from its rpc counterpart.
+func unmarshallTransaction(respTx *lnrpc.Transaction) (*Transaction, error) {
+	rawTx, err := hex.DecodeString(respTx.RawTxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, err
+	}
+
+	transaction := &Transaction{
+		Tx:            &tx,
+		TxHash:        tx.TxHash().String(),
+		Timestamp:     time.Unix(respTx.TimeStamp, 0),
+		Amount:        btcutil.Amount(respTx.Amount),
+		Fee:           btcutil.Amount(respTx.TotalFees),
+		Confirmations: respTx.NumConfirmations,
+		Label:         respTx.Label,
+		BlockHeight:   respTx.BlockHeight,
+		DestAddresses: respTx.DestAddresses,
+	}
+
+	if respTx.BlockHash != "" {
+		transaction.BlockHash, err = chainhash.NewHashFromStr(
+			respTx.BlockHash,
+		)
+		if err != nil {
 			return nil, err
 		}
+	}
+
+	return transaction, nil
+}
+
+// SubscribeTransactions subscribes to on chain transactions relevant to our
+// wallet. An update is sent when a transaction is first seen in the mempool
+// and again when it confirms.
+func (s *lightningClient) SubscribeTransactions(ctx context.Context) (
+	<-chan *Transaction, <-chan error, error) {
+
+	txStream, err := s.client.SubscribeTransactions(
+		s.adminMac.WithMacaroonAuth(ctx),
+		&lnrpc.GetTransactionsRequest{},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txUpdates := make(chan *Transaction)
+	streamErr := make(chan error, 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
 
-		txs[i] = Transaction{
-			Tx:            &tx,
-			TxHash:        tx.TxHash().String(),
-			Timestamp:     time.Unix(respTx.TimeStamp, 0),
-			Amount:        btcutil.Amount(respTx.Amount),
-			Fee:           btcutil.Amount(respTx.TotalFees),
-			Confirmations: respTx.NumConfirmations,
-			Label:         respTx.Label,
+		for {
+			rpcTx, err := txStream.Recv()
+			if err != nil {
+				streamErr <- err
+				return
+			}
+
+			tx, err := unmarshallTransaction(rpcTx)
+			if err != nil {
+				streamErr <- err
+				return
+			}
+
+			select {
+			case txUpdates <- tx:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	return txUpdates, streamErr, nil
+}
+
+// ListChannelsOption is a functional option that adds a server-side filter to
+// a ListChannels request.
+type ListChannelsOption func(r *lnrpc.ListChannelsRequest)
+
+// WithPeer is an option for only listing the channels with the given peer.
+func WithPeer(peer route.Vertex) ListChannelsOption {
+	return func(r *lnrpc.ListChannelsRequest) {
+		r.Peer = peer[:]
 	}
+}
 
-	return txs, nil
+// WithInactiveOnly is an option for only listing inactive channels.
+func WithInactiveOnly() ListChannelsOption {
+	return func(r *lnrpc.ListChannelsRequest) {
+		r.InactiveOnly = true
+	}
+}
+
+// WithPrivateOnly is an option for only listing private channels.
+func WithPrivateOnly() ListChannelsOption {
+	return func(r *lnrpc.ListChannelsRequest) {
+		r.PrivateOnly = true
+	}
 }
 
 // ListChannels retrieves all channels of the backing lnd node.
 func (s *lightningClient) ListChannels(ctx context.Context, activeOnly,
-	publicOnly bool) ([]ChannelInfo, error) {
+	publicOnly bool, opts ...ListChannelsOption) ([]ChannelInfo, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
+	req := &lnrpc.ListChannelsRequest{
+		ActiveOnly: activeOnly,
+		PublicOnly: publicOnly,
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	response, err := s.client.ListChannels(
-		s.adminMac.WithMacaroonAuth(rpcCtx),
-		&lnrpc.ListChannelsRequest{
-			ActiveOnly: activeOnly,
-			PublicOnly: publicOnly,
-		},
+		s.adminMac.WithMacaroonAuth(rpcCtx), req,
 	)
 	if err != nil {
 		return nil, err
@@ -1741,6 +2725,24 @@ type PendingChannel struct {
 
 	// ChannelInitiator indicates which party opened the channel.
 	ChannelInitiator Initiator
+
+	// LocalBalance is our balance in this channel.
+	LocalBalance btcutil.Amount
+
+	// RemoteBalance is the counterparty's balance in this channel.
+	RemoteBalance btcutil.Amount
+
+	// LocalChanReserve is the minimum balance we are required to keep in
+	// the channel.
+	LocalChanReserve btcutil.Amount
+
+	// RemoteChanReserve is the minimum balance the counterparty is
+	// required to keep in the channel.
+	RemoteChanReserve btcutil.Amount
+
+	// CommitmentType is the commitment type of the channel. It is nil if
+	// lnd reports an unknown commitment type.
+	CommitmentType *lnwallet.CommitmentType
 }
 
 // NewPendingChannel creates a pending channel from the rpc struct.
@@ -1762,40 +2764,154 @@ func NewPendingChannel(channel *lnrpc.PendingChannelsResponse_PendingChannel) (
 		return nil, err
 	}
 
+	commitmentType := unmarshallCommitmentType(channel.CommitmentType)
+
 	return &PendingChannel{
-		ChannelPoint:     outpoint,
-		PubKeyBytes:      peer,
-		Capacity:         btcutil.Amount(channel.Capacity),
-		ChannelInitiator: initiator,
+		ChannelPoint:      outpoint,
+		PubKeyBytes:       peer,
+		Capacity:          btcutil.Amount(channel.Capacity),
+		ChannelInitiator:  initiator,
+		LocalBalance:      btcutil.Amount(channel.LocalBalance),
+		RemoteBalance:     btcutil.Amount(channel.RemoteBalance),
+		LocalChanReserve:  btcutil.Amount(channel.LocalChanReserveSat),
+		RemoteChanReserve: btcutil.Amount(channel.RemoteChanReserveSat),
+		CommitmentType:    commitmentType,
 	}, nil
 }
 
-// ForceCloseChannel describes a channel that pending force close.
-type ForceCloseChannel struct {
-	// PendingChannel contains information about the channel.
-	PendingChannel
+// AnchorState describes the resolution state of the anchor output of a force
+// closed channel.
+type AnchorState uint8
 
-	// CloseTxid is the close transaction that confirmed on chain.
-	CloseTxid chainhash.Hash
-}
+const (
+	// AnchorStateLimbo indicates that the anchor output is not resolved
+	// yet.
+	AnchorStateLimbo AnchorState = iota
 
-// WaitingCloseChannel describes a channel that we are waiting to be closed on
-// chain. It contains both parties close txids because either may confirm at
-// this point.
-type WaitingCloseChannel struct {
-	// PendingChannel contains information about the channel.
-	PendingChannel
+	// AnchorStateRecovered indicates that we swept the anchor output.
+	AnchorStateRecovered
 
-	// LocalTxid is our close transaction's txid.
-	LocalTxid chainhash.Hash
+	// AnchorStateLost indicates that the anchor output was swept by
+	// somebody else.
+	AnchorStateLost
 
-	// RemoteTxid is the remote party's close txid.
-	RemoteTxid chainhash.Hash
+	// AnchorStateUnknown indicates that lnd reported an anchor state that
+	// we don't know.
+	AnchorStateUnknown
+)
 
-	// RemotePending is the txid of the remote party's pending commit.
-	RemotePending chainhash.Hash
+// String returns a human readable representation of the anchor state.
+func (a AnchorState) String() string {
+	switch a {
+	case AnchorStateLimbo:
+		return "Limbo"
 
-	// ChanStatusFlags specifies the current channel state, examples:
+	case AnchorStateRecovered:
+		return "Recovered"
+
+	case AnchorStateLost:
+		return "Lost"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// rpcAnchorState converts the anchor state of a force closed channel into our
+// own anchor state. States we don't know are mapped to AnchorStateUnknown.
+func rpcAnchorState(
+	channel *lnrpc.PendingChannelsResponse_ForceClosedChannel) AnchorState {
+
+	switch channel.Anchor {
+	case lnrpc.PendingChannelsResponse_ForceClosedChannel_LIMBO:
+		return AnchorStateLimbo
+
+	case lnrpc.PendingChannelsResponse_ForceClosedChannel_RECOVERED:
+		return AnchorStateRecovered
+
+	case lnrpc.PendingChannelsResponse_ForceClosedChannel_LOST:
+		return AnchorStateLost
+
+	default:
+		return AnchorStateUnknown
+	}
+}
+
+// PendingForceCloseHtlc describes an HTLC of a force closed channel that is
+// awaiting resolution on chain.
+type PendingForceCloseHtlc struct {
+	// Incoming indicates whether the HTLC is incoming or outgoing.
+	Incoming bool
+
+	// Amount is the total value of the HTLC.
+	Amount btcutil.Amount
+
+	// Outpoint is the final output to be swept back to our wallet.
+	Outpoint *wire.OutPoint
+
+	// MaturityHeight is the height at which the funds can be swept into
+	// the wallet.
+	MaturityHeight uint32
+
+	// BlocksTilMaturity is the number of blocks remaining until the
+	// HTLC can be swept. Negative values indicate how many blocks have
+	// passed since the maturity height.
+	BlocksTilMaturity int32
+
+	// Stage indicates whether the HTLC is in its first or second stage of
+	// recovery.
+	Stage uint32
+}
+
+// ForceCloseChannel describes a channel that pending force close.
+type ForceCloseChannel struct {
+	// PendingChannel contains information about the channel.
+	PendingChannel
+
+	// CloseTxid is the close transaction that confirmed on chain.
+	CloseTxid chainhash.Hash
+
+	// LimboBalance is the balance that is still locked up in limbo.
+	LimboBalance btcutil.Amount
+
+	// MaturityHeight is the height at which our commitment output can be
+	// swept into the wallet.
+	MaturityHeight uint32
+
+	// BlocksTilMaturity is the number of blocks remaining until our
+	// commitment output can be swept. Negative values indicate how many
+	// blocks have passed since the maturity height.
+	BlocksTilMaturity int32
+
+	// RecoveredBalance is the total value of the funds that were already
+	// swept into the wallet.
+	RecoveredBalance btcutil.Amount
+
+	// PendingHtlcs is the list of HTLCs that are awaiting resolution.
+	PendingHtlcs []PendingForceCloseHtlc
+
+	// AnchorState is the resolution state of the anchor output of the
+	// channel, if it has one.
+	AnchorState AnchorState
+}
+
+// WaitingCloseChannel describes a channel that we are waiting to be closed on
+// chain. It contains both parties close txids because either may confirm at
+// this point.
+type WaitingCloseChannel struct {
+	// PendingChannel contains information about the channel.
+	PendingChannel
+
+	// LocalTxid is our close transaction's txid.
+	LocalTxid chainhash.Hash
+
+	// RemoteTxid is the remote party's close txid.
+	RemoteTxid chainhash.Hash
+
+	// RemotePending is the txid of the remote party's pending commit.
+	RemotePending chainhash.Hash
+
+	// ChanStatusFlags specifies the current channel state, examples:
 	//   - ChanStatusBorked|ChanStatusCommitBroadcasted|ChanStatusLocalCloseInitiator
 	//   - ChanStatusCoopBroadcasted|ChanStatusLocalCloseInitiator
 	//   - ChanStatusCoopBroadcasted|ChanStatusRemoteCloseInitiator
@@ -1803,6 +2919,10 @@ type WaitingCloseChannel struct {
 
 	// CloseTxid is the close transaction that's broadcast.
 	CloseTxid chainhash.Hash
+
+	// LimboBalance is the balance that will be locked up in limbo until
+	// the close transaction confirms.
+	LimboBalance btcutil.Amount
 }
 
 // PendingChannels returns a list of lnd's pending channels.
@@ -1837,9 +2957,36 @@ func (s *lightningClient) PendingChannels(ctx context.Context) (*PendingChannels
 			return nil, err
 		}
 
+		anchorState := rpcAnchorState(force)
+
+		htlcs := make([]PendingForceCloseHtlc, len(force.PendingHtlcs))
+		for j, htlc := range force.PendingHtlcs {
+			outpoint, err := NewOutpointFromStr(htlc.Outpoint)
+			if err != nil {
+				return nil, err
+			}
+
+			htlcs[j] = PendingForceCloseHtlc{
+				Incoming:          htlc.Incoming,
+				Amount:            btcutil.Amount(htlc.Amount),
+				Outpoint:          outpoint,
+				MaturityHeight:    htlc.MaturityHeight,
+				BlocksTilMaturity: htlc.BlocksTilMaturity,
+				Stage:             htlc.Stage,
+			}
+		}
+
 		pending.PendingForceClose[i] = ForceCloseChannel{
-			PendingChannel: *channel,
-			CloseTxid:      *hash,
+			PendingChannel:    *channel,
+			CloseTxid:         *hash,
+			LimboBalance:      btcutil.Amount(force.LimboBalance),
+			MaturityHeight:    force.MaturityHeight,
+			BlocksTilMaturity: force.BlocksTilMaturity,
+			RecoveredBalance: btcutil.Amount(
+				force.RecoveredBalance,
+			),
+			PendingHtlcs: htlcs,
+			AnchorState:  anchorState,
 		}
 	}
 
@@ -1882,6 +3029,7 @@ func (s *lightningClient) PendingChannels(ctx context.Context) (*PendingChannels
 			RemotePending:   *remotePending,
 			ChanStatusFlags: waiting.Channel.ChanStatusFlags,
 			CloseTxid:       *hash,
+			LimboBalance:    btcutil.Amount(waiting.LimboBalance),
 		}
 		pending.WaitingClose[i] = closing
 	}
@@ -1923,6 +3071,16 @@ func getClosedChannel(closeSummary *lnrpc.ChannelCloseSummary) (
 		return nil, err
 	}
 
+	resolutions := make([]Resolution, len(closeSummary.Resolutions))
+	for i, rpcResolution := range closeSummary.Resolutions {
+		resolution, err := unmarshallResolution(rpcResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		resolutions[i] = *resolution
+	}
+
 	return &ClosedChannel{
 		ChannelPoint:   closeSummary.ChannelPoint,
 		ChannelID:      closeSummary.ChanId,
@@ -1934,19 +3092,122 @@ func getClosedChannel(closeSummary *lnrpc.ChannelCloseSummary) (
 		PubKeyBytes:    remote,
 		Capacity:       btcutil.Amount(closeSummary.Capacity),
 		SettledBalance: btcutil.Amount(closeSummary.SettledBalance),
+		Resolutions:    resolutions,
 	}, nil
 }
 
-// ClosedChannels returns a list of our closed channels.
-func (s *lightningClient) ClosedChannels(ctx context.Context) ([]ClosedChannel,
+// unmarshallResolution converts an rpc resolution of a closed channel output.
+func unmarshallResolution(rpcResolution *lnrpc.Resolution) (*Resolution,
 	error) {
 
+	rpcOutpoint := rpcResolution.Outpoint
+	if rpcOutpoint == nil {
+		return nil, errors.New("resolution without outpoint")
+	}
+
+	var (
+		outpoint *wire.OutPoint
+		err      error
+	)
+	if len(rpcOutpoint.TxidBytes) != 0 {
+		outpoint, err = getOutPoint(
+			rpcOutpoint.TxidBytes, rpcOutpoint.OutputIndex,
+		)
+	} else {
+		outpoint, err = NewOutpointFromStr(fmt.Sprintf("%v:%v",
+			rpcOutpoint.TxidStr, rpcOutpoint.OutputIndex))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := &Resolution{
+		Outpoint:  *outpoint,
+		Amount:    btcutil.Amount(rpcResolution.AmountSat),
+		SweepTxid: rpcResolution.SweepTxid,
+	}
+
+	switch rpcResolution.ResolutionType {
+	case lnrpc.ResolutionType_ANCHOR:
+		resolution.Type = ResolutionTypeAnchor
+
+	case lnrpc.ResolutionType_INCOMING_HTLC:
+		resolution.Type = ResolutionTypeIncomingHtlc
+
+	case lnrpc.ResolutionType_OUTGOING_HTLC:
+		resolution.Type = ResolutionTypeOutgoingHtlc
+
+	case lnrpc.ResolutionType_COMMIT:
+		resolution.Type = ResolutionTypeCommit
+	}
+
+	switch rpcResolution.Outcome {
+	case lnrpc.ResolutionOutcome_CLAIMED:
+		resolution.Outcome = ResolutionOutcomeClaimed
+
+	case lnrpc.ResolutionOutcome_UNCLAIMED:
+		resolution.Outcome = ResolutionOutcomeUnclaimed
+
+	case lnrpc.ResolutionOutcome_ABANDONED:
+		resolution.Outcome = ResolutionOutcomeAbandoned
+
+	case lnrpc.ResolutionOutcome_FIRST_STAGE:
+		resolution.Outcome = ResolutionOutcomeFirstStage
+
+	case lnrpc.ResolutionOutcome_TIMEOUT:
+		resolution.Outcome = ResolutionOutcomeTimeout
+	}
+
+	return resolution, nil
+}
+
+// ClosedChannelsOption is a functional option that adds a server-side filter
+// to a ClosedChannels request.
+type ClosedChannelsOption func(r *lnrpc.ClosedChannelsRequest)
+
+// WithCloseTypes is an option for only listing channels that were closed with
+// one of the given close types. If the option isn't used, channels of all
+// close types are returned.
+func WithCloseTypes(closeTypes ...CloseType) ClosedChannelsOption {
+	return func(r *lnrpc.ClosedChannelsRequest) {
+		for _, closeType := range closeTypes {
+			switch closeType {
+			case CloseTypeCooperative:
+				r.Cooperative = true
+
+			case CloseTypeLocalForce:
+				r.LocalForce = true
+
+			case CloseTypeRemoteForce:
+				r.RemoteForce = true
+
+			case CloseTypeBreach:
+				r.Breach = true
+
+			case CloseTypeFundingCancelled:
+				r.FundingCanceled = true
+
+			case CloseTypeAbandoned:
+				r.Abandoned = true
+			}
+		}
+	}
+}
+
+// ClosedChannels returns a list of our closed channels.
+func (s *lightningClient) ClosedChannels(ctx context.Context,
+	opts ...ClosedChannelsOption) ([]ClosedChannel, error) {
+
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
+	req := &lnrpc.ClosedChannelsRequest{}
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	response, err := s.client.ClosedChannels(
-		s.adminMac.WithMacaroonAuth(rpcCtx),
-		&lnrpc.ClosedChannelsRequest{},
+		s.adminMac.WithMacaroonAuth(rpcCtx), req,
 	)
 	if err != nil {
 		return nil, err
@@ -2106,8 +3367,15 @@ func (s *lightningClient) ForwardingHistory(ctx context.Context,
 
 	events := make([]ForwardingEvent, len(response.ForwardingEvents))
 	for i, event := range response.ForwardingEvents {
+		// Use the nanosecond timestamp if it is available, and fall
+		// back to the deprecated second precision timestamp otherwise.
+		timestamp := time.Unix(int64(event.Timestamp), 0) // nolint:staticcheck
+		if event.TimestampNs != 0 {
+			timestamp = time.Unix(0, int64(event.TimestampNs))
+		}
+
 		events[i] = ForwardingEvent{
-			Timestamp:     time.Unix(int64(event.Timestamp), 0), // nolint:staticcheck
+			Timestamp:     timestamp,
 			ChannelIn:     event.ChanIdIn,
 			ChannelOut:    event.ChanIdOut,
 			AmountMsatIn:  lnwire.MilliSatoshi(event.AmtInMsat),
@@ -2122,13 +3390,49 @@ func (s *lightningClient) ForwardingHistory(ctx context.Context,
 	}, nil
 }
 
+// ForEachForwardingEvent walks through all forwarding events in the period of
+// the given request by querying them page by page, starting at the request's
+// offset. The callback is invoked once for every event, in the order they
+// were recorded. If MaxEvents isn't set, a default page size is used.
+// Iteration stops at the first error returned by the callback, which is then
+// returned to the caller.
+func ForEachForwardingEvent(ctx context.Context, client LightningClient,
+	req ForwardingHistoryRequest, cb func(ForwardingEvent) error) error {
+
+	if req.MaxEvents == 0 {
+		req.MaxEvents = defaultPageSize
+	}
+
+	for {
+		resp, err := client.ForwardingHistory(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range resp.Events {
+			if err := cb(event); err != nil {
+				return err
+			}
+		}
+
+		// If we received less than a full page, we've reached the end
+		// of the queried period.
+		if uint32(len(resp.Events)) < req.MaxEvents {
+			return nil
+		}
+
+		req.Offset = resp.LastIndexOffset
+	}
+}
+
 // ListInvoicesRequest contains the request parameters for a paginated
 // list invoices call.
 type ListInvoicesRequest struct {
 	// MaxInvoices is the maximum number of invoices to return.
 	MaxInvoices uint64
 
-	// Offset is the index from which to start querying.
+	// Offset is the add index from which to start querying. The invoice
+	// at the offset itself is not included in the response.
 	Offset uint64
 
 	// Reversed is set to query our invoices backwards.
@@ -2161,7 +3465,7 @@ func (s *lightningClient) ListInvoices(ctx context.Context,
 	resp, err := s.client.ListInvoices(
 		s.adminMac.WithMacaroonAuth(rpcCtx),
 		&lnrpc.ListInvoiceRequest{
-			PendingOnly:    false,
+			PendingOnly:    req.PendingOnly,
 			IndexOffset:    req.Offset,
 			NumMaxInvoices: req.MaxInvoices,
 			Reversed:       req.Reversed,
@@ -2188,6 +3492,47 @@ func (s *lightningClient) ListInvoices(ctx context.Context,
 	}, nil
 }
 
+// ForEachInvoice walks through all invoices matching the given request by
+// querying them page by page, starting at the request's offset, so that the
+// full invoice database never has to be held in memory. The callback is
+// invoked once for every invoice, in the order they are returned by lnd. If
+// MaxInvoices isn't set, a default page size is used. Iteration stops at the
+// first error returned by the callback, which is then returned to the caller.
+func ForEachInvoice(ctx context.Context, client LightningClient,
+	req ListInvoicesRequest, cb func(Invoice) error) error {
+
+	if req.MaxInvoices == 0 {
+		req.MaxInvoices = defaultPageSize
+	}
+
+	for {
+		resp, err := client.ListInvoices(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, invoice := range resp.Invoices {
+			if err := cb(invoice); err != nil {
+				return err
+			}
+		}
+
+		// If we received less than a full page, we've reached the end
+		// of our invoices.
+		if uint64(len(resp.Invoices)) < req.MaxInvoices {
+			return nil
+		}
+
+		// The offset is exclusive, so we continue from the first or
+		// last index of this page, depending on our query direction.
+		if req.Reversed {
+			req.Offset = resp.FirstIndexOffset
+		} else {
+			req.Offset = resp.LastIndexOffset
+		}
+	}
+}
+
 // Payment represents a payment made by our node.
 type Payment struct {
 	// Hash is the payment hash used.
@@ -2208,7 +3553,8 @@ type Payment struct {
 	// Fee is the amount in millisatoshis that was paid in fees.
 	Fee lnwire.MilliSatoshi
 
-	// Status describes the state of a payment.
+	// Status describes the state of a payment. Its set of htlc attempts
+	// contains the route, failure and resolution time of each attempt.
 	Status *PaymentStatus
 
 	// Htlcs is the set of htlc attempts made by the payment.
@@ -2216,8 +3562,15 @@ type Payment struct {
 
 	// SequenceNumber is a unique id for each payment.
 	SequenceNumber uint64
+
+	// CreationTime is the time the payment was created.
+	CreationTime time.Time
 }
 
+// defaultPageSize is the number of items queried at once when paging through
+// payments, invoices or forwarding events and no page size is given.
+const defaultPageSize = 1000
+
 // ListPaymentsRequest contains the request parameters for a paginated
 // list payments call.
 type ListPaymentsRequest struct {
@@ -2288,6 +3641,10 @@ func (s *lightningClient) ListPayments(ctx context.Context,
 			SequenceNumber: payment.PaymentIndex,
 		}
 
+		if payment.CreationTimeNs != 0 {
+			pmt.CreationTime = time.Unix(0, payment.CreationTimeNs)
+		}
+
 		// Add our preimage if it is known.
 		if payment.PaymentPreimage != "" {
 			preimage, err := lntypes.MakePreimageFromStr(
@@ -2309,6 +3666,47 @@ func (s *lightningClient) ListPayments(ctx context.Context,
 	}, nil
 }
 
+// ForEachPayment walks through all payments matching the given request by
+// querying them page by page, starting at the request's offset. The callback
+// is invoked once for every payment, in the order they are returned by lnd.
+// If MaxPayments isn't set, a default page size is used. Iteration stops at
+// the first error returned by the callback, which is then returned to the
+// caller.
+func ForEachPayment(ctx context.Context, client LightningClient,
+	req ListPaymentsRequest, cb func(Payment) error) error {
+
+	if req.MaxPayments == 0 {
+		req.MaxPayments = defaultPageSize
+	}
+
+	for {
+		resp, err := client.ListPayments(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, payment := range resp.Payments {
+			if err := cb(payment); err != nil {
+				return err
+			}
+		}
+
+		// If we received less than a full page, we've reached the end
+		// of our payments.
+		if uint64(len(resp.Payments)) < req.MaxPayments {
+			return nil
+		}
+
+		// The offset is exclusive, so we continue from the first or
+		// last index of this page, depending on our query direction.
+		if req.Reversed {
+			req.Offset = resp.FirstIndexOffset
+		} else {
+			req.Offset = resp.LastIndexOffset
+		}
+	}
+}
+
 // ChannelBackup retrieves the backup for a particular channel. The backup is
 // returned as an encrypted chanbackup.Single payload.
 func (s *lightningClient) ChannelBackup(ctx context.Context,
@@ -2350,6 +3748,121 @@ func (s *lightningClient) ChannelBackups(ctx context.Context) ([]byte, error) {
 	return resp.MultiChanBackup.MultiChanBackup, nil
 }
 
+// SingleChannelBackup is the encrypted backup of a single channel.
+type SingleChannelBackup struct {
+	// ChannelPoint is the funding outpoint of the backed up channel.
+	ChannelPoint wire.OutPoint
+
+	// Backup is the encrypted chanbackup.Single payload.
+	Backup []byte
+}
+
+// ChannelBackupSet holds a set of channel backups to verify or restore.
+// Either Singles or Multi must be set, but not both.
+type ChannelBackupSet struct {
+	// Singles is a list of single channel backups, as returned by
+	// ChannelBackup.
+	Singles []SingleChannelBackup
+
+	// Multi is an encrypted chanbackup.Multi payload, as returned by
+	// ChannelBackups.
+	Multi []byte
+}
+
+// validate checks that exactly one kind of backup is set.
+func (b ChannelBackupSet) validate() error {
+	switch {
+	case len(b.Singles) == 0 && len(b.Multi) == 0:
+		return errors.New("no channel backups provided")
+
+	case len(b.Singles) != 0 && len(b.Multi) != 0:
+		return errors.New("either single or multi channel backups " +
+			"must be provided, not both")
+	}
+
+	return nil
+}
+
+// rpcSingles converts the single channel backups of the set to their rpc
+// counterpart.
+func (b ChannelBackupSet) rpcSingles() *lnrpc.ChannelBackups {
+	backups := &lnrpc.ChannelBackups{
+		ChanBackups: make([]*lnrpc.ChannelBackup, len(b.Singles)),
+	}
+
+	for i, single := range b.Singles {
+		single := single
+		rpcChanPoint := &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: single.ChannelPoint.Hash[:],
+			},
+			OutputIndex: single.ChannelPoint.Index,
+		}
+
+		backups.ChanBackups[i] = &lnrpc.ChannelBackup{
+			ChanPoint:  rpcChanPoint,
+			ChanBackup: single.Backup,
+		}
+	}
+
+	return backups
+}
+
+// VerifyChannelBackup verifies that the given channel backups can be
+// decrypted and parsed by the backing lnd node.
+func (s *lightningClient) VerifyChannelBackup(ctx context.Context,
+	backups ChannelBackupSet) error {
+
+	if err := backups.validate(); err != nil {
+		return err
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	req := &lnrpc.ChanBackupSnapshot{}
+	if len(backups.Multi) != 0 {
+		req.MultiChanBackup = &lnrpc.MultiChanBackup{
+			MultiChanBackup: backups.Multi,
+		}
+	} else {
+		req.SingleChanBackups = backups.rpcSingles()
+	}
+
+	_, err := s.client.VerifyChanBackup(rpcCtx, req)
+	return err
+}
+
+// RestoreChannelBackups restores the given channel backups.
+func (s *lightningClient) RestoreChannelBackups(ctx context.Context,
+	backups ChannelBackupSet) error {
+
+	if err := backups.validate(); err != nil {
+		return err
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	req := &lnrpc.RestoreChanBackupRequest{}
+	if len(backups.Multi) != 0 {
+		req.Backup = &lnrpc.RestoreChanBackupRequest_MultiChanBackup{
+			MultiChanBackup: backups.Multi,
+		}
+	} else {
+		req.Backup = &lnrpc.RestoreChanBackupRequest_ChanBackups{
+			ChanBackups: backups.rpcSingles(),
+		}
+	}
+
+	_, err := s.client.RestoreChannelBackups(rpcCtx, req)
+	return err
+}
+
 // getOutPoint is a helper go convert a hash and output index to
 // a wire.OutPoint object.
 func getOutPoint(txID []byte, idx uint32) (*wire.OutPoint, error) {
@@ -2377,6 +3890,11 @@ func (s *lightningClient) getChannelEventUpdate(
 	case lnrpc.ChannelEventUpdate_PENDING_OPEN_CHANNEL:
 		result.UpdateType = PendingOpenChannelUpdate
 		channelPoint := rpcChannelEventUpdate.GetPendingOpenChannel()
+		if channelPoint == nil {
+			return nil, errors.New("pending open update without " +
+				"channel point")
+		}
+
 		result.ChannelPoint, err = getOutPoint(
 			channelPoint.Txid,
 			channelPoint.OutputIndex,
@@ -2385,6 +3903,10 @@ func (s *lightningClient) getChannelEventUpdate(
 			return nil, err
 		}
 
+		result.Event = &PendingOpenChannelEvent{
+			ChannelPoint: *result.ChannelPoint,
+		}
+
 	case lnrpc.ChannelEventUpdate_OPEN_CHANNEL:
 		result.UpdateType = OpenChannelUpdate
 		channel := rpcChannelEventUpdate.GetOpenChannel()
@@ -2394,6 +3916,18 @@ func (s *lightningClient) getChannelEventUpdate(
 			return nil, err
 		}
 
+		chanPoint, err := NewOutpointFromStr(
+			result.OpenedChannelInfo.ChannelPoint,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Event = &OpenChannelEvent{
+			ChannelPoint: *chanPoint,
+			Channel:      result.OpenedChannelInfo,
+		}
+
 	case lnrpc.ChannelEventUpdate_CLOSED_CHANNEL:
 		result.UpdateType = ClosedChannelUpdate
 		closeSummary := rpcChannelEventUpdate.GetClosedChannel()
@@ -2402,45 +3936,52 @@ func (s *lightningClient) getChannelEventUpdate(
 			return nil, err
 		}
 
+		result.Event, err = NewClosedChannelEvent(
+			result.ClosedChannelInfo,
+		)
+		if err != nil {
+			return nil, err
+		}
+
 	case lnrpc.ChannelEventUpdate_ACTIVE_CHANNEL:
 		result.UpdateType = ActiveChannelUpdate
-		channelPoint := rpcChannelEventUpdate.GetActiveChannel()
-		fundingTxID := channelPoint.FundingTxid.(*lnrpc.ChannelPoint_FundingTxidBytes)
-
-		result.ChannelPoint, err = getOutPoint(
-			fundingTxID.FundingTxidBytes,
-			channelPoint.OutputIndex,
+		result.ChannelPoint, err = unmarshallChannelPoint(
+			rpcChannelEventUpdate.GetActiveChannel(),
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		result.Event = &ActiveChannelEvent{
+			ChannelPoint: *result.ChannelPoint,
+		}
+
 	case lnrpc.ChannelEventUpdate_INACTIVE_CHANNEL:
 		result.UpdateType = InactiveChannelUpdate
-		channelPoint := rpcChannelEventUpdate.GetInactiveChannel()
-		fundingTxID := channelPoint.FundingTxid.(*lnrpc.ChannelPoint_FundingTxidBytes)
-
-		result.ChannelPoint, err = getOutPoint(
-			fundingTxID.FundingTxidBytes,
-			channelPoint.OutputIndex,
+		result.ChannelPoint, err = unmarshallChannelPoint(
+			rpcChannelEventUpdate.GetInactiveChannel(),
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		result.Event = &InactiveChannelEvent{
+			ChannelPoint: *result.ChannelPoint,
+		}
+
 	case lnrpc.ChannelEventUpdate_FULLY_RESOLVED_CHANNEL:
 		result.UpdateType = FullyResolvedChannelUpdate
-		channelPoint := rpcChannelEventUpdate.GetFullyResolvedChannel()
-		fundingTxID := channelPoint.FundingTxid.(*lnrpc.ChannelPoint_FundingTxidBytes)
-
-		result.ChannelPoint, err = getOutPoint(
-			fundingTxID.FundingTxidBytes,
-			channelPoint.OutputIndex,
+		result.ChannelPoint, err = unmarshallChannelPoint(
+			rpcChannelEventUpdate.GetFullyResolvedChannel(),
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		result.Event = &FullyResolvedChannelEvent{
+			ChannelPoint: *result.ChannelPoint,
+		}
+
 	default:
 		return nil, fmt.Errorf("unhandled update type: %v",
 			rpcChannelEventUpdate.Type.String())
@@ -2556,6 +4097,24 @@ type PaymentRequest struct {
 	// PaymentAddress is the payment address associated with the invoice,
 	// set if the receiver supports mpp.
 	PaymentAddress [32]byte
+
+	// DescriptionHash is the hash of the description of the payment
+	// request, if it commits to its description by hash.
+	DescriptionHash []byte
+
+	// FallbackAddr is an optional on-chain fallback address.
+	FallbackAddr string
+
+	// CltvExpiry is the final cltv delta required by the payment request.
+	CltvExpiry int64
+
+	// RouteHints is the set of route hints that assist in reaching the
+	// destination.
+	RouteHints [][]zpay32.HopHint
+
+	// Features is the set of feature bits signaled by the payment
+	// request.
+	Features []lnwire.FeatureBit
 }
 
 // DecodePaymentRequest decodes a payment request.
@@ -2584,26 +4143,94 @@ func (s *lightningClient) DecodePaymentRequest(ctx context.Context,
 		return nil, err
 	}
 
+	routeHints, err := unmarshallRouteHints(resp.RouteHints)
+	if err != nil {
+		return nil, err
+	}
+
 	paymentReq := &PaymentRequest{
-		Destination: dest,
-		Hash:        hash,
-		Value:       lnwire.MilliSatoshi(resp.NumMsat),
-		Description: resp.Description,
+		Destination:  dest,
+		Hash:         hash,
+		Value:        lnwire.MilliSatoshi(resp.NumMsat),
+		Description:  resp.Description,
+		FallbackAddr: resp.FallbackAddr,
+		CltvExpiry:   resp.CltvExpiry,
+		RouteHints:   routeHints,
+		Features:     make([]lnwire.FeatureBit, 0, len(resp.Features)),
 	}
 
 	copy(paymentReq.PaymentAddress[:], resp.PaymentAddr)
 
+	if resp.DescriptionHash != "" {
+		paymentReq.DescriptionHash, err = hex.DecodeString(
+			resp.DescriptionHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for featureBit := range resp.Features {
+		paymentReq.Features = append(
+			paymentReq.Features, lnwire.FeatureBit(featureBit),
+		)
+	}
+
 	// Set our timestamp values if they are non-zero, because unix zero is
-	// different to a zero time struct.
+	// different to a zero time struct. The expiry is given in seconds
+	// relative to the timestamp of the payment request.
 	if resp.Timestamp != 0 {
 		paymentReq.Timestamp = time.Unix(resp.Timestamp, 0)
+		paymentReq.Expiry = paymentReq.Timestamp.Add(
+			time.Duration(resp.Expiry) * time.Second,
+		)
 	}
 
-	if resp.Expiry != 0 {
-		paymentReq.Expiry = time.Unix(resp.Expiry, 0)
+	return paymentReq, nil
+}
+
+// unmarshallRouteHints converts a list of rpc route hints to zpay32 hop hints.
+func unmarshallRouteHints(rpcRouteHints []*lnrpc.RouteHint) (
+	[][]zpay32.HopHint, error) {
+
+	routeHints := make([][]zpay32.HopHint, 0, len(rpcRouteHints))
+	for _, rpcRouteHint := range rpcRouteHints {
+		routeHint := make(
+			[]zpay32.HopHint, 0, len(rpcRouteHint.HopHints),
+		)
+		for _, rpcHint := range rpcRouteHint.HopHints {
+			hint, err := unmarshallHopHint(rpcHint)
+			if err != nil {
+				return nil, err
+			}
+
+			routeHint = append(routeHint, hint)
+		}
+		routeHints = append(routeHints, routeHint)
 	}
 
-	return paymentReq, nil
+	return routeHints, nil
+}
+
+// unmarshallHopHint converts a rpc hop hint to a zpay32 hop hint.
+func unmarshallHopHint(rpcHint *lnrpc.HopHint) (zpay32.HopHint, error) {
+	nodeID, err := hex.DecodeString(rpcHint.NodeId)
+	if err != nil {
+		return zpay32.HopHint{}, err
+	}
+
+	pubKey, err := btcec.ParsePubKey(nodeID, btcec.S256())
+	if err != nil {
+		return zpay32.HopHint{}, err
+	}
+
+	return zpay32.HopHint{
+		NodeID:                    pubKey,
+		ChannelID:                 rpcHint.ChanId,
+		FeeBaseMSat:               rpcHint.FeeBaseMsat,
+		FeeProportionalMillionths: rpcHint.FeeProportionalMillionths,
+		CLTVExpiryDelta:           uint16(rpcHint.CltvExpiryDelta),
+	}, nil
 }
 
 // OpenChannel opens a channel to the peer provided with the amounts specified.
@@ -2627,7 +4254,22 @@ func (s *lightningClient) OpenChannel(ctx context.Context, peer route.Vertex,
 		return nil, err
 	}
 
-	var hash *chainhash.Hash
+	return unmarshallChannelPoint(chanPoint)
+}
+
+// unmarshallChannelPoint converts a rpc channel point to an outpoint, handling
+// both ways the funding txid can be encoded.
+func unmarshallChannelPoint(chanPoint *lnrpc.ChannelPoint) (*wire.OutPoint,
+	error) {
+
+	if chanPoint == nil {
+		return nil, errors.New("channel point missing")
+	}
+
+	var (
+		hash *chainhash.Hash
+		err  error
+	)
 	switch h := chanPoint.FundingTxid.(type) {
 	case *lnrpc.ChannelPoint_FundingTxidBytes:
 		hash, err = chainhash.NewHash(h.FundingTxidBytes)
@@ -2649,6 +4291,181 @@ func (s *lightningClient) OpenChannel(ctx context.Context, peer route.Vertex,
 	}, nil
 }
 
+// BatchOpenChannel describes a single channel that is opened as part of a
+// batch channel open.
+type BatchOpenChannel struct {
+	// NodePubkey is the public key of the peer to open the channel with.
+	NodePubkey route.Vertex
+
+	// LocalFundingAmount is the amount we commit to the channel.
+	LocalFundingAmount btcutil.Amount
+
+	// PushAmount is the amount that is pushed to the remote side as part
+	// of the initial commitment state.
+	PushAmount btcutil.Amount
+
+	// Private indicates that the channel should not be announced to the
+	// network.
+	Private bool
+
+	// MinHtlc is the minimum value for incoming HTLCs on the channel. If
+	// it is zero, lnd's default is used.
+	MinHtlc lnwire.MilliSatoshi
+
+	// RemoteCsvDelay is the delay we require on the remote's commitment
+	// transaction. If it is zero, lnd's default is used.
+	RemoteCsvDelay uint32
+
+	// CloseAddress is an optional upfront shutdown address the funds are
+	// paid out to on cooperative close.
+	CloseAddress btcutil.Address
+}
+
+// BatchOpenChannelRequest is the request of a BatchOpenChannel call.
+type BatchOpenChannelRequest struct {
+	// Channels is the list of channels to open.
+	Channels []*BatchOpenChannel
+
+	// TargetConf is the confirmation target for the funding transaction.
+	// Either this or SatPerVbyte can be set.
+	TargetConf int32
+
+	// SatPerVbyte is the fee rate in sat/vbyte for the funding
+	// transaction. Either this or TargetConf can be set.
+	SatPerVbyte int64
+
+	// MinConfs is the minimum number of confirmations the UTXOs used to
+	// fund the channels must have.
+	MinConfs int32
+
+	// SpendUnconfirmed indicates that unconfirmed UTXOs may be used to
+	// fund the channels. It can't be combined with MinConfs.
+	SpendUnconfirmed bool
+
+	// Label is an optional label for the funding transaction.
+	Label string
+}
+
+// BatchOpenChannelResult is the result of a BatchOpenChannel call.
+type BatchOpenChannelResult struct {
+	// FundingTxid is the hash of the funding transaction of all channels.
+	FundingTxid chainhash.Hash
+
+	// ChannelPoints is the list of channel points of the opened channels,
+	// in the same order as the channels of the request.
+	ChannelPoints []*wire.OutPoint
+}
+
+// BatchOpenChannel opens multiple channels to the peers provided with a single
+// funding transaction.
+func (s *lightningClient) BatchOpenChannel(ctx context.Context,
+	req *BatchOpenChannelRequest) (*BatchOpenChannelResult, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	channels := make([]*lnrpc.BatchOpenChannel, len(req.Channels))
+	for i, channel := range req.Channels {
+		var closeAddr string
+		if channel.CloseAddress != nil {
+			closeAddr = channel.CloseAddress.String()
+		}
+
+		nodePubkey := channel.NodePubkey
+		channels[i] = &lnrpc.BatchOpenChannel{
+			NodePubkey:         nodePubkey[:],
+			LocalFundingAmount: int64(channel.LocalFundingAmount),
+			PushSat:            int64(channel.PushAmount),
+			Private:            channel.Private,
+			MinHtlcMsat:        int64(channel.MinHtlc),
+			RemoteCsvDelay:     channel.RemoteCsvDelay,
+			CloseAddress:       closeAddr,
+		}
+	}
+
+	resp, err := s.client.BatchOpenChannel(
+		rpcCtx, &lnrpc.BatchOpenChannelRequest{
+			Channels:         channels,
+			TargetConf:       req.TargetConf,
+			SatPerVbyte:      req.SatPerVbyte,
+			MinConfs:         req.MinConfs,
+			SpendUnconfirmed: req.SpendUnconfirmed,
+			Label:            req.Label,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchOpenChannelResult{
+		ChannelPoints: make([]*wire.OutPoint, len(resp.PendingChannels)),
+	}
+	for i, pending := range resp.PendingChannels {
+		hash, err := chainhash.NewHash(pending.Txid)
+		if err != nil {
+			return nil, err
+		}
+
+		result.FundingTxid = *hash
+		result.ChannelPoints[i] = &wire.OutPoint{
+			Hash:  *hash,
+			Index: pending.OutputIndex,
+		}
+	}
+
+	return result, nil
+}
+
+// AbandonChannelOption is a functional option that modifies an
+// AbandonChannel request.
+type AbandonChannelOption func(r *lnrpc.AbandonChannelRequest)
+
+// WithPendingFundingShimOnly is an option for only abandoning a channel that
+// is still pending and was funded through a funding shim (PSBT flow).
+func WithPendingFundingShimOnly() AbandonChannelOption {
+	return func(r *lnrpc.AbandonChannelRequest) {
+		r.PendingFundingShimOnly = true
+	}
+}
+
+// WithIKnowWhatIAmDoing is an option that confirms the caller is aware of the
+// risks of abandoning a channel. lnd nodes that were not built with the dev
+// build tag refuse to abandon channels that weren't funded through a funding
+// shim unless this is set.
+func WithIKnowWhatIAmDoing() AbandonChannelOption {
+	return func(r *lnrpc.AbandonChannelRequest) {
+		r.IKnowWhatIAmDoing = true
+	}
+}
+
+// AbandonChannel removes all channel state from the database of the backing
+// lnd node without attempting to close the channel on chain.
+func (s *lightningClient) AbandonChannel(ctx context.Context,
+	channel *wire.OutPoint, opts ...AbandonChannelOption) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req := &lnrpc.AbandonChannelRequest{
+		ChannelPoint: &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: channel.Hash[:],
+			},
+			OutputIndex: channel.Index,
+		},
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	_, err := s.client.AbandonChannel(
+		s.adminMac.WithMacaroonAuth(rpcCtx), req,
+	)
+	return err
+}
+
 // CloseChannelUpdate is an interface implemented by channel close updates.
 type CloseChannelUpdate interface {
 	// CloseTxid returns the closing txid of the channel.
@@ -2677,6 +4494,18 @@ func (p *ChannelClosedUpdate) CloseTxid() chainhash.Hash {
 	return p.CloseTx
 }
 
+// CloseChannelOption is a functional option that modifies the fee settings of
+// a CloseChannel request.
+type CloseChannelOption func(r *lnrpc.CloseChannelRequest)
+
+// WithSatPerVbyte is an option for setting the fee rate of the closing
+// transaction in sat/vbyte. It can't be combined with a conf target.
+func WithSatPerVbyte(satPerVbyte uint64) CloseChannelOption {
+	return func(r *lnrpc.CloseChannelRequest) {
+		r.SatPerVbyte = satPerVbyte
+	}
+}
+
 // CloseChannel closes the channel provided, returning a channel that will send
 // a stream of close updates, and an error channel which will receive errors if
 // the channel close stream fails. This function starts a goroutine to consume
@@ -2688,8 +4517,8 @@ func (p *ChannelClosedUpdate) CloseTxid() chainhash.Hash {
 // that *does not* have an upfront shutdown addresss set.
 func (s *lightningClient) CloseChannel(ctx context.Context,
 	channel *wire.OutPoint, force bool, confTarget int32,
-	deliveryAddr btcutil.Address) (chan CloseChannelUpdate, chan error,
-	error) {
+	deliveryAddr btcutil.Address, opts ...CloseChannelOption) (
+	chan CloseChannelUpdate, chan error, error) {
 
 	var (
 		rpcCtx  = s.adminMac.WithMacaroonAuth(ctx)
@@ -2700,7 +4529,7 @@ func (s *lightningClient) CloseChannel(ctx context.Context,
 		addrStr = deliveryAddr.String()
 	}
 
-	stream, err := s.client.CloseChannel(rpcCtx, &lnrpc.CloseChannelRequest{
+	req := &lnrpc.CloseChannelRequest{
 		ChannelPoint: &lnrpc.ChannelPoint{
 			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
 				FundingTxidBytes: channel.Hash[:],
@@ -2710,7 +4539,12 @@ func (s *lightningClient) CloseChannel(ctx context.Context,
 		TargetConf:      confTarget,
 		Force:           force,
 		DeliveryAddress: addrStr,
-	})
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	stream, err := s.client.CloseChannel(rpcCtx, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -2811,6 +4645,10 @@ type PolicyUpdateRequest struct {
 	// this value goes up to 6 decimal places, so 1e-6.
 	FeeRate float64
 
+	// FeeRatePPM is the effective fee rate in parts per million. It can't
+	// be set together with FeeRate.
+	FeeRatePPM uint32
+
 	// TimeLockDelta is the required timelock delta for HTLCs forwarded over
 	// the channel.
 	TimeLockDelta uint32
@@ -2840,33 +4678,171 @@ func (s *lightningClient) UpdateChanPolicy(ctx context.Context,
 	rpcReq := &lnrpc.PolicyUpdateRequest{
 		BaseFeeMsat:   req.BaseFeeMsat,
 		FeeRate:       req.FeeRate,
+		FeeRatePpm:    req.FeeRatePPM,
 		TimeLockDelta: req.TimeLockDelta,
 		MaxHtlcMsat:   req.MaxHtlcMsat,
 	}
 
-	if req.MinHtlcMsatSpecified {
-		rpcReq.MinHtlcMsatSpecified = true
-		rpcReq.MinHtlcMsat = req.MinHtlcMsat
+	if req.MinHtlcMsatSpecified {
+		rpcReq.MinHtlcMsatSpecified = true
+		rpcReq.MinHtlcMsat = req.MinHtlcMsat
+	}
+
+	if chanPoint != nil {
+		rpcChanPoint := &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: chanPoint.Hash[:],
+			},
+			OutputIndex: chanPoint.Index,
+		}
+		rpcReq.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{
+			ChanPoint: rpcChanPoint,
+		}
+	} else {
+		rpcReq.Scope = &lnrpc.PolicyUpdateRequest_Global{
+			Global: true,
+		}
+	}
+
+	resp, err := s.client.UpdateChannelPolicy(rpcCtx, rpcReq)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.FailedUpdates) == 0 {
+		return nil
+	}
+
+	policyErr := &PolicyUpdateError{
+		FailedUpdates: make(
+			[]FailedPolicyUpdate, len(resp.FailedUpdates),
+		),
+	}
+	for i, failure := range resp.FailedUpdates {
+		update := FailedPolicyUpdate{
+			Reason: failure.Reason,
+			Error:  failure.UpdateError,
+		}
+
+		if failure.Outpoint != nil {
+			hash, err := chainhash.NewHash(failure.Outpoint.TxidBytes)
+			if err != nil {
+				return err
+			}
+
+			update.ChannelPoint = wire.OutPoint{
+				Hash:  *hash,
+				Index: failure.Outpoint.OutputIndex,
+			}
+		}
+
+		policyErr.FailedUpdates[i] = update
+	}
+
+	return policyErr
+}
+
+// FailedPolicyUpdate describes why the policy of a channel couldn't be
+// updated.
+type FailedPolicyUpdate struct {
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Reason is the reason the update failed.
+	Reason lnrpc.UpdateFailure
+
+	// Error is a human readable description of the failure.
+	Error string
+}
+
+// PolicyUpdateError is returned by UpdateChanPolicy if the policy couldn't be
+// applied to some of the channels. The policy of all other channels was
+// updated successfully.
+type PolicyUpdateError struct {
+	// FailedUpdates holds the details of each channel that couldn't be
+	// updated.
+	FailedUpdates []FailedPolicyUpdate
+}
+
+// Error returns a string representation of the failed policy updates.
+func (e *PolicyUpdateError) Error() string {
+	failures := make([]string, len(e.FailedUpdates))
+	for i, update := range e.FailedUpdates {
+		failures[i] = fmt.Sprintf("%v: %v (%v)", update.ChannelPoint,
+			update.Reason, update.Error)
+	}
+
+	return fmt.Sprintf("policy update failed for %d channel(s): %v",
+		len(failures), strings.Join(failures, ", "))
+}
+
+// ChannelFeeReport holds the current fee policy of one of our channels.
+type ChannelFeeReport struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint string
+
+	// BaseFeeMsat is the base fee charged regardless of the number of
+	// milli-satoshis sent.
+	BaseFeeMsat lnwire.MilliSatoshi
+
+	// FeePerMil is the amount charged per million satoshis forwarded.
+	FeePerMil int64
+
+	// FeeRate is the effective fee rate in milli-satoshis, computed by
+	// dividing the fee per mil by one million.
+	FeeRate float64
+}
+
+// FeeReport holds the fee policies of our channels and the fees we earned
+// from forwarding.
+type FeeReport struct {
+	// Channels is the current fee policy of each of our channels.
+	Channels []ChannelFeeReport
+
+	// DayFees is the total amount of fees earned over the last day.
+	DayFees btcutil.Amount
+
+	// WeekFees is the total amount of fees earned over the last week.
+	WeekFees btcutil.Amount
+
+	// MonthFees is the total amount of fees earned over the last month.
+	MonthFees btcutil.Amount
+}
+
+// FeeReport returns the current fee policies of all our channels and the fees
+// we earned from forwarding over the last day, week and month.
+func (s *lightningClient) FeeReport(ctx context.Context) (*FeeReport, error) {
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	resp, err := s.client.FeeReport(rpcCtx, &lnrpc.FeeReportRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeeReport{
+		Channels:  make([]ChannelFeeReport, len(resp.ChannelFees)),
+		DayFees:   btcutil.Amount(resp.DayFeeSum),
+		WeekFees:  btcutil.Amount(resp.WeekFeeSum),
+		MonthFees: btcutil.Amount(resp.MonthFeeSum),
 	}
 
-	if chanPoint != nil {
-		rpcChanPoint := &lnrpc.ChannelPoint{
-			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
-				FundingTxidBytes: chanPoint.Hash[:],
-			},
-			OutputIndex: chanPoint.Index,
-		}
-		rpcReq.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{
-			ChanPoint: rpcChanPoint,
-		}
-	} else {
-		rpcReq.Scope = &lnrpc.PolicyUpdateRequest_Global{
-			Global: true,
+	for i, channel := range resp.ChannelFees {
+		report.Channels[i] = ChannelFeeReport{
+			ChannelID:    channel.ChanId,
+			ChannelPoint: channel.ChannelPoint,
+			BaseFeeMsat:  lnwire.MilliSatoshi(channel.BaseFeeMsat),
+			FeePerMil:    channel.FeePerMil,
+			FeeRate:      channel.FeeRate,
 		}
 	}
 
-	_, err := s.client.UpdateChannelPolicy(rpcCtx, rpcReq)
-	return err
+	return report, nil
 }
 
 // RoutingPolicy holds the edge routing policy for a channel edge.
@@ -2928,15 +4904,24 @@ func getRoutingPolicy(policy *lnrpc.RoutingPolicy) *RoutingPolicy {
 		return nil
 	}
 
-	return &RoutingPolicy{
+	routingPolicy := &RoutingPolicy{
 		TimeLockDelta:    policy.TimeLockDelta,
 		MinHtlcMsat:      policy.MinHtlc,
 		MaxHtlcMsat:      policy.MaxHtlcMsat,
 		FeeBaseMsat:      policy.FeeBaseMsat,
 		FeeRateMilliMsat: policy.FeeRateMilliMsat,
 		Disabled:         policy.Disabled,
-		LastUpdate:       time.Unix(int64(policy.LastUpdate), 0),
 	}
+
+	// Only set the last update if it is non-zero, because unix zero is
+	// different to a zero time struct.
+	if policy.LastUpdate != 0 {
+		routingPolicy.LastUpdate = time.Unix(
+			int64(policy.LastUpdate), 0,
+		)
+	}
+
+	return routingPolicy
 }
 
 // GetChanInfo returns the channel info for the passed channel, including the
@@ -3013,52 +4998,250 @@ func (s *lightningClient) ListPeers(ctx context.Context) ([]Peer,
 			PingTime:      pingTime,
 			Sent:          btcutil.Amount(peer.SatSent),
 			Received:      btcutil.Amount(peer.SatRecv),
+			Features: make(
+				[]lnwire.FeatureBit, 0, len(peer.Features),
+			),
+			SyncType:  peer.SyncType,
+			Errors:    make([]PeerError, len(peer.Errors)),
+			FlapCount: peer.FlapCount,
+		}
+
+		for featureBit := range peer.Features {
+			peers[i].Features = append(
+				peers[i].Features,
+				lnwire.FeatureBit(featureBit),
+			)
+		}
+
+		for j, peerErr := range peer.Errors {
+			timestamp := int64(peerErr.Timestamp)
+			peers[i].Errors[j] = PeerError{
+				Timestamp: time.Unix(timestamp, 0),
+				Error:     peerErr.Error,
+			}
+		}
+
+		if peer.LastFlapNs != 0 {
+			peers[i].LastFlap = time.Unix(0, peer.LastFlapNs)
 		}
 	}
 
 	return peers, err
 }
 
+// PeerEventType describes the type of a peer event.
+type PeerEventType uint8
+
+const (
+	// PeerOnline indicates that a peer came online.
+	PeerOnline PeerEventType = iota
+
+	// PeerOffline indicates that a peer went offline.
+	PeerOffline
+)
+
+// String returns a string representation of a peer event type.
+func (p PeerEventType) String() string {
+	switch p {
+	case PeerOnline:
+		return "Online"
+
+	case PeerOffline:
+		return "Offline"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// PeerEvent is an event that describes a change in the connection state of a
+// peer.
+type PeerEvent struct {
+	// PubKey is the public key of the peer.
+	PubKey route.Vertex
+
+	// Type is the type of the event.
+	Type PeerEventType
+}
+
+// SubscribePeerEvents allows a client to subscribe to peers coming online and
+// going offline.
+func (s *lightningClient) SubscribePeerEvents(ctx context.Context) (
+	<-chan *PeerEvent, <-chan error, error) {
+
+	eventStream, err := s.client.SubscribePeerEvents(
+		s.adminMac.WithMacaroonAuth(ctx),
+		&lnrpc.PeerEventSubscription{},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan *PeerEvent)
+	errChan := make(chan error, 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			rpcEvent, err := eventStream.Recv()
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			event, err := unmarshallPeerEvent(rpcEvent)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, errChan, nil
+}
+
+// unmarshallPeerEvent converts a rpc peer event to a PeerEvent.
+func unmarshallPeerEvent(rpcEvent *lnrpc.PeerEvent) (*PeerEvent, error) {
+	pubKey, err := route.NewVertexFromStr(rpcEvent.PubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &PeerEvent{
+		PubKey: pubKey,
+	}
+
+	switch rpcEvent.Type {
+	case lnrpc.PeerEvent_PEER_ONLINE:
+		event.Type = PeerOnline
+
+	case lnrpc.PeerEvent_PEER_OFFLINE:
+		event.Type = PeerOffline
+
+	default:
+		return nil, fmt.Errorf("unknown peer event type: %v",
+			rpcEvent.Type)
+	}
+
+	return event, nil
+}
+
+// ConnectOption is a functional option that configures a ConnectPeer request.
+type ConnectOption func(r *lnrpc.ConnectPeerRequest)
+
+// WithConnectTimeout is an option for setting the time lnd waits for the
+// connection to be established before giving up. The timeout is rounded down
+// to full seconds. Note that the timeout of the client's rpc call still
+// applies.
+func WithConnectTimeout(timeout time.Duration) ConnectOption {
+	return func(r *lnrpc.ConnectPeerRequest) {
+		r.Timeout = uint64(timeout / time.Second)
+	}
+}
+
 // Connect attempts to connect to a peer at the host specified.
 func (s *lightningClient) Connect(ctx context.Context, peer route.Vertex,
-	host string, permanent bool) error {
+	host string, permanent bool, opts ...ConnectOption) error {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
 
-	_, err := s.client.ConnectPeer(rpcCtx, &lnrpc.ConnectPeerRequest{
+	req := &lnrpc.ConnectPeerRequest{
 		Addr: &lnrpc.LightningAddress{
 			Pubkey: peer.String(),
 			Host:   host,
 		},
 		Perm: permanent,
+	}
+
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	_, err := s.client.ConnectPeer(rpcCtx, req)
+
+	return err
+}
+
+// Disconnect disconnects from the given peer.
+func (s *lightningClient) Disconnect(ctx context.Context,
+	peer route.Vertex) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	_, err := s.client.DisconnectPeer(rpcCtx, &lnrpc.DisconnectPeerRequest{
+		PubKey: peer.String(),
 	})
 
 	return err
 }
 
+// SendOption is a functional option that configures which of our outputs are
+// spent by an on chain send.
+type SendOption func(o *sendOptions)
+
+// sendOptions holds the options of an on chain send.
+type sendOptions struct {
+	minConfs         int32
+	spendUnconfirmed bool
+}
+
+// WithMinConfs is an option for only spending outputs that have at least the
+// given number of confirmations.
+func WithMinConfs(minConfs int32) SendOption {
+	return func(o *sendOptions) {
+		o.minConfs = minConfs
+	}
+}
+
+// WithSpendUnconfirmed is an option for allowing unconfirmed outputs to be
+// spent.
+func WithSpendUnconfirmed() SendOption {
+	return func(o *sendOptions) {
+		o.spendUnconfirmed = true
+	}
+}
+
 // SendCoins sends the passed amount of (or all) coins to the passed address.
 // Either amount or sendAll must be specified, while confTarget, satsPerByte are
 // optional and may be set to zero in which case automatic conf target and fee
 // will be used. Returns the tx id upon success.
 func (s *lightningClient) SendCoins(ctx context.Context, addr btcutil.Address,
 	amount btcutil.Amount, sendAll bool, confTarget int32,
-	satsPerByte int64, label string) (string, error) {
+	satsPerByte int64, label string, opts ...SendOption) (string, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
 
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	req := &lnrpc.SendCoinsRequest{
-		Addr:       addr.String(),
-		Amount:     int64(amount),
-		TargetConf: confTarget,
-		SatPerByte: satsPerByte,
-		SendAll:    sendAll,
-		Label:      label,
+		Addr:             addr.String(),
+		Amount:           int64(amount),
+		TargetConf:       confTarget,
+		SatPerVbyte:      uint64(satsPerByte),
+		SendAll:          sendAll,
+		Label:            label,
+		MinConfs:         options.minConfs,
+		SpendUnconfirmed: options.spendUnconfirmed,
 	}
 
 	resp, err := s.client.SendCoins(rpcCtx, req)
@@ -3069,6 +5252,41 @@ func (s *lightningClient) SendCoins(ctx context.Context, addr btcutil.Address,
 	return resp.Txid, nil
 }
 
+// SendMany sends coins to multiple addresses in a single transaction.
+func (s *lightningClient) SendMany(ctx context.Context,
+	outputs map[string]btcutil.Amount, confTarget int32, satPerVbyte uint64,
+	label string, opts ...SendOption) (*chainhash.Hash, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	addrToAmount := make(map[string]int64, len(outputs))
+	for addr, amount := range outputs {
+		addrToAmount[addr] = int64(amount)
+	}
+
+	resp, err := s.client.SendMany(rpcCtx, &lnrpc.SendManyRequest{
+		AddrToAmount:     addrToAmount,
+		TargetConf:       confTarget,
+		SatPerVbyte:      satPerVbyte,
+		Label:            label,
+		MinConfs:         options.minConfs,
+		SpendUnconfirmed: options.spendUnconfirmed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chainhash.NewHashFromStr(resp.Txid)
+}
+
 // ChannelBalance returns a summary of our channel balances.
 func (s *lightningClient) ChannelBalance(ctx context.Context) (*ChannelBalance,
 	error) {
@@ -3088,6 +5306,20 @@ func (s *lightningClient) ChannelBalance(ctx context.Context) (*ChannelBalance,
 	return &ChannelBalance{
 		Balance:        btcutil.Amount(resp.Balance),            // nolint:staticcheck
 		PendingBalance: btcutil.Amount(resp.PendingOpenBalance), // nolint:staticcheck
+		LocalBalance:   amountMsat(resp.LocalBalance),
+		RemoteBalance:  amountMsat(resp.RemoteBalance),
+		UnsettledLocalBalance: amountMsat(
+			resp.UnsettledLocalBalance,
+		),
+		UnsettledRemoteBalance: amountMsat(
+			resp.UnsettledRemoteBalance,
+		),
+		PendingOpenLocalBalance: amountMsat(
+			resp.PendingOpenLocalBalance,
+		),
+		PendingOpenRemoteBalance: amountMsat(
+			resp.PendingOpenRemoteBalance,
+		),
 	}, nil
 }
 
@@ -3136,6 +5368,33 @@ func (s *lightningClient) GetNodeInfo(ctx context.Context, pubkey route.Vertex,
 func (s *lightningClient) DescribeGraph(ctx context.Context,
 	includeUnannounced bool) (*Graph, error) {
 
+	graph := &Graph{}
+	err := s.WalkGraph(
+		ctx, includeUnannounced, func(node *Node) error {
+			graph.Nodes = append(graph.Nodes, *node)
+			return nil
+		}, func(edge *ChannelEdge) error {
+			graph.Edges = append(graph.Edges, *edge)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+// WalkGraph queries our view of the graph and passes each node and edge to
+// the given callbacks one by one. While lnd returns the graph in a single
+// response, each entry of the response is released as soon as it has been
+// decoded, so that the caller can process large graphs without holding both
+// the raw response and a typed copy of it in memory. Iteration stops at the
+// first error returned by a callback, which is then returned to the caller.
+func (s *lightningClient) WalkGraph(ctx context.Context,
+	includeUnannounced bool, nodeCb func(*Node) error,
+	edgeCb func(*ChannelEdge) error) error {
+
 	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -3145,33 +5404,44 @@ func (s *lightningClient) DescribeGraph(ctx context.Context,
 		IncludeUnannounced: includeUnannounced,
 	})
 	if err != nil {
-		return nil, err
-	}
-
-	graph := &Graph{
-		Nodes: make([]Node, len(resp.Nodes)),
-		Edges: make([]ChannelEdge, len(resp.Edges)),
+		return err
 	}
 
 	for i, node := range resp.Nodes {
+		resp.Nodes[i] = nil
+
+		if nodeCb == nil {
+			continue
+		}
+
 		nodeinfo, err := newNode(node)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		graph.Nodes[i] = *nodeinfo
+		if err := nodeCb(nodeinfo); err != nil {
+			return err
+		}
 	}
 
 	for i, edge := range resp.Edges {
+		resp.Edges[i] = nil
+
+		if edgeCb == nil {
+			continue
+		}
+
 		chanEdge, err := newChannelEdge(edge)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		graph.Edges[i] = *chanEdge
+		if err := edgeCb(chanEdge); err != nil {
+			return err
+		}
 	}
 
-	return graph, nil
+	return nil
 }
 
 // SubscribeGraph allows a client to subscribe to gaph topology updates.
@@ -3240,8 +5510,20 @@ func getGraphTopologyUpdate(update *lnrpc.GraphTopologyUpdate) (
 			return nil, err
 		}
 
+		// Prefer the typed node addresses and only fall back to the
+		// deprecated list of address strings if they aren't set.
+		addresses := nodeUpdate.Addresses // nolint:staticcheck
+		if len(nodeUpdate.NodeAddresses) > 0 {
+			addresses = make(
+				[]string, len(nodeUpdate.NodeAddresses),
+			)
+			for j, addr := range nodeUpdate.NodeAddresses {
+				addresses[j] = addr.Addr
+			}
+		}
+
 		result.NodeUpdates[i] = NodeUpdate{
-			Addresses:   nodeUpdate.Addresses, // nolint:staticcheck
+			Addresses:   addresses,
 			IdentityKey: identityKey,
 			Features: make(
 				[]lnwire.FeatureBit, 0,
@@ -3260,10 +5542,7 @@ func getGraphTopologyUpdate(update *lnrpc.GraphTopologyUpdate) (
 	}
 
 	for i, channelUpdate := range update.ChannelUpdates {
-		channelPoint, err := getOutPoint(
-			channelUpdate.ChanPoint.GetFundingTxidBytes(),
-			channelUpdate.ChanPoint.OutputIndex,
-		)
+		channelPoint, err := unmarshallChannelPoint(channelUpdate.ChanPoint)
 		if err != nil {
 			return nil, err
 		}
@@ -3299,10 +5578,7 @@ func getGraphTopologyUpdate(update *lnrpc.GraphTopologyUpdate) (
 	}
 
 	for i, closedChan := range update.ClosedChans {
-		channelPoint, err := getOutPoint(
-			closedChan.ChanPoint.GetFundingTxidBytes(),
-			closedChan.ChanPoint.OutputIndex,
-		)
+		channelPoint, err := unmarshallChannelPoint(closedChan.ChanPoint)
 		if err != nil {
 			return nil, err
 		}
@@ -3365,6 +5641,23 @@ type InvoiceSubscriptionRequest struct {
 	SettleIndex uint64
 }
 
+// Update advances the indices of the subscription request past the given
+// invoice update. Persisting the request after each update allows a
+// subscription to be resumed after a restart without missing any added
+// invoices. Settled invoices are only replayed by lnd once the settle index is
+// non-zero, so invoices settled before the first settlement was seen are
+// missed on resume. Callers that need those should initialize SettleIndex
+// from the invoices they already know about before subscribing.
+func (r *InvoiceSubscriptionRequest) Update(invoice *Invoice) {
+	if invoice.AddIndex > r.AddIndex {
+		r.AddIndex = invoice.AddIndex
+	}
+
+	if invoice.SettleIndex > r.SettleIndex {
+		r.SettleIndex = invoice.SettleIndex
+	}
+}
+
 // SubscribeInvoices subscribes a client to updates of newly added/settled invoices.
 func (s *lightningClient) SubscribeInvoices(ctx context.Context,
 	req InvoiceSubscriptionRequest) (<-chan *Invoice, <-chan error, error) {
@@ -3495,6 +5788,14 @@ func (s *lightningClient) ChannelAcceptor(ctx context.Context,
 
 			request, err := acceptStream.Recv()
 			if err != nil {
+				// If our context was canceled while we were
+				// waiting for a request, report the cancellation
+				// rather than the resulting stream error.
+				if ctx.Err() != nil {
+					errChan <- ctx.Err()
+					return
+				}
+
 				errChan <- fmt.Errorf("channel acceptor "+
 					"receive failed: %v", err)
 
@@ -3599,6 +5900,27 @@ func (s *lightningClient) QueryRoutes(ctx context.Context,
 		rpcReq.LastHopPubkey = req.LastHop[:]
 	}
 
+	if req.OutgoingChanID != nil {
+		rpcReq.OutgoingChanId = *req.OutgoingChanID
+	}
+
+	for _, node := range req.IgnoredNodes {
+		node := node
+		rpcReq.IgnoredNodes = append(rpcReq.IgnoredNodes, node[:])
+	}
+
+	for _, pair := range req.IgnoredPairs {
+		pair := pair
+		rpcReq.IgnoredPairs = append(
+			rpcReq.IgnoredPairs, &lnrpc.NodePair{
+				From: pair.From[:],
+				To:   pair.To[:],
+			},
+		)
+	}
+
+	rpcReq.DestCustomRecords = req.DestCustomRecords
+
 	var err error
 	rpcReq.RouteHints, err = marshallRouteHints(req.RouteHints)
 	if err != nil {
@@ -3614,20 +5936,87 @@ func (s *lightningClient) QueryRoutes(ctx context.Context,
 		return nil, ErrNoRouteFound
 	}
 
-	route := resp.Routes[0]
-	hops := make([]*Hop, len(route.Hops))
-	for i, rpcHop := range route.Hops {
+	rpcRoute := resp.Routes[0]
+	hops := make([]*Hop, len(rpcRoute.Hops))
+	for i, rpcHop := range rpcRoute.Hops {
 		hops[i], err = unmarshallHop(rpcHop)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	var source route.Vertex
+	if req.Source != nil {
+		source = *req.Source
+	}
+
+	fullRoute, err := unmarshallRoute(rpcRoute, source)
+	if err != nil {
+		return nil, err
+	}
+
 	return &QueryRoutesResponse{
-		TotalTimeLock: route.TotalTimeLock,
+		TotalTimeLock: rpcRoute.TotalTimeLock,
+		Hops:          hops,
+		TotalFeesMsat: lnwire.MilliSatoshi(rpcRoute.TotalFeesMsat),
+		TotalAmtMsat:  lnwire.MilliSatoshi(rpcRoute.TotalAmtMsat),
+		Route:         fullRoute,
+	}, nil
+}
+
+// unmarshallRoute converts an rpc route into a route.Route that starts at the
+// given source.
+func unmarshallRoute(rpcRoute *lnrpc.Route, source route.Vertex) (
+	*route.Route, error) {
+
+	if rpcRoute == nil {
+		return nil, errors.New("route missing")
+	}
+
+	hops := make([]*route.Hop, len(rpcRoute.Hops))
+	for i, rpcHop := range rpcRoute.Hops {
+		pubKey, err := route.NewVertexFromStr(rpcHop.PubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		hop := &route.Hop{
+			PubKeyBytes:      pubKey,
+			ChannelID:        rpcHop.ChanId,
+			OutgoingTimeLock: rpcHop.Expiry,
+			AmtToForward: lnwire.MilliSatoshi(
+				rpcHop.AmtToForwardMsat,
+			),
+			CustomRecords: rpcHop.CustomRecords,
+			LegacyPayload: !rpcHop.TlvPayload,
+		}
+
+		if mpp := rpcHop.MppRecord; mpp != nil {
+			var paymentAddr [32]byte
+			copy(paymentAddr[:], mpp.PaymentAddr)
+
+			hop.MPP = record.NewMPP(
+				lnwire.MilliSatoshi(mpp.TotalAmtMsat),
+				paymentAddr,
+			)
+		}
+
+		if amp := rpcHop.AmpRecord; amp != nil {
+			var rootShare, setID [32]byte
+			copy(rootShare[:], amp.RootShare)
+			copy(setID[:], amp.SetId)
+
+			hop.AMP = record.NewAMP(rootShare, setID, amp.ChildIndex)
+		}
+
+		hops[i] = hop
+	}
+
+	return &route.Route{
+		TotalTimeLock: rpcRoute.TotalTimeLock,
+		TotalAmount:   lnwire.MilliSatoshi(rpcRoute.TotalAmtMsat),
+		SourcePubKey:  source,
 		Hops:          hops,
-		TotalFeesMsat: lnwire.MilliSatoshi(route.TotalFeesMsat),
-		TotalAmtMsat:  lnwire.MilliSatoshi(route.TotalAmtMsat),
 	}, nil
 }
 
@@ -3825,3 +6214,54 @@ func (s *lightningClient) SubscribeCustomMessages(ctx context.Context) (
 
 	return msgChan, errChan, nil
 }
+
+// SignMessage signs a message with the identity key of the backing lnd node
+// and returns the zbase32 encoded signature.
+func (s *lightningClient) SignMessage(ctx context.Context, msg []byte) (string,
+	error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	resp, err := s.client.SignMessage(rpcCtx, &lnrpc.SignMessageRequest{
+		Msg: msg,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Signature, nil
+}
+
+// VerifyMessage verifies a zbase32 encoded signature over a message.
+func (s *lightningClient) VerifyMessage(ctx context.Context, msg []byte,
+	signature string) (route.Vertex, bool, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	resp, err := s.client.VerifyMessage(rpcCtx, &lnrpc.VerifyMessageRequest{
+		Msg:       msg,
+		Signature: signature,
+	})
+	if err != nil {
+		return route.Vertex{}, false, err
+	}
+
+	// lnd doesn't return a key if none could be recovered from the
+	// signature, which just means that the signature is invalid.
+	if !resp.Valid && resp.Pubkey == "" {
+		return route.Vertex{}, false, nil
+	}
+
+	pubKey, err := route.NewVertexFromStr(resp.Pubkey)
+	if err != nil {
+		return route.Vertex{}, false, err
+	}
+
+	return pubKey, resp.Valid, nil
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 )
@@ -27,6 +30,13 @@ type mockRPCClient struct {
 	addInvoice func(in *lnrpc.Invoice, opts ...grpc.CallOption) (
 		*lnrpc.AddInvoiceResponse, error)
 	addInvoiceArgs []addInvoiceArg
+
+	listInvoices func(in *lnrpc.ListInvoiceRequest) (
+		*lnrpc.ListInvoiceResponse, error)
+	listInvoicesArgs []*lnrpc.ListInvoiceRequest
+
+	verifyMessage func(in *lnrpc.VerifyMessageRequest) (
+		*lnrpc.VerifyMessageResponse, error)
 }
 
 func (m *mockRPCClient) AddInvoice(ctx context.Context, in *lnrpc.Invoice,
@@ -40,6 +50,22 @@ func (m *mockRPCClient) AddInvoice(ctx context.Context, in *lnrpc.Invoice,
 	return m.addInvoice(in, opts...)
 }
 
+func (m *mockRPCClient) ListInvoices(ctx context.Context,
+	in *lnrpc.ListInvoiceRequest, opts ...grpc.CallOption) (
+	*lnrpc.ListInvoiceResponse, error) {
+
+	m.listInvoicesArgs = append(m.listInvoicesArgs, in)
+
+	return m.listInvoices(in)
+}
+
+func (m *mockRPCClient) VerifyMessage(ctx context.Context,
+	in *lnrpc.VerifyMessageRequest, opts ...grpc.CallOption) (
+	*lnrpc.VerifyMessageResponse, error) {
+
+	return m.verifyMessage(in)
+}
+
 // TestLightningClientAddInvoice ensures that adding an invoice via
 // lightningClient is completed as expected.
 func TestLightningClientAddInvoice(t *testing.T) {
@@ -170,3 +196,188 @@ func TestLightningClientAddInvoice(t *testing.T) {
 		})
 	}
 }
+
+// TestForEachInvoice tests that ForEachInvoice pages through all invoices and
+// passes the request flags on to lnd.
+func TestForEachInvoice(t *testing.T) {
+	// Create a set of open invoices with add indices 1 to 5.
+	var rpcInvoices []*lnrpc.Invoice
+	for i := uint64(1); i <= 5; i++ {
+		var hash lntypes.Hash
+		hash[0] = byte(i)
+
+		rpcInvoices = append(rpcInvoices, &lnrpc.Invoice{
+			RHash:    hash[:],
+			AddIndex: i,
+			State:    lnrpc.Invoice_OPEN,
+		})
+	}
+
+	client := &mockRPCClient{
+		listInvoices: func(in *lnrpc.ListInvoiceRequest) (
+			*lnrpc.ListInvoiceResponse, error) {
+
+			var page []*lnrpc.Invoice
+			for _, invoice := range rpcInvoices {
+				if invoice.AddIndex <= in.IndexOffset {
+					continue
+				}
+				if uint64(len(page)) == in.NumMaxInvoices {
+					break
+				}
+				page = append(page, invoice)
+			}
+
+			resp := &lnrpc.ListInvoiceResponse{
+				Invoices: page,
+			}
+			if len(page) > 0 {
+				resp.FirstIndexOffset = page[0].AddIndex
+				resp.LastIndexOffset = page[len(page)-1].AddIndex
+			}
+
+			return resp, nil
+		},
+	}
+
+	ln := &lightningClient{
+		client: client,
+	}
+
+	var addIndices []uint64
+	err := ForEachInvoice(
+		context.Background(), ln, ListInvoicesRequest{
+			MaxInvoices: 2,
+			PendingOnly: true,
+		}, func(invoice Invoice) error {
+			addIndices = append(addIndices, invoice.AddIndex)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3, 4, 5}, addIndices)
+
+	// We expect three pages to be queried, each continuing where the
+	// previous one ended.
+	require.Len(t, client.listInvoicesArgs, 3)
+	for i, req := range client.listInvoicesArgs {
+		require.True(t, req.PendingOnly)
+		require.Equal(t, uint64(i*2), req.IndexOffset)
+	}
+
+	// An error returned by the callback stops the iteration.
+	errStop := errors.New("stop")
+	err = ForEachInvoice(
+		context.Background(), ln, ListInvoicesRequest{},
+		func(Invoice) error {
+			return errStop
+		},
+	)
+	require.Equal(t, errStop, err)
+}
+
+// TestGetChannelEventUpdate tests that channel points of channel events are
+// parsed regardless of how lnd encodes the funding txid.
+func TestGetChannelEventUpdate(t *testing.T) {
+	txid := chainhash.Hash{1, 2, 3}
+	expected := &wire.OutPoint{
+		Hash:  txid,
+		Index: 1,
+	}
+
+	chanPoints := []*lnrpc.ChannelPoint{{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+			FundingTxidBytes: txid[:],
+		},
+		OutputIndex: 1,
+	}, {
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{
+			FundingTxidStr: txid.String(),
+		},
+		OutputIndex: 1,
+	}}
+
+	ln := &lightningClient{}
+	for _, chanPoint := range chanPoints {
+		rpcUpdate := &lnrpc.ChannelEventUpdate{
+			Type: lnrpc.ChannelEventUpdate_INACTIVE_CHANNEL,
+			Channel: &lnrpc.ChannelEventUpdate_InactiveChannel{
+				InactiveChannel: chanPoint,
+			},
+		}
+
+		update, err := ln.getChannelEventUpdate(rpcUpdate)
+		require.NoError(t, err)
+		require.Equal(t, InactiveChannelUpdate, update.UpdateType)
+		require.Equal(t, expected, update.ChannelPoint)
+		require.Equal(t, &InactiveChannelEvent{
+			ChannelPoint: *expected,
+		}, update.Event)
+	}
+
+	// A missing channel point results in an error rather than a panic.
+	_, err := ln.getChannelEventUpdate(&lnrpc.ChannelEventUpdate{
+		Type: lnrpc.ChannelEventUpdate_ACTIVE_CHANNEL,
+	})
+	require.Error(t, err)
+}
+
+// TestVerifyMessage tests that the key recovered from a signature is returned
+// along with its validity, and that a signature no key can be recovered from
+// is reported as invalid instead of failing.
+func TestVerifyMessage(t *testing.T) {
+	pubKey := route.Vertex{2, 3}
+
+	tests := []struct {
+		name   string
+		resp   *lnrpc.VerifyMessageResponse
+		pubKey route.Vertex
+		valid  bool
+	}{
+		{
+			name: "valid",
+			resp: &lnrpc.VerifyMessageResponse{
+				Valid:  true,
+				Pubkey: pubKey.String(),
+			},
+			pubKey: pubKey,
+			valid:  true,
+		},
+		{
+			name: "unknown signer",
+			resp: &lnrpc.VerifyMessageResponse{
+				Pubkey: pubKey.String(),
+			},
+			pubKey: pubKey,
+		},
+		{
+			name: "unrecoverable signature",
+			resp: &lnrpc.VerifyMessageResponse{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			ln := lightningClient{
+				client: &mockRPCClient{
+					verifyMessage: func(
+						*lnrpc.VerifyMessageRequest) (
+						*lnrpc.VerifyMessageResponse,
+						error) {
+
+						return test.resp, nil
+					},
+				},
+			}
+
+			key, valid, err := ln.VerifyMessage(
+				context.Background(), []byte("msg"), "sig",
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.pubKey, key)
+			require.Equal(t, test.valid, valid)
+		})
+	}
+}
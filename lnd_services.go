@@ -152,6 +152,17 @@ type LndServicesConfig struct {
 	// calls to lnd. If this value is not set, it will default to 30
 	// seconds.
 	RPCTimeout time.Duration
+
+	// HeightHintCache is an optional cache that the chain notifier client
+	// consults and updates with the height hints of its registrations. If
+	// it is not set, the height hints passed in by the caller are always
+	// used as is.
+	HeightHintCache HeightHintCache
+
+	// NotifierMetrics is an optional interface that is informed about the
+	// delivery latency, reconnects and dropped events of all chain
+	// notifications.
+	NotifierMetrics NotifierMetrics
 }
 
 // DialerFunc is a function that is used as grpc.WithContextDialer().
@@ -337,19 +348,20 @@ func NewLndServices(cfg *LndServicesConfig) (*GrpcLndServices, error) {
 	// With the network check passed, we'll now initialize the rest of the
 	// sub-server connections, giving each of them their specific macaroon.
 	notifierClient := newChainNotifierClient(
-		conn, macaroons[chainMacFilename], timeout,
+		conn, macaroons[chainMacFilename], timeout, cfg.HeightHintCache,
+		cfg.NotifierMetrics,
 	)
 	signerClient := newSignerClient(
 		conn, macaroons[signerMacFilename], timeout,
 	)
 	walletKitClient := newWalletKitClient(
-		conn, macaroons[walletKitMacFilename], timeout,
+		conn, macaroons[walletKitMacFilename], timeout, chainParams,
 	)
 	invoicesClient := newInvoicesClient(
 		conn, macaroons[invoiceMacFilename], timeout,
 	)
 	routerClient := newRouterClient(
-		conn, macaroons[routerMacFilename], timeout,
+		conn, macaroons[routerMacFilename], timeout, chainParams,
 	)
 
 	cleanup := func() {
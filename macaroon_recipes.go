@@ -30,15 +30,37 @@ var (
 		"ChannelBackups":         "ExportAllChannelBackups",
 		"ConfirmedWalletBalance": "WalletBalance",
 		"Connect":                "ConnectPeer",
+		"CreateInvoice":          "AddInvoice",
 		"DecodePaymentRequest":   "DecodePayReq",
+		"Disconnect":             "DisconnectPeer",
 		"EstimateFeeToP2WSH":     "EstimateFee",
+		"EstimateRoute":          "EstimateRouteFee",
 		"ListTransactions":       "GetTransactions",
 		"PayInvoice":             "SendPaymentSync",
+		"Probe":                  "SendPaymentV2",
+		"SendKeysend":            "SendPaymentV2",
+		"SetDebugLevel":          "DebugLevel",
 		"UpdateChanPolicy":       "UpdateChannelPolicy",
+		"VerifyChannelBackup":    "VerifyChanBackup",
 		"NetworkInfo":            "GetNetworkInfo",
 		"SubscribeGraph":         "SubscribeChannelGraph",
 		"InterceptHtlcs":         "HtlcInterceptor",
+		"HoldHtlcs":              "HtlcInterceptor",
 		"ImportMissionControl":   "XImportMissionControl",
+		"WalkGraph":              "DescribeGraph",
+
+		"RegisterScriptConfirmationsNtfn": "RegisterConfirmationsNtfn",
+		"RegisterScriptSpendNtfn":         "RegisterSpendNtfn",
+		"SubscribeHtlcEventUpdates":       "SubscribeHtlcEvents",
+		"ResetMissionControlPairs":        "XImportMissionControl",
+	}
+
+	// ignores is a set of method names on the client interfaces that are
+	// not RPC methods and therefore don't require any permissions.
+	ignores = map[string]struct{}{
+		"RawClientWithMacAuth": {},
+		"Stop":                 {},
+		"WaitForFinished":      {},
 	}
 )
 
@@ -71,6 +93,10 @@ func MacaroonRecipe(c LightningClient, packages []string) ([]MacaroonPermission,
 			// differently. Rename according to our rename mapping
 			// table.
 			methodName := ifaceType.Method(i).Name
+			if _, ok := ignores[methodName]; ok {
+				continue
+			}
+
 			rename, ok := renames[methodName]
 			if ok {
 				methodName = rename
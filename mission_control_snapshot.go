@@ -0,0 +1,202 @@
+package lndclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// MissionControlSnapshotVersion is the version of the snapshot format written
+// by ExportMissionControl.
+const MissionControlSnapshotVersion = 1
+
+// MissionControlSnapshot is the on-disk format of lnd's mission control
+// state. It is serialized as json, with nodes encoded as hex and times as unix
+// seconds, so that it stays stable across lndclient and lnd versions.
+type MissionControlSnapshot struct {
+	// Version is the version of the snapshot format.
+	Version uint32 `json:"version"`
+
+	// CreatedAt is the unix time the snapshot was taken at.
+	CreatedAt int64 `json:"created_at"`
+
+	// Pairs holds the payment history of all node pairs.
+	Pairs []MissionControlSnapshotPair `json:"pairs"`
+}
+
+// MissionControlSnapshotPair is the payment history of a single node pair in
+// a mission control snapshot.
+type MissionControlSnapshotPair struct {
+	// NodeFrom is the hex encoded node that payments were forwarded from.
+	NodeFrom string `json:"node_from"`
+
+	// NodeTo is the hex encoded node that payments were forwarded to.
+	NodeTo string `json:"node_to"`
+
+	// FailTime is the unix time of the last failure, or zero.
+	FailTime int64 `json:"fail_time"`
+
+	// FailAmtMsat is the amount of the last failure.
+	FailAmtMsat uint64 `json:"fail_amt_msat"`
+
+	// SuccessTime is the unix time of the last success, or zero.
+	SuccessTime int64 `json:"success_time"`
+
+	// SuccessAmtMsat is the amount of the last success.
+	SuccessAmtMsat uint64 `json:"success_amt_msat"`
+}
+
+// NewMissionControlSnapshot creates a snapshot of the given mission control
+// entries.
+func NewMissionControlSnapshot(
+	entries []MissionControlEntry) *MissionControlSnapshot {
+
+	snapshot := &MissionControlSnapshot{
+		Version:   MissionControlSnapshotVersion,
+		CreatedAt: time.Now().Unix(),
+		Pairs:     make([]MissionControlSnapshotPair, len(entries)),
+	}
+
+	for i, entry := range entries {
+		pair := MissionControlSnapshotPair{
+			NodeFrom:       entry.NodeFrom.String(),
+			NodeTo:         entry.NodeTo.String(),
+			FailAmtMsat:    uint64(entry.FailAmt),
+			SuccessAmtMsat: uint64(entry.SuccessAmt),
+		}
+
+		if !entry.FailTime.IsZero() {
+			pair.FailTime = entry.FailTime.Unix()
+		}
+
+		if !entry.SuccessTime.IsZero() {
+			pair.SuccessTime = entry.SuccessTime.Unix()
+		}
+
+		snapshot.Pairs[i] = pair
+	}
+
+	return snapshot
+}
+
+// Entries returns the mission control entries of the snapshot.
+func (s *MissionControlSnapshot) Entries() ([]MissionControlEntry, error) {
+	if s.Version != MissionControlSnapshotVersion {
+		return nil, fmt.Errorf("unsupported mission control snapshot "+
+			"version %v", s.Version)
+	}
+
+	entries := make([]MissionControlEntry, len(s.Pairs))
+	for i, pair := range s.Pairs {
+		nodeFrom, err := route.NewVertexFromStr(pair.NodeFrom)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeTo, err := route.NewVertexFromStr(pair.NodeTo)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := MissionControlEntry{
+			NodeFrom:   nodeFrom,
+			NodeTo:     nodeTo,
+			FailAmt:    lnwire.MilliSatoshi(pair.FailAmtMsat),
+			SuccessAmt: lnwire.MilliSatoshi(pair.SuccessAmtMsat),
+		}
+
+		if pair.FailTime != 0 {
+			entry.FailTime = time.Unix(pair.FailTime, 0)
+		}
+
+		if pair.SuccessTime != 0 {
+			entry.SuccessTime = time.Unix(pair.SuccessTime, 0)
+		}
+
+		entries[i] = entry
+	}
+
+	return entries, nil
+}
+
+// ExportMissionControl writes a snapshot of lnd's mission control state to
+// the writer.
+func ExportMissionControl(ctx context.Context, router RouterClient,
+	w io.Writer) error {
+
+	entries, err := router.QueryMissionControl(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(NewMissionControlSnapshot(entries))
+}
+
+// ImportMissionControl reads a snapshot from the reader and imports it into
+// lnd's mission control. If force is set, the results of the snapshot
+// override newer results that lnd has for the same pairs.
+func ImportMissionControl(ctx context.Context, router RouterClient,
+	r io.Reader, force bool) error {
+
+	var snapshot MissionControlSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	entries, err := snapshot.Entries()
+	if err != nil {
+		return err
+	}
+
+	return router.ImportMissionControl(ctx, entries, force)
+}
+
+// ExportMissionControlFile writes a snapshot of lnd's mission control state
+// to the file at the given path. The file is replaced atomically, so that an
+// existing snapshot isn't lost if the export fails.
+func ExportMissionControlFile(ctx context.Context, router RouterClient,
+	path string) error {
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), ".mc-snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	err = ExportMissionControl(ctx, router, tempFile)
+	if err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
+
+// ImportMissionControlFile imports the snapshot in the file at the given path
+// into lnd's mission control.
+func ImportMissionControlFile(ctx context.Context, router RouterClient,
+	path string, force bool) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ImportMissionControl(ctx, router, file, force)
+}
@@ -0,0 +1,45 @@
+package lndclient
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMissionControlSnapshot tests that mission control entries survive a
+// round trip through the snapshot format and that unknown versions are
+// rejected.
+func TestMissionControlSnapshot(t *testing.T) {
+	entries := []MissionControlEntry{
+		{
+			NodeFrom: route.Vertex{1},
+			NodeTo:   route.Vertex{2},
+			FailTime: time.Unix(1000, 0),
+			FailAmt:  2000,
+		},
+		{
+			NodeFrom:    route.Vertex{2},
+			NodeTo:      route.Vertex{3},
+			SuccessTime: time.Unix(3000, 0),
+			SuccessAmt:  4000,
+		},
+	}
+
+	snapshot := NewMissionControlSnapshot(entries)
+	serialized, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+
+	var decoded MissionControlSnapshot
+	require.NoError(t, json.Unmarshal(serialized, &decoded))
+
+	decodedEntries, err := decoded.Entries()
+	require.NoError(t, err)
+	require.Equal(t, entries, decodedEntries)
+
+	decoded.Version++
+	_, err = decoded.Entries()
+	require.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package lndclient
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// maxTrackedBlocks is the number of most recent blocks we remember the
+	// arrival time of for latency metrics.
+	maxTrackedBlocks = 100
+)
+
+// NotificationType is the type of a chain notification.
+type NotificationType uint8
+
+const (
+	// NotificationBlockEpoch is the type of block epoch notifications.
+	NotificationBlockEpoch NotificationType = iota
+
+	// NotificationConfirmation is the type of confirmation notifications.
+	NotificationConfirmation
+
+	// NotificationSpend is the type of spend notifications.
+	NotificationSpend
+)
+
+// String returns a human readable representation of the notification type.
+func (n NotificationType) String() string {
+	switch n {
+	case NotificationBlockEpoch:
+		return "block_epoch"
+
+	case NotificationConfirmation:
+		return "confirmation"
+
+	case NotificationSpend:
+		return "spend"
+
+	default:
+		return "unknown"
+	}
+}
+
+// NotifierMetrics is an optional interface that is informed about the health
+// of the notifications of the chain notifier client. All methods must be safe
+// for concurrent use and should return quickly, as they are called from the
+// goroutines delivering the notifications.
+type NotifierMetrics interface {
+	// ObserveDeliveryLatency records the time between the arrival of the
+	// block an event happened in and the delivery of the event to the
+	// caller. If no block epoch notification is registered, the block
+	// arrival time is unknown and the time the event was received from
+	// lnd is used instead.
+	ObserveDeliveryLatency(ntfnType NotificationType, latency time.Duration)
+
+	// IncReconnects records that a notification stream was re-registered
+	// after it failed.
+	IncReconnects(ntfnType NotificationType)
+
+	// IncDroppedEvents records that an event was received from lnd but
+	// couldn't be delivered because the registration was canceled.
+	IncDroppedEvents(ntfnType NotificationType)
+}
+
+// noopNotifierMetrics is a NotifierMetrics implementation that discards all
+// metrics. It is used if the caller doesn't configure one.
+type noopNotifierMetrics struct{}
+
+// A compile-time constraint to ensure noopNotifierMetrics satisfies the
+// NotifierMetrics interface.
+var _ NotifierMetrics = (*noopNotifierMetrics)(nil)
+
+// ObserveDeliveryLatency discards the observed latency.
+func (noopNotifierMetrics) ObserveDeliveryLatency(NotificationType,
+	time.Duration) {
+}
+
+// IncReconnects discards the reconnect.
+func (noopNotifierMetrics) IncReconnects(NotificationType) {}
+
+// IncDroppedEvents discards the dropped event.
+func (noopNotifierMetrics) IncDroppedEvents(NotificationType) {}
+
+// blockArrivals keeps track of the time the most recent blocks were received
+// from lnd, so the latency of confirmation and spend events can be measured
+// from the arrival of the block they happened in.
+type blockArrivals struct {
+	arrivals map[int32]time.Time
+
+	sync.Mutex
+}
+
+// newBlockArrivals creates an empty set of block arrival times.
+func newBlockArrivals() *blockArrivals {
+	return &blockArrivals{
+		arrivals: make(map[int32]time.Time),
+	}
+}
+
+// add records the arrival time of the block with the given height, if we
+// haven't seen it yet.
+func (b *blockArrivals) add(height int32, arrival time.Time) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.arrivals[height]; ok {
+		return
+	}
+	b.arrivals[height] = arrival
+
+	// Forget about blocks that are too old to be of interest.
+	for h := range b.arrivals {
+		if h <= height-maxTrackedBlocks {
+			delete(b.arrivals, h)
+		}
+	}
+}
+
+// get returns the arrival time of the block with the given height, or the
+// given fallback time if it is unknown.
+func (b *blockArrivals) get(height int32, fallback time.Time) time.Time {
+	b.Lock()
+	defer b.Unlock()
+
+	arrival, ok := b.arrivals[height]
+	if !ok {
+		return fallback
+	}
+
+	return arrival
+}
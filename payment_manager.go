@@ -0,0 +1,546 @@
+package lndclient
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// paymentBucket is the name of the bucket that stores the hashes of the
+// payments whose result wasn't delivered yet.
+var paymentBucket = []byte("payments")
+
+// PaymentStore persists the hashes of the payments a PaymentManager initiated
+// until their result was delivered.
+type PaymentStore interface {
+	// AddPayment stores the hash of a payment that is about to be sent.
+	AddPayment(hash lntypes.Hash) error
+
+	// RemovePayment removes the hash of a payment whose result was
+	// delivered.
+	RemovePayment(hash lntypes.Hash) error
+
+	// ListPayments returns the hashes of all stored payments.
+	ListPayments() ([]lntypes.Hash, error)
+}
+
+// memPaymentStore is a PaymentStore that keeps all payments in memory.
+type memPaymentStore struct {
+	payments map[lntypes.Hash]struct{}
+	mu       sync.Mutex
+}
+
+// A compile-time constraint to ensure memPaymentStore satisfies the
+// PaymentStore interface.
+var _ PaymentStore = (*memPaymentStore)(nil)
+
+// NewMemPaymentStore returns a PaymentStore that keeps all payments in memory.
+// The payments are lost when the process exits.
+func NewMemPaymentStore() PaymentStore {
+	return &memPaymentStore{
+		payments: make(map[lntypes.Hash]struct{}),
+	}
+}
+
+// AddPayment stores the hash of a payment that is about to be sent.
+func (m *memPaymentStore) AddPayment(hash lntypes.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.payments[hash] = struct{}{}
+	return nil
+}
+
+// RemovePayment removes the hash of a payment whose result was delivered.
+func (m *memPaymentStore) RemovePayment(hash lntypes.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.payments, hash)
+	return nil
+}
+
+// ListPayments returns the hashes of all stored payments.
+func (m *memPaymentStore) ListPayments() ([]lntypes.Hash, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hashes := make([]lntypes.Hash, 0, len(m.payments))
+	for hash := range m.payments {
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, nil
+}
+
+// boltPaymentStore is a PaymentStore that persists all payments in a kvdb
+// backend.
+type boltPaymentStore struct {
+	db kvdb.Backend
+}
+
+// A compile-time constraint to ensure boltPaymentStore satisfies the
+// PaymentStore interface.
+var _ PaymentStore = (*boltPaymentStore)(nil)
+
+// NewBoltPaymentStore returns a PaymentStore that persists all payments in the
+// given database, so that their results are delivered after a restart. The
+// caller remains responsible for closing the database.
+func NewBoltPaymentStore(db kvdb.Backend) (PaymentStore, error) {
+	err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		_, err := tx.CreateTopLevelBucket(paymentBucket)
+		return err
+	}, func() {})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltPaymentStore{
+		db: db,
+	}, nil
+}
+
+// AddPayment stores the hash of a payment that is about to be sent.
+func (b *boltPaymentStore) AddPayment(hash lntypes.Hash) error {
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(paymentBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.Put(hash[:], []byte{})
+	}, func() {})
+}
+
+// RemovePayment removes the hash of a payment whose result was delivered.
+func (b *boltPaymentStore) RemovePayment(hash lntypes.Hash) error {
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(paymentBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.Delete(hash[:])
+	}, func() {})
+}
+
+// ListPayments returns the hashes of all stored payments.
+func (b *boltPaymentStore) ListPayments() ([]lntypes.Hash, error) {
+	var hashes []lntypes.Hash
+	err := kvdb.View(b.db, func(tx kvdb.RTx) error {
+		bucket := tx.ReadBucket(paymentBucket)
+		if bucket == nil {
+			return errBucketNotFound
+		}
+
+		return bucket.ForEach(func(key, _ []byte) error {
+			hash, err := lntypes.MakeHash(key)
+			if err != nil {
+				return err
+			}
+
+			hashes = append(hashes, hash)
+			return nil
+		})
+	}, func() {
+		hashes = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// PaymentFailedError is delivered to the failure callback of a PaymentManager
+// if a payment reached the failed state.
+type PaymentFailedError struct {
+	// Status is the final status of the payment.
+	Status *PaymentStatus
+}
+
+// Error returns a string representation of the error.
+func (e *PaymentFailedError) Error() string {
+	return fmt.Sprintf("payment %v failed: %v", e.Status.Hash,
+		e.Status.FailureReason)
+}
+
+// PaymentManagerConfig holds the configuration of a PaymentManager.
+type PaymentManagerConfig struct {
+	// Router is the client used to send and track payments.
+	Router RouterClient
+
+	// ChainParams are the parameters of the chain lnd runs on, needed
+	// to decode invoices.
+	ChainParams *chaincfg.Params
+
+	// Store persists the payments that were initiated but whose result
+	// wasn't delivered yet.
+	Store PaymentStore
+
+	// OnSuccess is called once for every payment that succeeded.
+	OnSuccess func(*PaymentStatus)
+
+	// OnFailure is called once for every payment that failed. The error
+	// is a *PaymentFailedError if lnd reports the payment as failed, or
+	// channeldb.ErrPaymentNotInitiated if lnd doesn't know the payment.
+	OnFailure func(lntypes.Hash, error)
+
+	// InitialBackoff is the time to wait before tracking a payment again
+	// after its stream failed, for example because lnd restarted. If zero,
+	// a default is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper bound of the time to wait between attempts
+	// to track a payment. If zero, a default is used.
+	MaxBackoff time.Duration
+}
+
+// PaymentManager sends payments and tracks them until they reached a final
+// state. The hash of every payment is persisted before it is sent, and the
+// manager re-attaches to all stored payments when it is started again. The
+// result of each payment is delivered exactly once, unless the process exits
+// after a callback returned but before the payment was removed from the store,
+// in which case it is delivered again on the next start.
+type PaymentManager struct {
+	cfg *PaymentManagerConfig
+
+	// tracked holds the payments that are currently tracked, so that a
+	// payment is never tracked twice.
+	tracked map[lntypes.Hash]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPaymentManager creates a new payment manager. Start must be called before
+// payments can be sent.
+func NewPaymentManager(cfg *PaymentManagerConfig) *PaymentManager {
+	return &PaymentManager{
+		cfg:     cfg,
+		tracked: make(map[lntypes.Hash]struct{}),
+	}
+}
+
+// Start re-attaches to all payments in the store whose result wasn't
+// delivered yet. The payments are tracked until Stop is called or the given
+// context is canceled.
+func (m *PaymentManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.cancel != nil {
+		m.mu.Unlock()
+		return errors.New("payment manager already started")
+	}
+
+	hashes, err := m.cfg.Store.ListPayments()
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.mu.Unlock()
+
+	for _, hash := range hashes {
+		if err := m.reserve(hash); err != nil {
+			return err
+		}
+
+		log.Debugf("Resuming tracking of payment %v", hash)
+		m.track(hash, nil, nil)
+	}
+
+	return nil
+}
+
+// Stop stops tracking all payments and waits for the goroutines of the
+// manager to exit. Payments whose result wasn't delivered yet remain in the
+// store.
+func (m *PaymentManager) Stop() {
+	m.stopOnce.Do(func() {
+		m.mu.Lock()
+		if m.cancel != nil {
+			m.cancel()
+		}
+		m.mu.Unlock()
+
+		m.wg.Wait()
+	})
+}
+
+// SendPayment persists the payment's hash, sends the payment and tracks it
+// until it reached a final state. Its result is delivered to the callbacks
+// of the manager. Either an invoice or a payment hash must be set. For
+// keysend payments, the preimage is generated before the payment is sent.
+// The payment isn't bound to the given context, canceling it after
+// SendPayment returned doesn't stop the payment from being tracked.
+func (m *PaymentManager) SendPayment(ctx context.Context,
+	request SendPaymentRequest) (lntypes.Hash, error) {
+
+	if err := ctx.Err(); err != nil {
+		return lntypes.Hash{}, err
+	}
+
+	hash, err := m.paymentHash(&request)
+	if err != nil {
+		return lntypes.Hash{}, err
+	}
+
+	if err := m.reserve(hash); err != nil {
+		return lntypes.Hash{}, err
+	}
+
+	// We persist the hash before sending, so that we can't lose track of
+	// the payment if we crash right after it was sent.
+	if err := m.cfg.Store.AddPayment(hash); err != nil {
+		m.release(hash)
+		return lntypes.Hash{}, err
+	}
+
+	// The payment stream is opened on our own context, as it must outlive
+	// this call. We don't hold the mutex for the call, so that a slow lnd
+	// doesn't block other payments or Stop.
+	statusChan, errChan, err := m.cfg.Router.SendPayment(m.ctx, request)
+	if err != nil {
+		if err := m.cfg.Store.RemovePayment(hash); err != nil {
+			log.Errorf("Unable to remove payment %v: %v", hash, err)
+		}
+		m.release(hash)
+
+		return lntypes.Hash{}, err
+	}
+
+	m.track(hash, statusChan, errChan)
+
+	return hash, nil
+}
+
+// reserve marks a payment as tracked and adds its goroutine to the wait group,
+// so that a payment is never tracked twice and Stop waits for it. It fails if
+// the manager isn't running.
+func (m *PaymentManager) reserve(hash lntypes.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel == nil {
+		return errors.New("payment manager not started")
+	}
+
+	if m.ctx.Err() != nil {
+		return errors.New("payment manager stopped")
+	}
+
+	if _, ok := m.tracked[hash]; ok {
+		return fmt.Errorf("payment %v already tracked", hash)
+	}
+
+	m.tracked[hash] = struct{}{}
+	m.wg.Add(1)
+
+	return nil
+}
+
+// release undoes the reservation of a payment once it is no longer tracked.
+func (m *PaymentManager) release(hash lntypes.Hash) {
+	m.mu.Lock()
+	delete(m.tracked, hash)
+	m.mu.Unlock()
+
+	m.wg.Done()
+}
+
+// paymentHash returns the hash of the payment request. For keysend payments,
+// the preimage is generated and added to the request, so that the hash is
+// known before the payment is sent.
+func (m *PaymentManager) paymentHash(request *SendPaymentRequest) (
+	lntypes.Hash, error) {
+
+	switch {
+	case request.Invoice != "":
+		invoice, err := zpay32.Decode(
+			request.Invoice, m.cfg.ChainParams,
+		)
+		if err != nil {
+			return lntypes.Hash{}, err
+		}
+
+		if invoice.PaymentHash == nil {
+			return lntypes.Hash{}, errors.New("invoice has no " +
+				"payment hash")
+		}
+
+		return *invoice.PaymentHash, nil
+
+	case request.KeySend:
+		if request.PaymentHash != nil {
+			return lntypes.Hash{}, errors.New("keysend payment " +
+				"must not include a preset payment hash")
+		}
+
+		var preimage lntypes.Preimage
+		if _, err := rand.Read(preimage[:]); err != nil {
+			return lntypes.Hash{}, err
+		}
+
+		records := make(map[uint64][]byte, len(request.CustomRecords)+1)
+		for key, value := range request.CustomRecords {
+			records[key] = value
+		}
+		records[record.KeySendType] = preimage[:]
+
+		hash := preimage.Hash()
+		request.CustomRecords = records
+		request.PaymentHash = &hash
+		request.KeySend = false
+
+		return hash, nil
+
+	case request.PaymentHash != nil:
+		return *request.PaymentHash, nil
+
+	default:
+		return lntypes.Hash{}, errors.New("invoice or payment hash " +
+			"required")
+	}
+}
+
+// track starts tracking a reserved payment in a goroutine. If no update
+// streams are given, the payment is tracked with TrackPayment.
+func (m *PaymentManager) track(hash lntypes.Hash,
+	statusChan chan PaymentStatus, errChan chan error) {
+
+	go func() {
+		defer m.release(hash)
+
+		m.trackPayment(m.ctx, hash, statusChan, errChan)
+	}()
+}
+
+// trackPayment follows the updates of a payment until it reached a final
+// state and delivers the result. If the update stream fails, the payment is
+// tracked again with an exponential backoff until the context is canceled.
+func (m *PaymentManager) trackPayment(ctx context.Context, hash lntypes.Hash,
+	statusChan chan PaymentStatus, errChan chan error) {
+
+	b := newBackoff(m.cfg.InitialBackoff, m.cfg.MaxBackoff)
+	for {
+		if statusChan == nil {
+			var err error
+			statusChan, errChan, err = m.cfg.Router.TrackPayment(
+				ctx, hash,
+			)
+			if err != nil {
+				log.Warnf("Unable to track payment %v, "+
+					"retrying in %v: %v", hash, b.next,
+					err)
+
+				if !b.wait(ctx) {
+					return
+				}
+				continue
+			}
+		}
+
+		status, err := m.waitForResult(ctx, statusChan, errChan)
+		switch {
+		case ctx.Err() != nil:
+			return
+
+		// lnd doesn't know the payment, so it was never sent and won't
+		// ever reach a final state.
+		case errors.Is(err, channeldb.ErrPaymentNotInitiated):
+			m.deliver(hash, nil, err)
+			return
+
+		case err != nil:
+			log.Warnf("Tracking payment %v failed, retrying in "+
+				"%v: %v", hash, b.next, err)
+
+			statusChan, errChan = nil, nil
+			if !b.wait(ctx) {
+				return
+			}
+			continue
+		}
+
+		m.deliver(hash, status, nil)
+		return
+	}
+}
+
+// waitForResult reads payment updates until the payment reached a final state.
+func (m *PaymentManager) waitForResult(ctx context.Context,
+	statusChan chan PaymentStatus, errChan chan error) (*PaymentStatus,
+	error) {
+
+	for {
+		select {
+		case status, ok := <-statusChan:
+			if !ok {
+				return nil, errors.New("payment stream " +
+					"closed before final state")
+			}
+
+			if status.State == lnrpc.Payment_SUCCEEDED ||
+				status.State == lnrpc.Payment_FAILED {
+
+				return &status, nil
+			}
+
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+
+			return nil, err
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// deliver calls the callback for the result of a payment and removes the
+// payment from the store afterwards.
+func (m *PaymentManager) deliver(hash lntypes.Hash, status *PaymentStatus,
+	err error) {
+
+	switch {
+	case err != nil:
+		if m.cfg.OnFailure != nil {
+			m.cfg.OnFailure(hash, err)
+		}
+
+	case status.State == lnrpc.Payment_FAILED:
+		if m.cfg.OnFailure != nil {
+			m.cfg.OnFailure(hash, &PaymentFailedError{
+				Status: status,
+			})
+		}
+
+	default:
+		if m.cfg.OnSuccess != nil {
+			m.cfg.OnSuccess(status)
+		}
+	}
+
+	if err := m.cfg.Store.RemovePayment(hash); err != nil {
+		log.Errorf("Unable to remove payment %v: %v", hash, err)
+	}
+}
@@ -0,0 +1,283 @@
+package lndclient
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+)
+
+// testTimeout is the time tests wait for an expected event.
+const testTimeout = 5 * time.Second
+
+// paymentStream is a payment update stream opened on the mock router.
+type paymentStream struct {
+	ctx        context.Context
+	hash       lntypes.Hash
+	statusChan chan PaymentStatus
+	errChan    chan error
+}
+
+// mockPaymentRouter is a router that hands all payment streams to the test.
+type mockPaymentRouter struct {
+	RouterClient
+
+	streams chan *paymentStream
+}
+
+func newMockPaymentRouter() *mockPaymentRouter {
+	return &mockPaymentRouter{
+		streams: make(chan *paymentStream, 10),
+	}
+}
+
+func (r *mockPaymentRouter) openStream(ctx context.Context,
+	hash lntypes.Hash) (chan PaymentStatus, chan error, error) {
+
+	stream := &paymentStream{
+		ctx:        ctx,
+		hash:       hash,
+		statusChan: make(chan PaymentStatus, 1),
+		errChan:    make(chan error, 1),
+	}
+	r.streams <- stream
+
+	return stream.statusChan, stream.errChan, nil
+}
+
+func (r *mockPaymentRouter) SendPayment(ctx context.Context,
+	request SendPaymentRequest) (chan PaymentStatus, chan error, error) {
+
+	return r.openStream(ctx, *request.PaymentHash)
+}
+
+func (r *mockPaymentRouter) TrackPayment(ctx context.Context,
+	hash lntypes.Hash) (chan PaymentStatus, chan error, error) {
+
+	return r.openStream(ctx, hash)
+}
+
+func (r *mockPaymentRouter) nextStream(t *testing.T) *paymentStream {
+	select {
+	case stream := <-r.streams:
+		return stream
+
+	case <-time.After(testTimeout):
+		t.Fatal("no payment stream opened")
+		return nil
+	}
+}
+
+// paymentManagerHarness holds a payment manager with a mock router.
+type paymentManagerHarness struct {
+	router    *mockPaymentRouter
+	store     PaymentStore
+	manager   *PaymentManager
+	successes chan *PaymentStatus
+	failures  chan error
+}
+
+func newPaymentManagerHarness(t *testing.T,
+	store PaymentStore) *paymentManagerHarness {
+
+	h := &paymentManagerHarness{
+		router:    newMockPaymentRouter(),
+		store:     store,
+		successes: make(chan *PaymentStatus, 1),
+		failures:  make(chan error, 1),
+	}
+
+	h.manager = NewPaymentManager(&PaymentManagerConfig{
+		Router: h.router,
+		Store:  store,
+		OnSuccess: func(status *PaymentStatus) {
+			h.successes <- status
+		},
+		OnFailure: func(_ lntypes.Hash, err error) {
+			h.failures <- err
+		},
+	})
+	require.NoError(t, h.manager.Start(context.Background()))
+
+	return h
+}
+
+func (h *paymentManagerHarness) waitForSuccess(t *testing.T,
+	hash lntypes.Hash) {
+
+	select {
+	case status := <-h.successes:
+		require.Equal(t, hash, status.Hash)
+
+	case err := <-h.failures:
+		t.Fatalf("unexpected payment failure: %v", err)
+
+	case <-time.After(testTimeout):
+		t.Fatal("payment result not delivered")
+	}
+}
+
+// TestPaymentManagerDelivery tests that the result of a payment is delivered
+// and the payment removed from the store afterwards.
+func TestPaymentManagerDelivery(t *testing.T) {
+	h := newPaymentManagerHarness(t, NewMemPaymentStore())
+
+	hash := lntypes.Hash{1}
+	sentHash, err := h.manager.SendPayment(
+		context.Background(), SendPaymentRequest{PaymentHash: &hash},
+	)
+	require.NoError(t, err)
+	require.Equal(t, hash, sentHash)
+
+	// The payment is stored while it is in flight.
+	hashes, err := h.store.ListPayments()
+	require.NoError(t, err)
+	require.Equal(t, []lntypes.Hash{hash}, hashes)
+
+	// The same payment can't be sent twice.
+	_, err = h.manager.SendPayment(
+		context.Background(), SendPaymentRequest{PaymentHash: &hash},
+	)
+	require.Error(t, err)
+
+	stream := h.router.nextStream(t)
+	stream.statusChan <- PaymentStatus{
+		Hash:  hash,
+		State: lnrpc.Payment_IN_FLIGHT,
+	}
+	stream.statusChan <- PaymentStatus{
+		Hash:  hash,
+		State: lnrpc.Payment_SUCCEEDED,
+	}
+	h.waitForSuccess(t, hash)
+
+	h.manager.Stop()
+
+	hashes, err = h.store.ListPayments()
+	require.NoError(t, err)
+	require.Empty(t, hashes)
+}
+
+// TestPaymentManagerCallerCancel tests that canceling the context of the
+// caller doesn't abort tracking of a payment that was sent.
+func TestPaymentManagerCallerCancel(t *testing.T) {
+	h := newPaymentManagerHarness(t, NewMemPaymentStore())
+	defer h.manager.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hash := lntypes.Hash{2}
+	_, err := h.manager.SendPayment(
+		ctx, SendPaymentRequest{PaymentHash: &hash},
+	)
+	require.NoError(t, err)
+
+	stream := h.router.nextStream(t)
+	cancel()
+
+	require.NoError(t, stream.ctx.Err())
+
+	stream.statusChan <- PaymentStatus{
+		Hash:  hash,
+		State: lnrpc.Payment_SUCCEEDED,
+	}
+	h.waitForSuccess(t, hash)
+
+	// A caller whose context is already canceled can't send a payment.
+	hash2 := lntypes.Hash{3}
+	_, err = h.manager.SendPayment(
+		ctx, SendPaymentRequest{PaymentHash: &hash2},
+	)
+	require.Equal(t, context.Canceled, err)
+}
+
+// newTestPaymentStore creates a payment store backed by a bolt database in a
+// temporary directory.
+func newTestPaymentStore(t *testing.T) (PaymentStore, func()) {
+	tempDirPath, err := ioutil.TempDir("", ".testPayments")
+	require.NoError(t, err)
+
+	db, err := kvdb.GetBoltBackend(&kvdb.BoltBackendConfig{
+		DBPath:     tempDirPath,
+		DBFileName: "payments.db",
+		DBTimeout:  defaultDBTimeout,
+	})
+	require.NoError(t, err)
+
+	store, err := NewBoltPaymentStore(db)
+	require.NoError(t, err)
+
+	return store, func() {
+		require.NoError(t, db.Close())
+		require.NoError(t, os.RemoveAll(tempDirPath))
+	}
+}
+
+// TestPaymentManagerStop tests that stopping the manager closes all payment
+// streams and keeps the payments in the store, so that they are tracked again
+// after a restart.
+func TestPaymentManagerStop(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testPaymentManagerStop(t, NewMemPaymentStore())
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		store, cleanup := newTestPaymentStore(t)
+		defer cleanup()
+
+		testPaymentManagerStop(t, store)
+	})
+}
+
+func testPaymentManagerStop(t *testing.T, store PaymentStore) {
+	h := newPaymentManagerHarness(t, store)
+
+	hash := lntypes.Hash{4}
+	_, err := h.manager.SendPayment(
+		context.Background(), SendPaymentRequest{PaymentHash: &hash},
+	)
+	require.NoError(t, err)
+
+	stream := h.router.nextStream(t)
+	h.manager.Stop()
+
+	require.Error(t, stream.ctx.Err())
+	require.Empty(t, h.successes)
+	require.Empty(t, h.failures)
+
+	hashes, err := store.ListPayments()
+	require.NoError(t, err)
+	require.Equal(t, []lntypes.Hash{hash}, hashes)
+
+	// No payments can be sent once the manager is stopped.
+	hash2 := lntypes.Hash{5}
+	_, err = h.manager.SendPayment(
+		context.Background(), SendPaymentRequest{PaymentHash: &hash2},
+	)
+	require.Error(t, err)
+
+	// A new manager re-attaches to the stored payment.
+	h = newPaymentManagerHarness(t, store)
+
+	stream = h.router.nextStream(t)
+	require.Equal(t, hash, stream.hash)
+
+	stream.statusChan <- PaymentStatus{
+		Hash:  hash,
+		State: lnrpc.Payment_SUCCEEDED,
+	}
+	h.waitForSuccess(t, hash)
+	h.manager.Stop()
+
+	// The payment is removed from the store once its result was
+	// delivered.
+	hashes, err = store.ListPayments()
+	require.NoError(t, err)
+	require.Empty(t, hashes)
+}
@@ -6,9 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnrpc"
@@ -44,11 +47,54 @@ type RouterClient interface {
 	EstimateRouteFee(ctx context.Context, dest route.Vertex,
 		amt btcutil.Amount) (lnwire.MilliSatoshi, error)
 
+	// EstimateRoute uses the channel router's internal state to estimate
+	// the routing fee and the time lock delay of a payment of the given
+	// amount to the destination node.
+	EstimateRoute(ctx context.Context, dest route.Vertex,
+		amt btcutil.Amount) (*RouteFeeEstimate, error)
+
+	// BuildRoute builds a fully specified route through the given hops,
+	// using the current policies of the channels in lnd's graph. If amt
+	// is zero, the minimum amount that can be carried by the route is
+	// used. If finalCltvDelta is zero, a default of 40 blocks is used.
+	// The outgoing channel and payment address are optional.
+	BuildRoute(ctx context.Context, amt lnwire.MilliSatoshi,
+		finalCltvDelta uint16, outgoingChan *uint64,
+		hops []route.Vertex, payAddr *[32]byte) (*route.Route, error)
+
+	// SendKeysend sends a spontaneous payment to the destination and
+	// blocks until it reached a final state. The preimage is generated
+	// and sent to the destination in the keysend record. A failed payment
+	// is not an error, its final status holds the failure reason. Unless
+	// it is set with WithKeysendMaxFee, the fee limit is 5% of the
+	// amount, but at least 10 sat.
+	SendKeysend(ctx context.Context, dest route.Vertex, amt btcutil.Amount,
+		opts ...KeysendOption) (*PaymentStatus, error)
+
+	// Probe sends a payment with a random hash to the destination to find
+	// out whether the amount can be routed to it and at which fee. The
+	// payment can't settle, so no funds are spent. Unless the fee is
+	// limited with WithProbeMaxFee, routes of any fee are probed.
+	Probe(ctx context.Context, dest route.Vertex, amt btcutil.Amount,
+		opts ...ProbeOption) (*ProbeResult, error)
+
+	// SendToRoute attempts to pay the given payment hash over the route
+	// provided. The call blocks until the htlc is resolved and returns
+	// the resulting attempt, which holds the preimage if the htlc settled
+	// or the failure and its source if it failed.
+	SendToRoute(ctx context.Context, hash lntypes.Hash,
+		payRoute *route.Route) (*HtlcAttempt, error)
+
 	// SubscribeHtlcEvents subscribes to a stream of htlc events from the
 	// router.
 	SubscribeHtlcEvents(ctx context.Context) (<-chan *routerrpc.HtlcEvent,
 		<-chan error, error)
 
+	// SubscribeHtlcEventUpdates subscribes to a stream of typed htlc
+	// events from the router.
+	SubscribeHtlcEventUpdates(ctx context.Context) (
+		<-chan *HtlcEventUpdate, <-chan error, error)
+
 	// InterceptHtlcs intercepts htlcs, using the handling function provided
 	// to respond to htlcs. This function blocks, and can be terminated by
 	// canceling the context provided. The handler provided should exit on
@@ -57,6 +103,13 @@ type RouterClient interface {
 	InterceptHtlcs(ctx context.Context,
 		handler HtlcInterceptHandler) error
 
+	// HoldHtlcs intercepts htlcs and hands each of them to the handler as
+	// a handle that can be resolved later on. Htlcs that aren't resolved
+	// within the hold timeout, or that are still held when the context is
+	// canceled, are failed back.
+	HoldHtlcs(ctx context.Context, handler HtlcHoldHandler,
+		holdTimeout time.Duration) error
+
 	// QueryMissionControl will query Mission Control state from lnd.
 	QueryMissionControl(ctx context.Context) ([]MissionControlEntry, error)
 
@@ -66,10 +119,42 @@ type RouterClient interface {
 
 	// ResetMissionControl resets the Mission Control state of lnd.
 	ResetMissionControl(ctx context.Context) error
+
+	// ResetMissionControlPairs clears the results that mission control
+	// has for the given node pairs, without touching the history of
+	// other pairs.
+	ResetMissionControlPairs(ctx context.Context, pairs []NodePair) error
+
+	// QueryProbability returns mission control's estimate of the
+	// probability of successfully forwarding the given amount from one
+	// node to the other, along with the history of the pair it is based
+	// on.
+	QueryProbability(ctx context.Context, from, to route.Vertex,
+		amt lnwire.MilliSatoshi) (float64, *MissionControlEntry, error)
+
+	// GetMissionControlConfig returns the configuration of lnd's mission
+	// control.
+	GetMissionControlConfig(ctx context.Context) (*MissionControlConfig,
+		error)
+
+	// SetMissionControlConfig updates the configuration of lnd's mission
+	// control. The new values are applied immediately, without
+	// restarting lnd.
+	SetMissionControlConfig(ctx context.Context,
+		cfg *MissionControlConfig) error
+
+	// UpdateChanStatus sets the status of a channel that is advertised to
+	// the network. A channel can be disabled manually, for example for
+	// maintenance, and re-enabled later on.
+	UpdateChanStatus(ctx context.Context, chanPoint *wire.OutPoint,
+		action ChanStatusAction) error
 }
 
 // PaymentStatus describe the state of a payment.
 type PaymentStatus struct {
+	// Hash is the hash of the payment.
+	Hash lntypes.Hash
+
 	State lnrpc.Payment_PaymentStatus
 
 	// FailureReason is the reason why the payment failed. Only set when
@@ -82,7 +167,34 @@ type PaymentStatus struct {
 	InFlightAmt   lnwire.MilliSatoshi
 	InFlightHtlcs int
 
+	// InFlightFee is the fee of the htlcs that are in flight.
+	InFlightFee lnwire.MilliSatoshi
+
+	// SettledAmt is the amount that was delivered to the recipient by the
+	// settled htlcs, excluding fees.
+	SettledAmt lnwire.MilliSatoshi
+
+	// SettledFee is the fee paid by the settled htlcs so far. Once the
+	// payment succeeded, it equals Fee.
+	SettledFee lnwire.MilliSatoshi
+
+	// SettledHtlcs is the number of htlcs that settled.
+	SettledHtlcs int
+
+	// FailedHtlcs is the number of htlcs that failed.
+	FailedHtlcs int
+
+	// Htlcs holds all htlc attempts of the payment, each with its own
+	// state. A payment that is split with MPP or AMP has one attempt per
+	// shard, plus those that failed and were retried. The total number of
+	// attempts is len(Htlcs).
 	Htlcs []*HtlcAttempt
+
+	// Routes holds the routes of the settled htlcs of a succeeded payment.
+	// A payment that was split into multiple parts has one route per
+	// part. The source of the routes is not set, as they all start at our
+	// node.
+	Routes []*route.Route
 }
 
 func (p PaymentStatus) String() string {
@@ -124,7 +236,13 @@ func (h *HtlcAttempt) AmountInFlight() lnwire.MilliSatoshi {
 		return 0
 	}
 
-	if h.Route == nil || h.Route.Hops == nil {
+	return h.Amount()
+}
+
+// Amount returns the amount of this htlc attempt that is delivered to the
+// final recipient, regardless of the attempt's status.
+func (h *HtlcAttempt) Amount() lnwire.MilliSatoshi {
+	if h.Route == nil || len(h.Route.Hops) == 0 {
 		return 0
 	}
 
@@ -132,6 +250,15 @@ func (h *HtlcAttempt) AmountInFlight() lnwire.MilliSatoshi {
 	return lnwire.MilliSatoshi(lastHop.AmtToForwardMsat)
 }
 
+// Fee returns the routing fee of this htlc attempt.
+func (h *HtlcAttempt) Fee() lnwire.MilliSatoshi {
+	if h.Route == nil {
+		return 0
+	}
+
+	return lnwire.MilliSatoshi(h.Route.TotalFeesMsat)
+}
+
 // String returns a string representation of a htlc attempt.
 func (h *HtlcAttempt) String() string {
 	return fmt.Sprintf("Htlc attempt status: %v, attempted at: %v, "+
@@ -214,6 +341,16 @@ type SendPaymentRequest struct {
 	// MaxFee and MaxFeeMsat are mutually exclusive.
 	MaxFeeMsat lnwire.MilliSatoshi
 
+	// MaxFeePercent is the fee limit for this payment as a percentage of
+	// the payment amount. It is mutually exclusive with MaxFee and
+	// MaxFeeMsat.
+	MaxFeePercent float64
+
+	// FeeBudget is the fee limit for this payment as a combination of an
+	// absolute and a relative cap. It is mutually exclusive with all
+	// other fee limits.
+	FeeBudget *FeeBudget
+
 	// MaxCltv is the maximum timelock for this payment. If nil, there is no
 	// maximum.
 	MaxCltv *int32
@@ -260,6 +397,10 @@ type SendPaymentRequest struct {
 	// to complete the full amount.
 	MaxParts uint32
 
+	// MaxShardSizeMsat is the largest partial payment that may be used to
+	// complete the full amount. If zero, lnd's default is used.
+	MaxShardSizeMsat lnwire.MilliSatoshi
+
 	// KeySend is set to true if the tlv payload will include the preimage.
 	KeySend bool
 
@@ -269,8 +410,151 @@ type SendPaymentRequest struct {
 
 	// If set, circular payments to self are permitted.
 	AllowSelfPayment bool
+
+	// PaymentAddr is the payment address of the final hop. It is taken
+	// from the invoice if one is set.
+	PaymentAddr *[32]byte
+
+	// IgnoreInvoiceRouteHints pays the invoice without the route hints it
+	// contains. By default, lnd applies the route hints, payment address
+	// and final CLTV delta of the invoice. If set, the invoice is decoded
+	// locally and paid without its route hints instead. AMP invoices can't
+	// be paid this way, and Amount must not be set if the invoice has an
+	// amount.
+	IgnoreInvoiceRouteHints bool
+
+	// AttachToExisting makes the payment idempotent. If lnd already knows
+	// a payment with the same hash, the returned streams deliver the
+	// updates of that payment instead of an error. It can't be used for
+	// keysend payments.
+	AttachToExisting bool
+}
+
+// HtlcEventType is the type of the htlc an event belongs to.
+type HtlcEventType uint8
+
+const (
+	// HtlcEventTypeUnknown indicates that lnd didn't report the type of
+	// the htlc.
+	HtlcEventTypeUnknown HtlcEventType = iota
+
+	// HtlcEventTypeSend indicates that the htlc is a payment sent by us.
+	HtlcEventTypeSend
+
+	// HtlcEventTypeReceive indicates that the htlc is a payment received
+	// by us.
+	HtlcEventTypeReceive
+
+	// HtlcEventTypeForward indicates that the htlc is forwarded by us.
+	HtlcEventTypeForward
+)
+
+// String returns a human readable representation of the htlc event type.
+func (t HtlcEventType) String() string {
+	switch t {
+	case HtlcEventTypeSend:
+		return "Send"
+
+	case HtlcEventTypeReceive:
+		return "Receive"
+
+	case HtlcEventTypeForward:
+		return "Forward"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// HtlcEventUpdate holds the identifiers of an htlc and an event that happened
+// to it.
+type HtlcEventUpdate struct {
+	// IncomingChannelID is the short channel ID of the incoming channel.
+	// It is zero for htlcs sent by us.
+	IncomingChannelID uint64
+
+	// OutgoingChannelID is the short channel ID of the outgoing channel.
+	// It is zero for htlcs received by us.
+	OutgoingChannelID uint64
+
+	// IncomingHtlcID is the index of the htlc on the incoming channel.
+	IncomingHtlcID uint64
+
+	// OutgoingHtlcID is the index of the htlc on the outgoing channel.
+	OutgoingHtlcID uint64
+
+	// Timestamp is the time the event happened.
+	Timestamp time.Time
+
+	// EventType is the type of the htlc.
+	EventType HtlcEventType
+
+	// Event holds the details of the event. Consumers can switch over its
+	// concrete type.
+	Event HtlcEvent
+}
+
+// HtlcEvent is an interface implemented by all htlc events.
+type HtlcEvent interface {
+	// htlcEvent is a marker method that restricts the implementations to
+	// the event types of this package.
+	htlcEvent()
+}
+
+// HtlcInfo holds the amounts and time locks of an htlc.
+type HtlcInfo struct {
+	// IncomingTimelock is the time lock of the incoming htlc.
+	IncomingTimelock uint32
+
+	// OutgoingTimelock is the time lock of the outgoing htlc.
+	OutgoingTimelock uint32
+
+	// IncomingAmt is the amount of the incoming htlc.
+	IncomingAmt lnwire.MilliSatoshi
+
+	// OutgoingAmt is the amount of the outgoing htlc.
+	OutgoingAmt lnwire.MilliSatoshi
+}
+
+// ForwardHtlcEvent indicates that an htlc was forwarded to the outgoing
+// channel.
+type ForwardHtlcEvent struct {
+	// Info holds the amounts and time locks of the htlc.
+	Info HtlcInfo
+}
+
+func (*ForwardHtlcEvent) htlcEvent() {}
+
+// ForwardFailHtlcEvent indicates that a forwarded htlc was failed back by the
+// downstream node.
+type ForwardFailHtlcEvent struct{}
+
+func (*ForwardFailHtlcEvent) htlcEvent() {}
+
+// SettleHtlcEvent indicates that an htlc was settled.
+type SettleHtlcEvent struct{}
+
+func (*SettleHtlcEvent) htlcEvent() {}
+
+// LinkFailHtlcEvent indicates that an htlc was failed by our node, before it
+// could be forwarded.
+type LinkFailHtlcEvent struct {
+	// Info holds the amounts and time locks of the htlc.
+	Info HtlcInfo
+
+	// WireFailure is the failure code that was sent back to the sender.
+	WireFailure lnrpc.Failure_FailureCode
+
+	// FailureDetail provides additional information about the failure
+	// that isn't sent over the wire.
+	FailureDetail routerrpc.FailureDetail
+
+	// FailureString is a human readable description of the failure.
+	FailureString string
 }
 
+func (*LinkFailHtlcEvent) htlcEvent() {}
+
 // InterceptedHtlc contains information about a htlc that was intercepted in
 // lnd's switch.
 type InterceptedHtlc struct {
@@ -297,10 +581,19 @@ type InterceptedHtlc struct {
 	// Since lnd has non-strict forwarding, this may not be the channel that
 	// the htlc ends up being forwarded on.
 	OutgoingChannelID lnwire.ShortChannelID
+
+	// CustomRecords holds the custom tlv records that were included in
+	// the htlc's onion payload for us.
+	CustomRecords map[uint64][]byte
+
+	// OnionBlob is the onion blob for the next hop.
+	OnionBlob []byte
 }
 
 // HtlcInterceptHandler is a function signature for handling code for htlc
-// interception.
+// interception. If the handler returns a nil response, the htlc is resumed.
+// If it returns an error, the htlc is resumed before the interceptor exits
+// with that error, so that it is never left on hold.
 type HtlcInterceptHandler func(context.Context,
 	InterceptedHtlc) (*InterceptedHtlcResponse, error)
 
@@ -339,18 +632,21 @@ type routerClient struct {
 	client       routerrpc.RouterClient
 	routerKitMac serializedMacaroon
 	timeout      time.Duration
+	params       *chaincfg.Params
 	quitOnce     sync.Once
 	quit         chan struct{}
 	wg           sync.WaitGroup
 }
 
 func newRouterClient(conn grpc.ClientConnInterface,
-	routerKitMac serializedMacaroon, timeout time.Duration) *routerClient {
+	routerKitMac serializedMacaroon, timeout time.Duration,
+	params *chaincfg.Params) *routerClient {
 
 	return &routerClient{
 		client:       routerrpc.NewRouterClient(conn),
 		routerKitMac: routerKitMac,
 		timeout:      timeout,
+		params:       params,
 		quit:         make(chan struct{}),
 	}
 }
@@ -370,6 +666,11 @@ func (r *routerClient) WaitForFinished() {
 func (r *routerClient) SendPayment(ctx context.Context,
 	request SendPaymentRequest) (chan PaymentStatus, chan error, error) {
 
+	if request.AttachToExisting {
+		request.AttachToExisting = false
+		return r.attachOrSend(ctx, request)
+	}
+
 	rpcCtx := r.routerKitMac.WithMacaroonAuth(ctx)
 	rpcReq := &routerrpc.SendPaymentRequest{
 		FeeLimitSat:      int64(request.MaxFee),
@@ -380,6 +681,40 @@ func (r *routerClient) SendPayment(ctx context.Context,
 		OutgoingChanIds:  request.OutgoingChanIds,
 		AllowSelfPayment: request.AllowSelfPayment,
 		Amt:              int64(request.Amount),
+		MaxShardSizeMsat: uint64(request.MaxShardSizeMsat),
+	}
+
+	if request.MaxFeePercent != 0 {
+		if request.MaxFee != 0 || request.MaxFeeMsat != 0 {
+			return nil, nil, errors.New("fee percentage can't be " +
+				"combined with an absolute fee limit")
+		}
+
+		feeLimit, err := r.percentFeeLimit(request)
+		if err != nil {
+			return nil, nil, err
+		}
+		rpcReq.FeeLimitMsat = int64(feeLimit)
+	}
+	if request.FeeBudget != nil {
+		if request.MaxFee != 0 || request.MaxFeeMsat != 0 ||
+			request.MaxFeePercent != 0 {
+
+			return nil, nil, errors.New("fee budget can't be " +
+				"combined with another fee limit")
+		}
+
+		// The payment amount is only needed for the relative cap, so
+		// an absolute budget works for invoices without an amount.
+		var amt lnwire.MilliSatoshi
+		if request.FeeBudget.MaxFeePPM != 0 {
+			var err error
+			amt, err = r.paymentAmount(request)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		rpcReq.FeeLimitMsat = int64(request.FeeBudget.Limit(amt))
 	}
 	if request.MaxCltv != nil {
 		rpcReq.CltvLimit = *request.MaxCltv
@@ -389,6 +724,9 @@ func (r *routerClient) SendPayment(ctx context.Context,
 		rpcReq.LastHopPubkey = request.LastHopPubkey[:]
 	}
 
+	if err := ValidateCustomRecords(request.CustomRecords); err != nil {
+		return nil, nil, err
+	}
 	rpcReq.DestCustomRecords = request.CustomRecords
 
 	if request.KeySend {
@@ -412,6 +750,13 @@ func (r *routerClient) SendPayment(ctx context.Context,
 		request.PaymentHash = &hash
 	}
 
+	if request.Invoice != "" && request.IgnoreInvoiceRouteHints {
+		err := r.applyInvoice(&request, rpcReq)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Only if there is no payment request set, we will parse the individual
 	// payment parameters.
 	if request.Invoice == "" {
@@ -419,6 +764,10 @@ func (r *routerClient) SendPayment(ctx context.Context,
 		rpcReq.PaymentHash = request.PaymentHash[:]
 		rpcReq.FinalCltvDelta = int32(request.FinalCLTVDelta)
 
+		if request.PaymentAddr != nil {
+			rpcReq.PaymentAddr = request.PaymentAddr[:]
+		}
+
 		routeHints, err := marshallRouteHints(request.RouteHints)
 		if err != nil {
 			return nil, nil, err
@@ -434,6 +783,263 @@ func (r *routerClient) SendPayment(ctx context.Context,
 	return r.trackPayment(ctx, stream)
 }
 
+// percentFeeLimit converts the fee percentage of a payment request into an
+// absolute fee limit. The payment amount is taken from the invoice, or from the
+// request if the invoice doesn't specify an amount.
+func (r *routerClient) percentFeeLimit(request SendPaymentRequest) (
+	lnwire.MilliSatoshi, error) {
+
+	if request.MaxFeePercent < 0 {
+		return 0, errors.New("fee percentage must not be negative")
+	}
+
+	amt, err := r.paymentAmount(request)
+	if err != nil {
+		return 0, err
+	}
+
+	return lnwire.MilliSatoshi(
+		float64(amt) * request.MaxFeePercent / 100,
+	), nil
+}
+
+// paymentAmount returns the amount of a payment, which is taken from its
+// invoice if it specifies one. An error is returned if the amount is zero.
+func (r *routerClient) paymentAmount(request SendPaymentRequest) (
+	lnwire.MilliSatoshi, error) {
+
+	amt := lnwire.NewMSatFromSatoshis(request.Amount)
+	if request.Invoice != "" {
+		payReq, err := zpay32.Decode(request.Invoice, r.params)
+		if err != nil {
+			return 0, err
+		}
+
+		if payReq.MilliSat != nil {
+			amt = *payReq.MilliSat
+		}
+	}
+
+	if amt == 0 {
+		return 0, errors.New("relative fee limit requires a payment " +
+			"amount")
+	}
+
+	return amt, nil
+}
+
+// FeeBudget is a fee limit that combines an absolute and a relative cap. The
+// lower of both applies to a payment.
+type FeeBudget struct {
+	// MaxFeeMsat is the absolute fee cap. If zero, only the relative cap
+	// applies.
+	MaxFeeMsat lnwire.MilliSatoshi
+
+	// MaxFeePPM is the fee cap in parts per million of the payment
+	// amount. If zero, only the absolute cap applies.
+	MaxFeePPM uint64
+}
+
+// Limit returns the fee limit of the budget for a payment of the given
+// amount. If neither cap is set, the limit is zero.
+func (b FeeBudget) Limit(amt lnwire.MilliSatoshi) lnwire.MilliSatoshi {
+	if b.MaxFeePPM == 0 {
+		return b.MaxFeeMsat
+	}
+
+	limit := amt * lnwire.MilliSatoshi(b.MaxFeePPM) / 1e6
+	if b.MaxFeeMsat != 0 && b.MaxFeeMsat < limit {
+		return b.MaxFeeMsat
+	}
+
+	return limit
+}
+
+// attachOrSend tracks the payment with the hash of the request and only sends
+// it if lnd doesn't know it yet or it already failed, in which case lnd allows
+// it to be retried. If the payment is started concurrently after our check,
+// we attach to it as well. The updates of whichever payment we end up
+// following are delivered on a single pair of streams.
+func (r *routerClient) attachOrSend(ctx context.Context,
+	request SendPaymentRequest) (chan PaymentStatus, chan error, error) {
+
+	var hash lntypes.Hash
+	switch {
+	case request.KeySend:
+		return nil, nil, errors.New("keysend payments can't be " +
+			"attached to")
+
+	case request.Invoice != "":
+		payReq, err := zpay32.Decode(request.Invoice, r.params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if payReq.PaymentHash == nil {
+			return nil, nil, errors.New("invoice has no payment " +
+				"hash")
+		}
+		hash = *payReq.PaymentHash
+
+	case request.PaymentHash != nil:
+		hash = *request.PaymentHash
+
+	default:
+		return nil, nil, errors.New("invoice or payment hash required")
+	}
+
+	statusChan, errChan, err := r.TrackPayment(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// next decides how to continue once the stream we follow failed.
+	var sent, reattached, attached, failed bool
+	next := func(err error) (chan PaymentStatus, chan error, error) {
+		switch {
+		// lnd doesn't know the payment, so we send it.
+		case errors.Is(err, channeldb.ErrPaymentNotInitiated) && !sent:
+			sent = true
+			return r.SendPayment(ctx, request)
+
+		// The payment was started after we checked, so we attach to it
+		// after all.
+		case errors.Is(err, channeldb.ErrAlreadyPaid) && sent &&
+			!reattached:
+
+			reattached = true
+			return r.TrackPayment(ctx, hash)
+
+		default:
+			return nil, nil, err
+		}
+	}
+
+	statusOut := make(chan PaymentStatus)
+	errOut := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case status, ok := <-statusChan:
+				// A payment that already failed is sent again
+				// once its tracking stream ended.
+				if !ok && failed {
+					failed = false
+					sent = true
+
+					var err error
+					statusChan, errChan, err = r.SendPayment(
+						ctx, request,
+					)
+					if err != nil {
+						errOut <- err
+						return
+					}
+
+					continue
+				}
+
+				if !ok {
+					close(statusOut)
+					close(errOut)
+					return
+				}
+
+				// The first update of a tracked payment is its
+				// current state. We only attach to a payment
+				// that is in flight or succeeded.
+				if !sent && !attached {
+					if status.State == lnrpc.Payment_FAILED {
+						failed = true
+						continue
+					}
+
+					attached = true
+				}
+
+				select {
+				case statusOut <- status:
+				case <-ctx.Done():
+					return
+				}
+
+			case err, ok := <-errChan:
+				if !ok {
+					errChan = nil
+					continue
+				}
+
+				statusChan, errChan, err = next(err)
+				if err != nil {
+					errOut <- err
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return statusOut, errOut, nil
+}
+
+// applyInvoice decodes the invoice of the request and replaces it with the
+// individual payment parameters it contains, except for its route hints.
+func (r *routerClient) applyInvoice(request *SendPaymentRequest,
+	rpcReq *routerrpc.SendPaymentRequest) error {
+
+	payReq, err := zpay32.Decode(request.Invoice, r.params)
+	if err != nil {
+		return err
+	}
+
+	if payReq.PaymentHash == nil {
+		return errors.New("invoice has no payment hash")
+	}
+
+	// Paying an AMP invoice by its payment hash would turn it into a
+	// regular payment the recipient can't settle.
+	if payReq.Features != nil &&
+		payReq.Features.HasFeature(lnwire.AMPOptional) {
+
+		return errors.New("AMP invoices can't be paid without their " +
+			"route hints")
+	}
+
+	// lnd rejects an amount for an invoice that specifies one, so we
+	// don't silently replace it either.
+	if payReq.MilliSat != nil && request.Amount != 0 {
+		return errors.New("amount must not be set for an invoice " +
+			"with an amount")
+	}
+
+	hash := lntypes.Hash(*payReq.PaymentHash)
+	request.Target = route.NewVertex(payReq.Destination)
+	request.PaymentHash = &hash
+	request.FinalCLTVDelta = uint16(payReq.MinFinalCLTVExpiry())
+	request.PaymentAddr = payReq.PaymentAddr
+	request.RouteHints = nil
+	request.Invoice = ""
+
+	rpcReq.PaymentRequest = ""
+	if payReq.MilliSat != nil {
+		rpcReq.AmtMsat = int64(*payReq.MilliSat)
+	}
+
+	// Without the invoice, lnd doesn't know which features the recipient
+	// supports, for example whether it accepts multi-part payments.
+	if payReq.Features != nil {
+		for bit := range payReq.Features.Features() {
+			rpcReq.DestFeatures = append(
+				rpcReq.DestFeatures, lnrpc.FeatureBit(bit),
+			)
+		}
+	}
+
+	return nil
+}
+
 // TrackPayment picks up a previously started payment and returns a payment
 // update stream and an error stream.
 func (r *routerClient) TrackPayment(ctx context.Context,
@@ -511,74 +1117,491 @@ func (r *routerClient) trackPayment(ctx context.Context,
 	return statusChan, errorChan, nil
 }
 
-// EstimateRouteFee uses the channel router's internal state to estimate the
-// routing cost of the given amount to the destination node.
-func (r *routerClient) EstimateRouteFee(ctx context.Context, dest route.Vertex,
-	amt btcutil.Amount) (lnwire.MilliSatoshi, error) {
+const (
+	// defaultKeysendTimeout is the payment loop timeout used for keysend
+	// payments if none is set.
+	defaultKeysendTimeout = time.Minute
+
+	// defaultKeysendFeePPM is the fee limit of keysend payments in parts
+	// per million of their amount if none is set.
+	defaultKeysendFeePPM = 50000
+
+	// defaultKeysendMinFeeMsat is the lowest fee limit of keysend
+	// payments if none is set, so that small payments aren't restricted
+	// to routes without fees.
+	defaultKeysendMinFeeMsat = lnwire.MilliSatoshi(10000)
+)
 
-	rpcCtx := r.routerKitMac.WithMacaroonAuth(ctx)
-	rpcReq := &routerrpc.RouteFeeRequest{
-		Dest:   dest[:],
-		AmtSat: int64(amt),
+// KeysendOption is a functional option that configures a keysend payment.
+type KeysendOption func(r *SendPaymentRequest)
+
+// WithKeysendCustomRecords is an option for adding custom TLV records to a
+// keysend payment. The keysend record itself is set by SendKeysend.
+func WithKeysendCustomRecords(records map[uint64][]byte) KeysendOption {
+	return func(r *SendPaymentRequest) {
+		r.CustomRecords = make(map[uint64][]byte, len(records))
+		for key, value := range records {
+			r.CustomRecords[key] = value
+		}
 	}
+}
 
-	rpcRes, err := r.client.EstimateRouteFee(rpcCtx, rpcReq)
-	if err != nil {
-		return 0, err
+// WithKeysendMaxFee is an option for limiting the fee of a keysend payment.
+// It replaces the default limit of 5% of the amount, but at least 10 sat.
+func WithKeysendMaxFee(maxFee btcutil.Amount) KeysendOption {
+	return func(r *SendPaymentRequest) {
+		r.MaxFee = maxFee
+		r.MaxFeeMsat = 0
 	}
+}
 
-	return lnwire.MilliSatoshi(rpcRes.RoutingFeeMsat), nil
+// WithKeysendTimeout is an option for setting the payment loop timeout of a
+// keysend payment. After this time, no new payment attempts are started.
+func WithKeysendTimeout(timeout time.Duration) KeysendOption {
+	return func(r *SendPaymentRequest) {
+		r.Timeout = timeout
+	}
 }
 
-// unmarshallPaymentStatus converts an rpc status update to the PaymentStatus
-// type that is used throughout the application.
-func unmarshallPaymentStatus(rpcPayment *lnrpc.Payment) (
-	*PaymentStatus, error) {
+// SendKeysend sends a spontaneous payment to the destination and blocks until
+// it reached a final state. Unless it is set with WithKeysendMaxFee, the fee
+// limit of the payment is 5% of its amount, but at least 10 sat.
+func (r *routerClient) SendKeysend(ctx context.Context, dest route.Vertex,
+	amt btcutil.Amount, opts ...KeysendOption) (*PaymentStatus, error) {
 
-	status := PaymentStatus{
-		State: rpcPayment.Status,
-		Htlcs: make([]*HtlcAttempt, len(rpcPayment.Htlcs)),
+	maxFee := lnwire.NewMSatFromSatoshis(amt) * defaultKeysendFeePPM / 1e6
+	if maxFee < defaultKeysendMinFeeMsat {
+		maxFee = defaultKeysendMinFeeMsat
 	}
 
-	switch status.State {
-	case lnrpc.Payment_SUCCEEDED:
-		preimage, err := lntypes.MakePreimageFromStr(
-			rpcPayment.PaymentPreimage,
-		)
-		if err != nil {
-			return nil, err
-		}
-		status.Preimage = preimage
-		status.Fee = lnwire.MilliSatoshi(rpcPayment.FeeMsat)
-		status.Value = lnwire.MilliSatoshi(rpcPayment.ValueMsat)
-
-	case lnrpc.Payment_FAILED:
-		status.FailureReason = rpcPayment.FailureReason
+	request := SendPaymentRequest{
+		Target:     dest,
+		Amount:     amt,
+		MaxFeeMsat: maxFee,
+		Timeout:    defaultKeysendTimeout,
+		KeySend:    true,
+	}
+	for _, opt := range opts {
+		opt(&request)
 	}
 
-	for i, htlc := range rpcPayment.Htlcs {
-		attempt, err := NewHtlcAttempt(htlc)
-		if err != nil {
-			return nil, err
-		}
-		status.Htlcs[i] = attempt
+	return r.sendAndWait(ctx, request)
+}
 
-		if htlc.Status != lnrpc.HTLCAttempt_IN_FLIGHT {
-			continue
-		}
+// sendAndWait sends a payment and blocks until it reached a final state.
+func (r *routerClient) sendAndWait(ctx context.Context,
+	request SendPaymentRequest) (*PaymentStatus, error) {
 
-		status.InFlightHtlcs++
-		status.InFlightAmt += attempt.AmountInFlight()
+	statusChan, errChan, err := r.SendPayment(ctx, request)
+	if err != nil {
+		return nil, err
 	}
 
-	return &status, nil
-}
+	var final *PaymentStatus
+	for {
+		select {
+		case status, ok := <-statusChan:
+			// The status channel is closed once the payment
+			// reached a final state.
+			if !ok {
+				if final == nil {
+					return nil, errors.New("payment " +
+						"stream closed without status")
+				}
 
-// marshallRouteHints marshalls a list of route hints.
-func marshallRouteHints(routeHints [][]zpay32.HopHint) (
-	[]*lnrpc.RouteHint, error) {
+				return final, nil
+			}
 
-	rpcRouteHints := make([]*lnrpc.RouteHint, 0, len(routeHints))
+			final = &status
+
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+
+			return nil, err
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+const (
+	// defaultProbeTimeout is the payment loop timeout used for probes if
+	// none is set.
+	defaultProbeTimeout = time.Minute
+
+	// defaultProbeMaxFeeMsat is the fee limit of probes if none is set.
+	// A probe can't settle, so its fee is never paid and we don't need
+	// to exclude any route because of its fee.
+	defaultProbeMaxFeeMsat = lnwire.MilliSatoshi(math.MaxInt64)
+)
+
+// ProbeOption is a functional option that configures a probe.
+type ProbeOption func(r *SendPaymentRequest)
+
+// WithProbeMaxFee is an option for limiting the fee of the routes that are
+// probed. By default, the fee of the probed routes isn't limited.
+func WithProbeMaxFee(maxFee btcutil.Amount) ProbeOption {
+	return func(r *SendPaymentRequest) {
+		r.MaxFee = maxFee
+		r.MaxFeeMsat = 0
+	}
+}
+
+// WithProbeTimeout is an option for setting the payment loop timeout of a
+// probe. After this time, no new routes are tried.
+func WithProbeTimeout(timeout time.Duration) ProbeOption {
+	return func(r *SendPaymentRequest) {
+		r.Timeout = timeout
+	}
+}
+
+// ProbeResult is the result of a successful probe.
+type ProbeResult struct {
+	// Route is the route that reached the destination.
+	Route *route.Route
+
+	// Fee is the routing fee of the route.
+	Fee lnwire.MilliSatoshi
+
+	// TimeLock is the total time lock of the route.
+	TimeLock uint32
+
+	// Latency is the time it took for the htlc on the route to be failed
+	// back by the destination.
+	Latency time.Duration
+}
+
+// ProbeError is returned if a probe didn't reach the destination.
+type ProbeError struct {
+	// Reason is the reason the probe payment failed.
+	Reason lnrpc.PaymentFailureReason
+}
+
+// Error returns a string representation of the error.
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("probe failed: %v", e.Reason)
+}
+
+// Probe sends a payment with a random hash to the destination. As the
+// destination doesn't know the hash, it fails the payment with incorrect
+// payment details if a route reached it, which is interpreted as success.
+func (r *routerClient) Probe(ctx context.Context, dest route.Vertex,
+	amt btcutil.Amount, opts ...ProbeOption) (*ProbeResult, error) {
+
+	var hash lntypes.Hash
+	if _, err := rand.Read(hash[:]); err != nil {
+		return nil, err
+	}
+
+	request := SendPaymentRequest{
+		Target:      dest,
+		Amount:      amt,
+		PaymentHash: &hash,
+		MaxFeeMsat:  defaultProbeMaxFeeMsat,
+		Timeout:     defaultProbeTimeout,
+		MaxParts:    1,
+	}
+	for _, opt := range opts {
+		opt(&request)
+	}
+
+	status, err := r.sendAndWait(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// The attempt that reached the destination is the one that was
+	// failed with incorrect payment details by the final hop.
+	for _, htlc := range status.Htlcs {
+		if !reachedDestination(htlc) {
+			continue
+		}
+
+		probeRoute, err := unmarshallRoute(htlc.Route, route.Vertex{})
+		if err != nil {
+			return nil, err
+		}
+
+		return &ProbeResult{
+			Route:    probeRoute,
+			Fee:      probeRoute.TotalFees(),
+			TimeLock: probeRoute.TotalTimeLock,
+			Latency:  htlc.ResolveTime.Sub(htlc.AttemptTime),
+		}, nil
+	}
+
+	return nil, &ProbeError{Reason: status.FailureReason}
+}
+
+// reachedDestination returns true if the htlc attempt was failed by the final
+// hop of its route because it didn't know the payment hash.
+func reachedDestination(htlc *HtlcAttempt) bool {
+	if htlc.Failure == nil || htlc.Route == nil {
+		return false
+	}
+
+	if htlc.Failure.Code !=
+		lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS {
+
+		return false
+	}
+
+	return int(htlc.Failure.FailureSourceIndex) == len(htlc.Route.Hops)
+}
+
+// EstimateRouteFee uses the channel router's internal state to estimate the
+// routing cost of the given amount to the destination node.
+func (r *routerClient) EstimateRouteFee(ctx context.Context, dest route.Vertex,
+	amt btcutil.Amount) (lnwire.MilliSatoshi, error) {
+
+	estimate, err := r.EstimateRoute(ctx, dest, amt)
+	if err != nil {
+		return 0, err
+	}
+
+	return estimate.RoutingFee, nil
+}
+
+// RouteFeeEstimate is the estimated cost of routing a payment to a node.
+type RouteFeeEstimate struct {
+	// RoutingFee is the estimated fee paid to the nodes along the route.
+	RoutingFee lnwire.MilliSatoshi
+
+	// TimeLockDelay is the estimated number of blocks our funds could be
+	// locked up for if the payment got stuck.
+	TimeLockDelay int64
+}
+
+// EstimateRoute uses the channel router's internal state to estimate the
+// routing fee and the time lock delay of a payment of the given amount to the
+// destination node.
+func (r *routerClient) EstimateRoute(ctx context.Context, dest route.Vertex,
+	amt btcutil.Amount) (*RouteFeeEstimate, error) {
+
+	rpcCtx := r.routerKitMac.WithMacaroonAuth(ctx)
+	rpcReq := &routerrpc.RouteFeeRequest{
+		Dest:   dest[:],
+		AmtSat: int64(amt),
+	}
+
+	rpcRes, err := r.client.EstimateRouteFee(rpcCtx, rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteFeeEstimate{
+		RoutingFee:    lnwire.MilliSatoshi(rpcRes.RoutingFeeMsat),
+		TimeLockDelay: rpcRes.TimeLockDelay,
+	}, nil
+}
+
+// defaultFinalCltvDelta is the final cltv delta used to build routes if none
+// is given. It matches lnd's default timelock delta.
+const defaultFinalCltvDelta = 40
+
+// BuildRoute builds a fully specified route through the given hops, using the
+// current policies of the channels in lnd's graph. If amt is zero, the minimum
+// amount that can be carried by the route is used. If finalCltvDelta is zero,
+// a default of 40 blocks is used. The outgoing channel and payment address are
+// optional. The source of the returned route is not set, as it always starts
+// at our node.
+func (r *routerClient) BuildRoute(ctx context.Context, amt lnwire.MilliSatoshi,
+	finalCltvDelta uint16, outgoingChan *uint64, hops []route.Vertex,
+	payAddr *[32]byte) (*route.Route, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+
+	// lnd doesn't default the final cltv delta, a route built without it
+	// would expire at the current height.
+	if finalCltvDelta == 0 {
+		finalCltvDelta = defaultFinalCltvDelta
+	}
+
+	rpcReq := &routerrpc.BuildRouteRequest{
+		AmtMsat:        int64(amt),
+		FinalCltvDelta: int32(finalCltvDelta),
+		HopPubkeys:     make([][]byte, len(hops)),
+	}
+
+	for i, hop := range hops {
+		hop := hop
+		rpcReq.HopPubkeys[i] = hop[:]
+	}
+
+	if outgoingChan != nil {
+		rpcReq.OutgoingChanId = *outgoingChan
+	}
+
+	if payAddr != nil {
+		rpcReq.PaymentAddr = payAddr[:]
+	}
+
+	resp, err := r.client.BuildRoute(rpcCtx, rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshallRoute(resp.Route, route.Vertex{})
+}
+
+// SendToRoute attempts to pay the given payment hash over the route provided.
+// The call blocks until the htlc is resolved and returns the resulting
+// attempt, which holds the preimage if the htlc settled or the failure and its
+// source if it failed. As the htlc may be held by the recipient, no timeout is
+// applied other than the one of the passed context.
+func (r *routerClient) SendToRoute(ctx context.Context, hash lntypes.Hash,
+	payRoute *route.Route) (*HtlcAttempt, error) {
+
+	rpcRoute, err := marshallRoute(payRoute)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcCtx := r.routerKitMac.WithMacaroonAuth(ctx)
+	resp, err := r.client.SendToRouteV2(
+		rpcCtx, &routerrpc.SendToRouteRequest{
+			PaymentHash: hash[:],
+			Route:       rpcRoute,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHtlcAttempt(resp)
+}
+
+// unmarshallPaymentStatus converts an rpc status update to the PaymentStatus
+// type that is used throughout the application.
+func unmarshallPaymentStatus(rpcPayment *lnrpc.Payment) (
+	*PaymentStatus, error) {
+
+	hash, err := lntypes.MakeHashFromStr(rpcPayment.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	status := PaymentStatus{
+		Hash:  hash,
+		State: rpcPayment.Status,
+		Htlcs: make([]*HtlcAttempt, len(rpcPayment.Htlcs)),
+	}
+
+	switch status.State {
+	case lnrpc.Payment_SUCCEEDED:
+		preimage, err := lntypes.MakePreimageFromStr(
+			rpcPayment.PaymentPreimage,
+		)
+		if err != nil {
+			return nil, err
+		}
+		status.Preimage = preimage
+		status.Fee = lnwire.MilliSatoshi(rpcPayment.FeeMsat)
+		status.Value = lnwire.MilliSatoshi(rpcPayment.ValueMsat)
+
+	case lnrpc.Payment_FAILED:
+		status.FailureReason = rpcPayment.FailureReason
+	}
+
+	for i, htlc := range rpcPayment.Htlcs {
+		attempt, err := NewHtlcAttempt(htlc)
+		if err != nil {
+			return nil, err
+		}
+		status.Htlcs[i] = attempt
+
+		if status.State == lnrpc.Payment_SUCCEEDED &&
+			htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED &&
+			htlc.Route != nil {
+
+			htlcRoute, err := unmarshallRoute(
+				htlc.Route, route.Vertex{},
+			)
+			if err != nil {
+				return nil, err
+			}
+			status.Routes = append(status.Routes, htlcRoute)
+		}
+
+		switch htlc.Status {
+		case lnrpc.HTLCAttempt_IN_FLIGHT:
+			status.InFlightHtlcs++
+			status.InFlightAmt += attempt.AmountInFlight()
+			status.InFlightFee += attempt.Fee()
+
+		case lnrpc.HTLCAttempt_SUCCEEDED:
+			status.SettledHtlcs++
+			status.SettledAmt += attempt.Amount()
+			status.SettledFee += attempt.Fee()
+
+		case lnrpc.HTLCAttempt_FAILED:
+			status.FailedHtlcs++
+		}
+	}
+
+	return &status, nil
+}
+
+// marshallRoute converts a route into its rpc counterpart.
+func marshallRoute(r *route.Route) (*lnrpc.Route, error) {
+	if r == nil {
+		return nil, errors.New("route required")
+	}
+
+	rpcRoute := &lnrpc.Route{
+		TotalTimeLock: r.TotalTimeLock,
+		TotalFeesMsat: int64(r.TotalFees()),
+		TotalAmtMsat:  int64(r.TotalAmount),
+		Hops:          make([]*lnrpc.Hop, len(r.Hops)),
+	}
+
+	for i, hop := range r.Hops {
+		rpcHop := &lnrpc.Hop{
+			ChanId:           hop.ChannelID,
+			Expiry:           hop.OutgoingTimeLock,
+			AmtToForwardMsat: int64(hop.AmtToForward),
+			FeeMsat:          int64(r.HopFee(i)),
+			PubKey:           hop.PubKeyBytes.String(),
+			TlvPayload:       !hop.LegacyPayload,
+			CustomRecords:    hop.CustomRecords,
+		}
+
+		if hop.MPP != nil {
+			paymentAddr := hop.MPP.PaymentAddr()
+			rpcHop.MppRecord = &lnrpc.MPPRecord{
+				PaymentAddr:  paymentAddr[:],
+				TotalAmtMsat: int64(hop.MPP.TotalMsat()),
+			}
+		}
+
+		if hop.AMP != nil {
+			rootShare := hop.AMP.RootShare()
+			setID := hop.AMP.SetID()
+			rpcHop.AmpRecord = &lnrpc.AMPRecord{
+				RootShare:  rootShare[:],
+				SetId:      setID[:],
+				ChildIndex: hop.AMP.ChildIndex(),
+			}
+		}
+
+		rpcRoute.Hops[i] = rpcHop
+	}
+
+	return rpcRoute, nil
+}
+
+// marshallRouteHints marshalls a list of route hints.
+func marshallRouteHints(routeHints [][]zpay32.HopHint) (
+	[]*lnrpc.RouteHint, error) {
+
+	rpcRouteHints := make([]*lnrpc.RouteHint, 0, len(routeHints))
 	for _, routeHint := range routeHints {
 		rpcRouteHint := make(
 			[]*lnrpc.HopHint, 0, len(routeHint),
@@ -662,6 +1685,124 @@ func (r *routerClient) SubscribeHtlcEvents(ctx context.Context) (
 	return htlcChan, errChan, nil
 }
 
+// SubscribeHtlcEventUpdates subscribes to a stream of typed htlc events from
+// the router. Both returned channels are closed when the subscription ends.
+func (r *routerClient) SubscribeHtlcEventUpdates(ctx context.Context) (
+	<-chan *HtlcEventUpdate, <-chan error, error) {
+
+	stream, err := r.client.SubscribeHtlcEvents(
+		r.routerKitMac.WithMacaroonAuth(ctx),
+		&routerrpc.SubscribeHtlcEventsRequest{},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errChan := make(chan error, 1)
+	updates := make(chan *HtlcEventUpdate)
+
+	go func() {
+		defer close(errChan)
+		defer close(updates)
+
+		for {
+			rpcEvent, err := stream.Recv()
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			update, err := unmarshallHtlcEvent(rpcEvent)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			if update == nil {
+				continue
+			}
+
+			select {
+			case updates <- update:
+
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return updates, errChan, nil
+}
+
+// unmarshallHtlcEvent converts an rpc htlc event into a typed update. Nil is
+// returned for unknown events.
+func unmarshallHtlcEvent(rpcEvent *routerrpc.HtlcEvent) (*HtlcEventUpdate,
+	error) {
+
+	update := &HtlcEventUpdate{
+		IncomingChannelID: rpcEvent.IncomingChannelId,
+		OutgoingChannelID: rpcEvent.OutgoingChannelId,
+		IncomingHtlcID:    rpcEvent.IncomingHtlcId,
+		OutgoingHtlcID:    rpcEvent.OutgoingHtlcId,
+		Timestamp:         time.Unix(0, int64(rpcEvent.TimestampNs)),
+	}
+
+	switch rpcEvent.EventType {
+	case routerrpc.HtlcEvent_SEND:
+		update.EventType = HtlcEventTypeSend
+
+	case routerrpc.HtlcEvent_RECEIVE:
+		update.EventType = HtlcEventTypeReceive
+
+	case routerrpc.HtlcEvent_FORWARD:
+		update.EventType = HtlcEventTypeForward
+	}
+
+	switch event := rpcEvent.Event.(type) {
+	case *routerrpc.HtlcEvent_ForwardEvent:
+		update.Event = &ForwardHtlcEvent{
+			Info: unmarshallHtlcInfo(event.ForwardEvent.Info),
+		}
+
+	case *routerrpc.HtlcEvent_ForwardFailEvent:
+		update.Event = &ForwardFailHtlcEvent{}
+
+	case *routerrpc.HtlcEvent_SettleEvent:
+		update.Event = &SettleHtlcEvent{}
+
+	case *routerrpc.HtlcEvent_LinkFailEvent:
+		linkFail := event.LinkFailEvent
+		update.Event = &LinkFailHtlcEvent{
+			Info:          unmarshallHtlcInfo(linkFail.Info),
+			WireFailure:   linkFail.WireFailure,
+			FailureDetail: linkFail.FailureDetail,
+			FailureString: linkFail.FailureString,
+		}
+
+	// Events we don't know about, for example ones added by newer lnd
+	// versions, are skipped rather than failing the subscription.
+	default:
+		return nil, nil
+	}
+
+	return update, nil
+}
+
+// unmarshallHtlcInfo converts the rpc info of an htlc, which may be nil.
+func unmarshallHtlcInfo(info *routerrpc.HtlcInfo) HtlcInfo {
+	if info == nil {
+		return HtlcInfo{}
+	}
+
+	return HtlcInfo{
+		IncomingTimelock: info.IncomingTimelock,
+		OutgoingTimelock: info.OutgoingTimelock,
+		IncomingAmt:      lnwire.MilliSatoshi(info.IncomingAmtMsat),
+		OutgoingAmt:      lnwire.MilliSatoshi(info.OutgoingAmtMsat),
+	}
+}
+
 // InterceptHtlcs intercepts htlcs on a node, using the handler function
 // provided to provide the interceptor with interception decisions. The handler
 // provided may block, but must exit if the context passed in is canceled, and
@@ -727,43 +1868,12 @@ func (r *routerClient) InterceptHtlcs(ctx context.Context,
 				return
 			}
 
-			hash, err := lntypes.MakeHash(request.PaymentHash)
+			req, err := unmarshallInterceptedHtlc(request)
 			if err != nil {
 				sendErr(err)
 				return
 			}
 
-			if request.IncomingCircuitKey == nil {
-				sendErr(errors.New("incoming circuit key " +
-					"required"))
-
-				return
-			}
-
-			chanIn := lnwire.NewShortChanIDFromInt(
-				request.IncomingCircuitKey.ChanId,
-			)
-			chanOut := lnwire.NewShortChanIDFromInt(
-				request.OutgoingRequestedChanId,
-			)
-
-			req := InterceptedHtlc{
-				IncomingCircuitKey: channeldb.CircuitKey{
-					ChanID: chanIn,
-					HtlcID: request.IncomingCircuitKey.HtlcId,
-				},
-				Hash: hash,
-				AmountInMsat: lnwire.MilliSatoshi(
-					request.IncomingAmountMsat,
-				),
-				AmountOutMsat: lnwire.MilliSatoshi(
-					request.OutgoingAmountMsat,
-				),
-				IncomingExpiryHeight: request.IncomingExpiry,
-				OutgoingExpiryHeight: request.OutgoingExpiry,
-				OutgoingChannelID:    chanOut,
-			}
-
 			// Try to send our interception request, failing on
 			// context cancel or router exit. Under the hood, lnd
 			// releases all htlcs that are held once we cancel the
@@ -799,10 +1909,28 @@ func (r *routerClient) InterceptHtlcs(ctx context.Context,
 				// for a while.
 				resp, err := handler(ctx, request)
 				if err != nil {
+					// Resume the htlc before we exit so
+					// that it isn't held until lnd
+					// notices that our stream is gone.
+					resume := &InterceptedHtlcResponse{
+						Action: InterceptorActionResume,
+					}
+					rpcResp, _ := rpcInterceptorResponse(
+						request, resume,
+					)
+					_ = stream.Send(rpcResp)
+
 					sendErr(err)
 					return
 				}
 
+				// A nil response doesn't hold on to the htlc.
+				if resp == nil {
+					resp = &InterceptedHtlcResponse{
+						Action: InterceptorActionResume,
+					}
+				}
+
 				rpcResp, err := rpcInterceptorResponse(
 					request, resp,
 				)
@@ -831,6 +1959,49 @@ func (r *routerClient) InterceptHtlcs(ctx context.Context,
 	}
 }
 
+// unmarshallInterceptedHtlc converts a forward intercepted by lnd to an
+// InterceptedHtlc.
+func unmarshallInterceptedHtlc(
+	request *routerrpc.ForwardHtlcInterceptRequest) (InterceptedHtlc,
+	error) {
+
+	hash, err := lntypes.MakeHash(request.PaymentHash)
+	if err != nil {
+		return InterceptedHtlc{}, err
+	}
+
+	if request.IncomingCircuitKey == nil {
+		return InterceptedHtlc{}, errors.New("incoming circuit key " +
+			"required")
+	}
+
+	chanIn := lnwire.NewShortChanIDFromInt(
+		request.IncomingCircuitKey.ChanId,
+	)
+	chanOut := lnwire.NewShortChanIDFromInt(
+		request.OutgoingRequestedChanId,
+	)
+
+	return InterceptedHtlc{
+		IncomingCircuitKey: channeldb.CircuitKey{
+			ChanID: chanIn,
+			HtlcID: request.IncomingCircuitKey.HtlcId,
+		},
+		Hash: hash,
+		AmountInMsat: lnwire.MilliSatoshi(
+			request.IncomingAmountMsat,
+		),
+		AmountOutMsat: lnwire.MilliSatoshi(
+			request.OutgoingAmountMsat,
+		),
+		IncomingExpiryHeight: request.IncomingExpiry,
+		OutgoingExpiryHeight: request.OutgoingExpiry,
+		OutgoingChannelID:    chanOut,
+		CustomRecords:        request.CustomRecords,
+		OnionBlob:            request.OnionBlob,
+	}, nil
+}
+
 func rpcInterceptorResponse(request InterceptedHtlc,
 	response *InterceptedHtlcResponse) (
 	*routerrpc.ForwardHtlcInterceptResponse, error) {
@@ -868,6 +2039,238 @@ func rpcInterceptorResponse(request InterceptedHtlc,
 	return rpcResp, nil
 }
 
+// ErrHtlcAlreadyResolved is returned when a held htlc is resolved more than
+// once.
+var ErrHtlcAlreadyResolved = errors.New("htlc already resolved")
+
+// HeldHtlc is a handle for an intercepted htlc that is held until it is
+// resolved. Exactly one of its resolution methods takes effect, all later
+// calls return ErrHtlcAlreadyResolved.
+type HeldHtlc struct {
+	InterceptedHtlc
+
+	resolve func(*InterceptedHtlcResponse) error
+	once    sync.Once
+}
+
+// Resolve resolves the htlc with the given response.
+func (h *HeldHtlc) Resolve(resp *InterceptedHtlcResponse) error {
+	// We check the response before we use up the handle, so that an
+	// invalid response doesn't leave the htlc on hold.
+	_, err := rpcInterceptorResponse(h.InterceptedHtlc, resp)
+	if err != nil {
+		return err
+	}
+
+	err = ErrHtlcAlreadyResolved
+	h.once.Do(func() {
+		err = h.resolve(resp)
+	})
+
+	return err
+}
+
+// Resume resumes the htlc, forwarding it as normal.
+func (h *HeldHtlc) Resume() error {
+	return h.Resolve(&InterceptedHtlcResponse{
+		Action: InterceptorActionResume,
+	})
+}
+
+// Settle settles the htlc with the given preimage.
+func (h *HeldHtlc) Settle(preimage lntypes.Preimage) error {
+	return h.Resolve(&InterceptedHtlcResponse{
+		Action:   InterceptorActionSettle,
+		Preimage: &preimage,
+	})
+}
+
+// Fail fails the htlc back to the sender.
+func (h *HeldHtlc) Fail() error {
+	return h.Resolve(&InterceptedHtlcResponse{
+		Action: InterceptorActionFail,
+	})
+}
+
+// HtlcHoldHandler is a function signature for handling code for held htlcs.
+// The handler doesn't need to resolve the htlc before it returns, it can keep
+// the handle and resolve it later on.
+type HtlcHoldHandler func(context.Context, *HeldHtlc)
+
+// HoldHtlcs intercepts htlcs on a node and hands each of them to the handler
+// as a handle that can be resolved at any later point. Htlcs that aren't
+// resolved within the hold timeout are failed back. If the hold timeout is
+// zero, htlcs are held until they are resolved.
+//
+// The function blocks until the context is canceled, the router is shut down
+// or the interception stream fails. In the first two cases, all htlcs that
+// are still held are failed back before it returns. If the stream fails, lnd
+// releases the held htlcs itself. The context passed to the handlers is
+// canceled once the function returns.
+func (r *routerClient) HoldHtlcs(ctx context.Context, handler HtlcHoldHandler,
+	holdTimeout time.Duration) error {
+
+	// The stream must outlive the caller's context, so that we can still
+	// fail back the htlcs we hold once it is canceled.
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	defer cancelStream()
+
+	// Create a child context for the handlers that is canceled when this
+	// function exits, so that they don't outlive the stream if it fails
+	// or the router shuts down.
+	handlerCtx, cancelHandlers := context.WithCancel(ctx)
+	defer cancelHandlers()
+
+	stream, err := r.client.HtlcInterceptor(
+		r.routerKitMac.WithMacaroonAuth(streamCtx),
+	)
+	if err != nil {
+		return err
+	}
+
+	var (
+		held   = make(map[channeldb.CircuitKey]*HeldHtlc)
+		timers = make(map[channeldb.CircuitKey]*time.Timer)
+		heldMu sync.Mutex
+		sendMu sync.Mutex
+	)
+
+	// hold creates the handle for an intercepted htlc and tracks it until
+	// it is resolved.
+	hold := func(htlc InterceptedHtlc) *HeldHtlc {
+		key := htlc.IncomingCircuitKey
+
+		heldHtlc := &HeldHtlc{
+			InterceptedHtlc: htlc,
+		}
+		heldHtlc.resolve = func(resp *InterceptedHtlcResponse) error {
+			heldMu.Lock()
+			delete(held, key)
+			if timer, ok := timers[key]; ok {
+				timer.Stop()
+				delete(timers, key)
+			}
+			heldMu.Unlock()
+
+			rpcResp, err := rpcInterceptorResponse(htlc, resp)
+			if err != nil {
+				return err
+			}
+
+			sendMu.Lock()
+			defer sendMu.Unlock()
+
+			return stream.Send(rpcResp)
+		}
+
+		heldMu.Lock()
+		held[key] = heldHtlc
+		if holdTimeout > 0 {
+			timers[key] = time.AfterFunc(holdTimeout, func() {
+				log.Infof("Failing htlc %v after hold timeout",
+					key)
+
+				if err := heldHtlc.Fail(); err != nil &&
+					err != ErrHtlcAlreadyResolved {
+
+					log.Errorf("Unable to fail htlc %v: %v",
+						key, err)
+				}
+			})
+		}
+		heldMu.Unlock()
+
+		return heldHtlc
+	}
+
+	// stopTimers stops the hold timers of all htlcs that are still held,
+	// so that they don't try to fail them back on a stream that is gone.
+	stopTimers := func() {
+		heldMu.Lock()
+		defer heldMu.Unlock()
+
+		for key, timer := range timers {
+			timer.Stop()
+			delete(timers, key)
+		}
+	}
+
+	// failAll fails back all htlcs that are still held.
+	failAll := func() {
+		heldMu.Lock()
+		htlcs := make([]*HeldHtlc, 0, len(held))
+		for _, heldHtlc := range held {
+			htlcs = append(htlcs, heldHtlc)
+		}
+		heldMu.Unlock()
+
+		for _, heldHtlc := range htlcs {
+			err := heldHtlc.Fail()
+			if err != nil && err != ErrHtlcAlreadyResolved {
+				log.Errorf("Unable to fail htlc %v: %v",
+					heldHtlc.IncomingCircuitKey, err)
+			}
+		}
+	}
+
+	// Consume the interception requests of lnd in a goroutine. The error
+	// channel is buffered by 1 so that the goroutine can exit without
+	// the error being read.
+	requestChan := make(chan InterceptedHtlc)
+	errChan := make(chan error, 1)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			request, err := stream.Recv()
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			htlc, err := unmarshallInterceptedHtlc(request)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			select {
+			case requestChan <- htlc:
+			case <-streamCtx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case htlc := <-requestChan:
+			heldHtlc := hold(htlc)
+
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+
+				handler(handlerCtx, heldHtlc)
+			}()
+
+		// lnd releases the held htlcs itself once the stream is gone.
+		case err := <-errChan:
+			stopTimers()
+			return err
+
+		case <-r.quit:
+			failAll()
+			return ErrRouterShuttingDown
+
+		case <-ctx.Done():
+			failAll()
+			return ctx.Err()
+		}
+	}
+}
+
 // MissionControlEntry contains a mission control entry for a node pair.
 type MissionControlEntry struct {
 	// NodeFrom is the node that the payment was forwarded from.
@@ -920,31 +2323,72 @@ func (r *routerClient) QueryMissionControl(ctx context.Context) (
 			return nil, err
 		}
 
-		entry := MissionControlEntry{
-			NodeFrom: nodeFrom,
-			NodeTo:   nodeTo,
-			FailAmt: lnwire.MilliSatoshi(
-				pair.History.FailAmtMsat,
-			),
-			SuccessAmt: lnwire.MilliSatoshi(
-				pair.History.SuccessAmtMsat,
+		result = append(
+			result, newMissionControlEntry(
+				nodeFrom, nodeTo, pair.History,
 			),
-		}
+		)
+	}
 
-		if pair.History.FailTime != 0 {
-			entry.FailTime = time.Unix(pair.History.FailTime, 0)
-		}
+	return result, nil
+}
 
-		if pair.History.SuccessTime != 0 {
-			entry.SuccessTime = time.Unix(
-				pair.History.SuccessTime, 0,
-			)
-		}
+// newMissionControlEntry creates a mission control entry for the given pair
+// from its rpc history.
+func newMissionControlEntry(nodeFrom, nodeTo route.Vertex,
+	history *routerrpc.PairData) MissionControlEntry {
 
-		result = append(result, entry)
+	entry := MissionControlEntry{
+		NodeFrom:   nodeFrom,
+		NodeTo:     nodeTo,
+		FailAmt:    lnwire.MilliSatoshi(history.FailAmtMsat),
+		SuccessAmt: lnwire.MilliSatoshi(history.SuccessAmtMsat),
 	}
 
-	return result, nil
+	if history.FailTime != 0 {
+		entry.FailTime = time.Unix(history.FailTime, 0)
+	}
+
+	if history.SuccessTime != 0 {
+		entry.SuccessTime = time.Unix(history.SuccessTime, 0)
+	}
+
+	return entry
+}
+
+// QueryProbability returns mission control's estimate of the probability of
+// successfully forwarding the given amount from one node to the other, along
+// with the history of the pair it is based on. The history is nil if mission
+// control has no results for the pair.
+func (r *routerClient) QueryProbability(ctx context.Context, from,
+	to route.Vertex, amt lnwire.MilliSatoshi) (float64,
+	*MissionControlEntry, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	resp, err := r.client.QueryProbability(
+		r.routerKitMac.WithMacaroonAuth(rpcCtx),
+		&routerrpc.QueryProbabilityRequest{
+			FromNode: from[:],
+			ToNode:   to[:],
+			AmtMsat:  int64(amt),
+		},
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if resp.History == nil {
+		return resp.Probability, nil, nil
+	}
+
+	entry := newMissionControlEntry(from, to, resp.History)
+	if entry.FailTime.IsZero() && entry.SuccessTime.IsZero() {
+		return resp.Probability, nil, nil
+	}
+
+	return resp.Probability, &entry, nil
 }
 
 // ImportMissionControl imports a set of pathfinding results to mission control.
@@ -955,6 +2399,19 @@ func (r *routerClient) ImportMissionControl(ctx context.Context,
 	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
+	req := marshallMissionControlEntries(entries, force)
+
+	_, err := r.client.XImportMissionControl(
+		r.routerKitMac.WithMacaroonAuth(rpcCtx), req,
+	)
+	return err
+}
+
+// marshallMissionControlEntries creates an import request for the given
+// mission control entries.
+func marshallMissionControlEntries(entries []MissionControlEntry,
+	force bool) *routerrpc.XImportMissionControlRequest {
+
 	req := &routerrpc.XImportMissionControlRequest{
 		Pairs: make([]*routerrpc.PairHistory, len(entries)),
 		Force: force,
@@ -982,10 +2439,7 @@ func (r *routerClient) ImportMissionControl(ctx context.Context,
 		req.Pairs[i] = rpcEntry
 	}
 
-	_, err := r.client.XImportMissionControl(
-		r.routerKitMac.WithMacaroonAuth(rpcCtx), req,
-	)
-	return err
+	return req
 }
 
 // ResetMissionControl resets the Mission Control state of lnd.
@@ -999,3 +2453,207 @@ func (r *routerClient) ResetMissionControl(ctx context.Context) error {
 	)
 	return err
 }
+
+// ResetMissionControlPairs clears the results that mission control has for
+// the given node pairs, leaving the history of all other pairs untouched.
+// lnd can't delete single pairs, so their results are overwritten with a
+// success of the smallest possible amount and no failure, which carries no
+// information for pathfinding.
+func (r *routerClient) ResetMissionControlPairs(ctx context.Context,
+	pairs []NodePair) error {
+
+	entries, err := resetPairEntries(pairs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// We need to force the import, as the pairs' results may be newer
+	// than the ones we import.
+	return r.ImportMissionControl(ctx, entries, true)
+}
+
+// resetPairEntries creates the mission control entries that overwrite the
+// results of the given pairs. lnd rejects pairs without any result, and a
+// result timestamp without an amount, so we import a 1 msat success.
+func resetPairEntries(pairs []NodePair,
+	now time.Time) ([]MissionControlEntry, error) {
+
+	entries := make([]MissionControlEntry, len(pairs))
+	for i, pair := range pairs {
+		if pair.From == pair.To {
+			return nil, fmt.Errorf("pair %v -> %v: source and "+
+				"destination node must differ", pair.From,
+				pair.To)
+		}
+
+		entries[i] = MissionControlEntry{
+			NodeFrom:    pair.From,
+			NodeTo:      pair.To,
+			SuccessTime: now,
+			SuccessAmt:  1,
+		}
+	}
+
+	return entries, nil
+}
+
+// MissionControlConfig holds the parameters of lnd's mission control, which
+// estimates the probability of successfully routing over a channel from the
+// outcome of past payments.
+type MissionControlConfig struct {
+	// HalfLife is the time after which the penalty of a failed payment
+	// attempt is halved.
+	HalfLife time.Duration
+
+	// HopProbability is the a priori probability of successfully routing
+	// over a channel we have no history for.
+	HopProbability float64
+
+	// Weight is the importance of historical results relative to the a
+	// priori hop probability, between 0 and 1.
+	Weight float64
+
+	// MaximumPaymentResults is the number of payment results that are
+	// kept on disk.
+	MaximumPaymentResults uint32
+
+	// MinimumFailureRelaxInterval is the minimum time that must pass
+	// after a failure before a success with a higher amount is allowed to
+	// relax it.
+	MinimumFailureRelaxInterval time.Duration
+}
+
+// GetMissionControlConfig returns the configuration of lnd's mission control.
+func (r *routerClient) GetMissionControlConfig(ctx context.Context) (
+	*MissionControlConfig, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	resp, err := r.client.GetMissionControlConfig(
+		r.routerKitMac.WithMacaroonAuth(rpcCtx),
+		&routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcCfg := resp.Config
+	if rpcCfg == nil {
+		return nil, errors.New("mission control config missing")
+	}
+
+	relaxInterval := time.Duration(rpcCfg.MinimumFailureRelaxInterval) *
+		time.Second
+
+	return &MissionControlConfig{
+		HalfLife: time.Duration(rpcCfg.HalfLifeSeconds) *
+			time.Second,
+		HopProbability:              float64(rpcCfg.HopProbability),
+		Weight:                      float64(rpcCfg.Weight),
+		MaximumPaymentResults:       rpcCfg.MaximumPaymentResults,
+		MinimumFailureRelaxInterval: relaxInterval,
+	}, nil
+}
+
+// SetMissionControlConfig updates the configuration of lnd's mission control.
+// The new values are applied immediately, without restarting lnd.
+func (r *routerClient) SetMissionControlConfig(ctx context.Context,
+	cfg *MissionControlConfig) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rpcCfg := &routerrpc.MissionControlConfig{
+		HalfLifeSeconds:       uint64(cfg.HalfLife.Seconds()),
+		HopProbability:        float32(cfg.HopProbability),
+		Weight:                float32(cfg.Weight),
+		MaximumPaymentResults: cfg.MaximumPaymentResults,
+		MinimumFailureRelaxInterval: uint64(
+			cfg.MinimumFailureRelaxInterval.Seconds(),
+		),
+	}
+
+	_, err := r.client.SetMissionControlConfig(
+		r.routerKitMac.WithMacaroonAuth(rpcCtx),
+		&routerrpc.SetMissionControlConfigRequest{
+			Config: rpcCfg,
+		},
+	)
+	return err
+}
+
+// ChanStatusAction is the status change requested for a channel.
+type ChanStatusAction uint8
+
+const (
+	// ChanStatusActionEnable enables a channel and keeps it enabled
+	// until it is disabled again manually.
+	ChanStatusActionEnable ChanStatusAction = iota
+
+	// ChanStatusActionDisable disables a channel and keeps it disabled
+	// until it is enabled again manually.
+	ChanStatusActionDisable
+
+	// ChanStatusActionAuto returns the channel to lnd's automatic status
+	// management, which enables and disables it depending on whether the
+	// peer is online.
+	ChanStatusActionAuto
+)
+
+// String returns a human readable representation of the action.
+func (a ChanStatusAction) String() string {
+	switch a {
+	case ChanStatusActionEnable:
+		return "Enable"
+
+	case ChanStatusActionDisable:
+		return "Disable"
+
+	case ChanStatusActionAuto:
+		return "Auto"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// UpdateChanStatus sets the status of a channel that is advertised to the
+// network.
+func (r *routerClient) UpdateChanStatus(ctx context.Context,
+	chanPoint *wire.OutPoint, action ChanStatusAction) error {
+
+	var rpcAction routerrpc.ChanStatusAction
+	switch action {
+	case ChanStatusActionEnable:
+		rpcAction = routerrpc.ChanStatusAction_ENABLE
+
+	case ChanStatusActionDisable:
+		rpcAction = routerrpc.ChanStatusAction_DISABLE
+
+	case ChanStatusActionAuto:
+		rpcAction = routerrpc.ChanStatusAction_AUTO
+
+	default:
+		return fmt.Errorf("unknown channel status action: %v", action)
+	}
+
+	rpcChanPoint := &lnrpc.ChannelPoint{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+			FundingTxidBytes: chanPoint.Hash[:],
+		},
+		OutputIndex: chanPoint.Index,
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.client.UpdateChanStatus(
+		r.routerKitMac.WithMacaroonAuth(rpcCtx),
+		&routerrpc.UpdateChanStatusRequest{
+			ChanPoint: rpcChanPoint,
+			Action:    rpcAction,
+		},
+	)
+	return err
+}
@@ -0,0 +1,875 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validatePairHistory applies the checks that lnd's XImportMissionControl
+// performs on each imported pair.
+func validatePairHistory(pair *routerrpc.PairHistory) error {
+	if bytes.Equal(pair.NodeFrom, pair.NodeTo) {
+		return errors.New("source and destination node must differ")
+	}
+
+	history := pair.History
+	results := []struct {
+		amt       int64
+		timestamp int64
+	}{
+		{history.FailAmtMsat, history.FailTime},
+		{history.SuccessAmtMsat, history.SuccessTime},
+	}
+
+	for _, result := range results {
+		switch {
+		case result.timestamp != 0 && result.amt == 0:
+			return errors.New("non-zero timestamp requires " +
+				"non-zero amount")
+
+		case result.timestamp == 0 && result.amt != 0:
+			return errors.New("non-zero amount requires " +
+				"non-zero timestamp")
+		}
+	}
+
+	if history.FailAmtMsat == 0 && history.SuccessAmtMsat == 0 {
+		return errors.New("either success or failure result required")
+	}
+
+	return nil
+}
+
+// TestResetPairEntries tests that the entries used to reset mission control
+// pairs are accepted by lnd.
+func TestResetPairEntries(t *testing.T) {
+	pairs := []NodePair{
+		{From: route.Vertex{1}, To: route.Vertex{2}},
+		{From: route.Vertex{2}, To: route.Vertex{1}},
+	}
+
+	now := time.Unix(1000, 0)
+	entries, err := resetPairEntries(pairs, now)
+	require.NoError(t, err)
+	require.Len(t, entries, len(pairs))
+
+	req := marshallMissionControlEntries(entries, true)
+	require.True(t, req.Force)
+	require.Len(t, req.Pairs, len(pairs))
+
+	for i, pair := range req.Pairs {
+		require.NoError(t, validatePairHistory(pair))
+
+		require.Equal(t, pairs[i].From[:], pair.NodeFrom)
+		require.Equal(t, pairs[i].To[:], pair.NodeTo)
+		require.Equal(t, now.Unix(), pair.History.SuccessTime)
+		require.Zero(t, pair.History.FailTime)
+		require.Zero(t, pair.History.FailAmtMsat)
+	}
+
+	// A pair from a node to itself is rejected by lnd, so we don't send
+	// it.
+	_, err = resetPairEntries([]NodePair{
+		{From: route.Vertex{1}, To: route.Vertex{1}},
+	}, now)
+	require.Error(t, err)
+}
+
+// errPaymentCaptured is returned by the mock router once it captured a
+// payment request.
+var errPaymentCaptured = errors.New("payment captured")
+
+// mockPaymentStream is a payment update stream that delivers a fixed list of
+// updates and then ends with the given error, or EOF if it is nil.
+type mockPaymentStream struct {
+	grpc.ClientStream
+
+	updates []*lnrpc.Payment
+	err     error
+}
+
+func (m *mockPaymentStream) Recv() (*lnrpc.Payment, error) {
+	if len(m.updates) == 0 {
+		if m.err != nil {
+			return nil, m.err
+		}
+
+		return nil, io.EOF
+	}
+
+	update := m.updates[0]
+	m.updates = m.updates[1:]
+
+	return update, nil
+}
+
+// mockSendPaymentRPC is a router rpc client that records the payment requests
+// it is sent. If no payment updates are set, the payments aren't dispatched.
+type mockSendPaymentRPC struct {
+	routerrpc.RouterClient
+
+	requests []*routerrpc.SendPaymentRequest
+	updates  []*lnrpc.Payment
+}
+
+func (m *mockSendPaymentRPC) SendPaymentV2(_ context.Context,
+	req *routerrpc.SendPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_SendPaymentV2Client, error) {
+
+	m.requests = append(m.requests, req)
+	if m.updates == nil {
+		return nil, errPaymentCaptured
+	}
+
+	return &mockPaymentStream{updates: m.updates}, nil
+}
+
+// TestSendPaymentFeeBudget tests that a fee budget is converted into the fee
+// limit of the payment, and that the payment amount is only required if the
+// budget has a relative cap.
+func TestSendPaymentFeeBudget(t *testing.T) {
+	rpc := &mockSendPaymentRPC{}
+	router := &routerClient{
+		client: rpc,
+		quit:   make(chan struct{}),
+	}
+
+	tests := []struct {
+		name     string
+		amount   btcutil.Amount
+		budget   FeeBudget
+		expected int64
+		err      bool
+	}{
+		{
+			name:     "absolute cap without amount",
+			budget:   FeeBudget{MaxFeeMsat: 5000},
+			expected: 5000,
+		},
+		{
+			name:   "relative cap without amount",
+			budget: FeeBudget{MaxFeePPM: 1000},
+			err:    true,
+		},
+		{
+			name:     "relative cap is lower",
+			amount:   1000,
+			budget:   FeeBudget{MaxFeeMsat: 5000, MaxFeePPM: 1000},
+			expected: 1000,
+		},
+		{
+			name:     "absolute cap is lower",
+			amount:   1000,
+			budget:   FeeBudget{MaxFeeMsat: 500, MaxFeePPM: 1000},
+			expected: 500,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			rpc.requests = nil
+
+			budget := test.budget
+			_, _, err := router.SendPayment(
+				context.Background(), SendPaymentRequest{
+					Amount:      test.amount,
+					PaymentHash: &lntypes.Hash{1},
+					FeeBudget:   &budget,
+				},
+			)
+
+			if test.err {
+				require.Error(t, err)
+				require.NotEqual(t, errPaymentCaptured, err)
+				require.Empty(t, rpc.requests)
+				return
+			}
+
+			require.Equal(t, errPaymentCaptured, err)
+			require.Len(t, rpc.requests, 1)
+			require.Equal(
+				t, test.expected, rpc.requests[0].FeeLimitMsat,
+			)
+		})
+	}
+}
+
+// TestSendToRouteNilRoute tests that sending to a nil route fails without
+// reaching lnd.
+func TestSendToRouteNilRoute(t *testing.T) {
+	router := &routerClient{
+		client: &mockSendPaymentRPC{},
+		quit:   make(chan struct{}),
+	}
+
+	_, err := router.SendToRoute(context.Background(), lntypes.Hash{1}, nil)
+	require.Error(t, err)
+}
+
+// newTestRoute creates an rpc route with the given number of hops.
+func newTestRoute(numHops int) *lnrpc.Route {
+	rpcRoute := &lnrpc.Route{
+		TotalTimeLock: 500,
+		TotalAmtMsat:  1000000 + int64(numHops)*1000,
+	}
+
+	for i := 0; i < numHops; i++ {
+		rpcRoute.Hops = append(rpcRoute.Hops, &lnrpc.Hop{
+			ChanId:           uint64(i + 1),
+			AmtToForwardMsat: 1000000,
+			PubKey:           route.Vertex{byte(i + 1)}.String(),
+		})
+	}
+
+	return rpcRoute
+}
+
+// TestProbe tests that a probe only succeeds if an attempt was failed with
+// incorrect payment details by the final hop of its route, and that the fee of
+// the probed routes isn't limited by default.
+func TestProbe(t *testing.T) {
+	unknownDetails := lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS
+
+	// The first attempt was failed by an intermediate hop that doesn't
+	// know the hash, the second one by the destination.
+	payment := &lnrpc.Payment{
+		PaymentHash: lntypes.Hash{1}.String(),
+		Status:      lnrpc.Payment_FAILED,
+		Htlcs: []*lnrpc.HTLCAttempt{
+			{
+				Status: lnrpc.HTLCAttempt_FAILED,
+				Route:  newTestRoute(3),
+				Failure: &lnrpc.Failure{
+					Code:               unknownDetails,
+					FailureSourceIndex: 1,
+				},
+			},
+			{
+				Status:        lnrpc.HTLCAttempt_FAILED,
+				Route:         newTestRoute(2),
+				AttemptTimeNs: 1000,
+				ResolveTimeNs: 3000,
+				Failure: &lnrpc.Failure{
+					Code:               unknownDetails,
+					FailureSourceIndex: 2,
+				},
+			},
+		},
+	}
+
+	rpc := &mockSendPaymentRPC{
+		updates: []*lnrpc.Payment{payment},
+	}
+	router := &routerClient{
+		client: rpc,
+		quit:   make(chan struct{}),
+	}
+
+	result, err := router.Probe(context.Background(), route.Vertex{2}, 1000)
+	require.NoError(t, err)
+	require.Len(t, result.Route.Hops, 2)
+	require.EqualValues(t, 2000, result.Fee)
+	require.EqualValues(t, 500, result.TimeLock)
+	require.Equal(t, 2*time.Microsecond, result.Latency)
+
+	require.Len(t, rpc.requests, 1)
+	require.EqualValues(t, math.MaxInt64, rpc.requests[0].FeeLimitMsat)
+	require.Zero(t, rpc.requests[0].FeeLimitSat)
+
+	// Without an attempt that reached the destination, the probe fails.
+	payment.Htlcs = payment.Htlcs[:1]
+	rpc.requests = nil
+	rpc.updates = []*lnrpc.Payment{payment}
+
+	_, err = router.Probe(
+		context.Background(), route.Vertex{2}, 1000,
+		WithProbeMaxFee(10),
+	)
+	var probeErr *ProbeError
+	require.True(t, errors.As(err, &probeErr))
+
+	require.Len(t, rpc.requests, 1)
+	require.EqualValues(t, 10, rpc.requests[0].FeeLimitSat)
+	require.Zero(t, rpc.requests[0].FeeLimitMsat)
+}
+
+// holdHtlcsHarness runs HoldHtlcs on a router backed by a mock rpc client and
+// hands all held htlcs to the test.
+type holdHtlcsHarness struct {
+	router  *routerClient
+	rpc     *mockInterceptorRPC
+	handles chan *HeldHtlc
+	result  chan error
+}
+
+// newHoldHtlcsHarness starts holding htlcs with the given hold timeout. The
+// handler blocks until its context is canceled.
+func newHoldHtlcsHarness(ctx context.Context,
+	holdTimeout time.Duration) *holdHtlcsHarness {
+
+	h := &holdHtlcsHarness{
+		rpc: &mockInterceptorRPC{
+			streams: make(chan *mockInterceptStream, 10),
+		},
+		handles: make(chan *HeldHtlc, 10),
+		result:  make(chan error, 1),
+	}
+	h.router = &routerClient{
+		client: h.rpc,
+		quit:   make(chan struct{}),
+	}
+
+	handler := func(ctx context.Context, htlc *HeldHtlc) {
+		h.handles <- htlc
+		<-ctx.Done()
+	}
+
+	go func() {
+		h.result <- h.router.HoldHtlcs(ctx, handler, holdTimeout)
+	}()
+
+	return h
+}
+
+func (h *holdHtlcsHarness) nextHandle(t *testing.T) *HeldHtlc {
+	select {
+	case htlc := <-h.handles:
+		return htlc
+
+	case <-time.After(testTimeout):
+		t.Fatal("htlc not handed to handler")
+		return nil
+	}
+}
+
+func (h *holdHtlcsHarness) waitForResult(t *testing.T) error {
+	select {
+	case err := <-h.result:
+		return err
+
+	case <-time.After(testTimeout):
+		t.Fatal("HoldHtlcs didn't return")
+		return nil
+	}
+}
+
+// waitForFinished asserts that the router shuts down, which requires all
+// handlers to have returned.
+func (h *holdHtlcsHarness) waitForFinished(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		h.router.WaitForFinished()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("router didn't shut down")
+	}
+}
+
+// TestHoldHtlcsTimeout tests that an htlc that isn't resolved within the hold
+// timeout is failed back.
+func TestHoldHtlcsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := newHoldHtlcsHarness(ctx, 10*time.Millisecond)
+
+	stream := h.rpc.nextStream(t)
+	stream.intercept(t, 1)
+	heldHtlc := h.nextHandle(t)
+
+	resp := stream.nextResponse(t)
+	require.EqualValues(t, 1, resp.IncomingCircuitKey.HtlcId)
+	require.Equal(t, routerrpc.ResolveHoldForwardAction_FAIL, resp.Action)
+
+	// The handle was used up by the timeout.
+	require.Equal(t, ErrHtlcAlreadyResolved, heldHtlc.Resume())
+
+	cancel()
+	require.Equal(t, context.Canceled, h.waitForResult(t))
+	h.waitForFinished(t)
+}
+
+// TestHoldHtlcsFailAllOnCancel tests that all htlcs that are still held are
+// failed back once the context is canceled, and that resolved htlcs aren't.
+func TestHoldHtlcsFailAllOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := newHoldHtlcsHarness(ctx, 0)
+
+	stream := h.rpc.nextStream(t)
+	for htlcID := uint64(1); htlcID <= 3; htlcID++ {
+		stream.intercept(t, htlcID)
+	}
+
+	handles := make(map[uint64]*HeldHtlc)
+	for i := 0; i < 3; i++ {
+		heldHtlc := h.nextHandle(t)
+		handles[heldHtlc.IncomingCircuitKey.HtlcID] = heldHtlc
+	}
+
+	require.NoError(t, handles[2].Resume())
+	resp := stream.nextResponse(t)
+	require.EqualValues(t, 2, resp.IncomingCircuitKey.HtlcId)
+	require.Equal(
+		t, routerrpc.ResolveHoldForwardAction_RESUME, resp.Action,
+	)
+
+	cancel()
+	require.Equal(t, context.Canceled, h.waitForResult(t))
+
+	failed := make(map[uint64]bool)
+	for i := 0; i < 2; i++ {
+		resp := stream.nextResponse(t)
+		require.Equal(
+			t, routerrpc.ResolveHoldForwardAction_FAIL,
+			resp.Action,
+		)
+		failed[resp.IncomingCircuitKey.HtlcId] = true
+	}
+	require.Equal(t, map[uint64]bool{1: true, 3: true}, failed)
+	require.Empty(t, stream.responses)
+
+	h.waitForFinished(t)
+}
+
+// TestHoldHtlcsStreamFailure tests that the handlers are released when the
+// interception stream fails while the caller's context is still live, and
+// that no htlc is failed back on the dead stream after the hold timeout.
+func TestHoldHtlcsStreamFailure(t *testing.T) {
+	const holdTimeout = 100 * time.Millisecond
+
+	h := newHoldHtlcsHarness(context.Background(), holdTimeout)
+
+	stream := h.rpc.nextStream(t)
+	stream.intercept(t, 1)
+	h.nextHandle(t)
+
+	streamErr := errors.New("stream failed")
+	stream.errs <- streamErr
+	require.Equal(t, streamErr, h.waitForResult(t))
+
+	// The handler waits for its context, so the router can only shut
+	// down if the handler was released.
+	h.waitForFinished(t)
+
+	// The htlc isn't failed back once its hold timeout expires.
+	time.Sleep(2 * holdTimeout)
+	require.Zero(t, atomic.LoadInt32(&stream.sendAttempts))
+}
+
+// mockAttachRPC is a router rpc client that serves the given payment streams
+// in order for calls to TrackPaymentV2 and SendPaymentV2.
+type mockAttachRPC struct {
+	routerrpc.RouterClient
+
+	trackStreams []*mockPaymentStream
+	sendStreams  []*mockPaymentStream
+
+	numTracked int
+	numSent    int
+}
+
+func (m *mockAttachRPC) TrackPaymentV2(context.Context,
+	*routerrpc.TrackPaymentRequest, ...grpc.CallOption) (
+	routerrpc.Router_TrackPaymentV2Client, error) {
+
+	stream := m.trackStreams[m.numTracked]
+	m.numTracked++
+
+	return stream, nil
+}
+
+func (m *mockAttachRPC) SendPaymentV2(context.Context,
+	*routerrpc.SendPaymentRequest, ...grpc.CallOption) (
+	routerrpc.Router_SendPaymentV2Client, error) {
+
+	stream := m.sendStreams[m.numSent]
+	m.numSent++
+
+	return stream, nil
+}
+
+// copyPaymentStreams copies the given streams, so that they can be consumed
+// without affecting the originals.
+func copyPaymentStreams(
+	streams []*mockPaymentStream) []*mockPaymentStream {
+
+	copies := make([]*mockPaymentStream, len(streams))
+	for i, stream := range streams {
+		streamCopy := *stream
+		copies[i] = &streamCopy
+	}
+
+	return copies
+}
+
+// TestAttachToExisting tests that a payment with AttachToExisting set is only
+// sent if lnd doesn't know it or it already failed, and that it is attached
+// to if it was started concurrently.
+func TestAttachToExisting(t *testing.T) {
+	hash := lntypes.Hash{1}
+	payment := func(state lnrpc.Payment_PaymentStatus) *lnrpc.Payment {
+		return &lnrpc.Payment{
+			PaymentHash:     hash.String(),
+			PaymentPreimage: lntypes.Preimage{2}.String(),
+			Status:          state,
+		}
+	}
+
+	var (
+		inFlight  = payment(lnrpc.Payment_IN_FLIGHT)
+		succeeded = payment(lnrpc.Payment_SUCCEEDED)
+		failed    = payment(lnrpc.Payment_FAILED)
+
+		notFound = &mockPaymentStream{
+			err: status.Error(codes.NotFound, "not found"),
+		}
+		alreadyExists = &mockPaymentStream{
+			err: status.Error(codes.AlreadyExists, "exists"),
+		}
+		sendErr = errors.New("send failed")
+	)
+
+	tests := []struct {
+		name         string
+		trackStreams []*mockPaymentStream
+		sendStreams  []*mockPaymentStream
+
+		states     []lnrpc.Payment_PaymentStatus
+		err        error
+		numTracked int
+		numSent    int
+	}{
+		{
+			name:         "unknown payment is sent",
+			trackStreams: []*mockPaymentStream{notFound},
+			sendStreams: []*mockPaymentStream{{
+				updates: []*lnrpc.Payment{inFlight, succeeded},
+			}},
+			states: []lnrpc.Payment_PaymentStatus{
+				lnrpc.Payment_IN_FLIGHT,
+				lnrpc.Payment_SUCCEEDED,
+			},
+			numTracked: 1,
+			numSent:    1,
+		},
+		{
+			name: "in flight payment is attached to",
+			trackStreams: []*mockPaymentStream{{
+				updates: []*lnrpc.Payment{inFlight, succeeded},
+			}},
+			states: []lnrpc.Payment_PaymentStatus{
+				lnrpc.Payment_IN_FLIGHT,
+				lnrpc.Payment_SUCCEEDED,
+			},
+			numTracked: 1,
+		},
+		{
+			name: "succeeded payment is attached to",
+			trackStreams: []*mockPaymentStream{{
+				updates: []*lnrpc.Payment{succeeded},
+			}},
+			states: []lnrpc.Payment_PaymentStatus{
+				lnrpc.Payment_SUCCEEDED,
+			},
+			numTracked: 1,
+		},
+		{
+			name: "failed payment is sent again",
+			trackStreams: []*mockPaymentStream{{
+				updates: []*lnrpc.Payment{failed},
+			}},
+			sendStreams: []*mockPaymentStream{{
+				updates: []*lnrpc.Payment{succeeded},
+			}},
+			states: []lnrpc.Payment_PaymentStatus{
+				lnrpc.Payment_SUCCEEDED,
+			},
+			numTracked: 1,
+			numSent:    1,
+		},
+		{
+			name: "concurrently started payment is attached to",
+			trackStreams: []*mockPaymentStream{
+				notFound, {
+					updates: []*lnrpc.Payment{succeeded},
+				},
+			},
+			sendStreams: []*mockPaymentStream{alreadyExists},
+			states: []lnrpc.Payment_PaymentStatus{
+				lnrpc.Payment_SUCCEEDED,
+			},
+			numTracked: 2,
+			numSent:    1,
+		},
+		{
+			name:         "send error is delivered",
+			trackStreams: []*mockPaymentStream{notFound},
+			sendStreams: []*mockPaymentStream{{
+				err: sendErr,
+			}},
+			err:        sendErr,
+			numTracked: 1,
+			numSent:    1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			rpc := &mockAttachRPC{
+				trackStreams: copyPaymentStreams(
+					test.trackStreams,
+				),
+				sendStreams: copyPaymentStreams(
+					test.sendStreams,
+				),
+			}
+			router := &routerClient{
+				client: rpc,
+				quit:   make(chan struct{}),
+			}
+
+			statusChan, errChan, err := router.SendPayment(
+				context.Background(), SendPaymentRequest{
+					PaymentHash:      &hash,
+					AttachToExisting: true,
+				},
+			)
+			require.NoError(t, err)
+
+			var states []lnrpc.Payment_PaymentStatus
+		loop:
+			for {
+				select {
+				case update, ok := <-statusChan:
+					if !ok {
+						break loop
+					}
+					states = append(states, update.State)
+
+				case err := <-errChan:
+					require.Equal(t, test.err, err)
+					break loop
+
+				case <-time.After(testTimeout):
+					t.Fatal("payment not finished")
+				}
+			}
+
+			require.Equal(t, test.states, states)
+			require.Equal(t, test.numTracked, rpc.numTracked)
+			require.Equal(t, test.numSent, rpc.numSent)
+		})
+	}
+}
+
+// TestSendKeysendFeeLimit tests that keysend payments have a default fee limit
+// that can be replaced with an option.
+func TestSendKeysendFeeLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       btcutil.Amount
+		opts         []KeysendOption
+		feeLimitMsat int64
+		feeLimitSat  int64
+	}{
+		{
+			name:         "relative default",
+			amount:       1000,
+			feeLimitMsat: 50000,
+		},
+		{
+			name:         "minimum default",
+			amount:       10,
+			feeLimitMsat: 10000,
+		},
+		{
+			name:        "option",
+			amount:      1000,
+			opts:        []KeysendOption{WithKeysendMaxFee(3)},
+			feeLimitSat: 3,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			rpc := &mockSendPaymentRPC{}
+			router := &routerClient{
+				client: rpc,
+				quit:   make(chan struct{}),
+			}
+
+			_, err := router.SendKeysend(
+				context.Background(), route.Vertex{1},
+				test.amount, test.opts...,
+			)
+			require.Equal(t, errPaymentCaptured, err)
+
+			require.Len(t, rpc.requests, 1)
+			require.Equal(
+				t, test.feeLimitMsat,
+				rpc.requests[0].FeeLimitMsat,
+			)
+			require.Equal(
+				t, test.feeLimitSat,
+				rpc.requests[0].FeeLimitSat,
+			)
+		})
+	}
+}
+
+// newTestInvoice encodes an invoice for the given payment hash that is signed
+// by the given key and carries a route hint.
+func newTestInvoice(t *testing.T, key *btcec.PrivateKey, hash lntypes.Hash,
+	options ...func(*zpay32.Invoice)) string {
+
+	options = append(
+		options, zpay32.Description("test"), zpay32.CLTVExpiry(40),
+		zpay32.PaymentAddr([32]byte{2}),
+		zpay32.RouteHint([]zpay32.HopHint{{
+			NodeID:    key.PubKey(),
+			ChannelID: 1,
+		}}),
+	)
+
+	invoice, err := zpay32.NewInvoice(
+		&chaincfg.RegressionNetParams, hash, time.Now(), options...,
+	)
+	require.NoError(t, err)
+
+	encoded, err := invoice.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(btcec.S256(), key, hash, true)
+		},
+	})
+	require.NoError(t, err)
+
+	return encoded
+}
+
+// TestSendPaymentIgnoreInvoiceRouteHints tests that an invoice paid without
+// its route hints is replaced with the payment parameters it contains, and
+// that invoices whose parameters can't be applied are rejected.
+func TestSendPaymentIgnoreInvoiceRouteHints(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+
+	hash := lntypes.Hash{1}
+	ampFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.TLVOnionPayloadOptional,
+			lnwire.PaymentAddrOptional, lnwire.AMPOptional,
+		), lnwire.Features,
+	)
+
+	tests := []struct {
+		name    string
+		amount  btcutil.Amount
+		options []func(*zpay32.Invoice)
+		amtMsat int64
+		amt     int64
+		err     bool
+	}{
+		{
+			name: "invoice amount",
+			options: []func(*zpay32.Invoice){
+				zpay32.Amount(1000000),
+			},
+			amtMsat: 1000000,
+		},
+		{
+			name:   "request amount",
+			amount: 1000,
+			amt:    1000,
+		},
+		{
+			name:   "both amounts",
+			amount: 1000,
+			options: []func(*zpay32.Invoice){
+				zpay32.Amount(1000000),
+			},
+			err: true,
+		},
+		{
+			name: "amp",
+			options: []func(*zpay32.Invoice){
+				zpay32.Amount(1000000),
+				zpay32.Features(ampFeatures),
+			},
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			rpc := &mockSendPaymentRPC{}
+			router := &routerClient{
+				client: rpc,
+				params: &chaincfg.RegressionNetParams,
+				quit:   make(chan struct{}),
+			}
+
+			invoice := newTestInvoice(t, key, hash, test.options...)
+			_, _, err := router.SendPayment(
+				context.Background(), SendPaymentRequest{
+					Invoice:                 invoice,
+					Amount:                  test.amount,
+					IgnoreInvoiceRouteHints: true,
+				},
+			)
+			if test.err {
+				require.NotEqual(t, errPaymentCaptured, err)
+				require.Error(t, err)
+				require.Empty(t, rpc.requests)
+				return
+			}
+			require.Equal(t, errPaymentCaptured, err)
+
+			require.Len(t, rpc.requests, 1)
+			req := rpc.requests[0]
+			require.Empty(t, req.PaymentRequest)
+			require.Empty(t, req.RouteHints)
+			require.Equal(
+				t, key.PubKey().SerializeCompressed(), req.Dest,
+			)
+			require.Equal(t, hash[:], req.PaymentHash)
+			require.EqualValues(t, 40, req.FinalCltvDelta)
+			require.Equal(t, []byte{2}, req.PaymentAddr[:1])
+			require.Equal(t, test.amtMsat, req.AmtMsat)
+			require.Equal(t, test.amt, req.Amt)
+		})
+	}
+}
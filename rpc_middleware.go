@@ -0,0 +1,372 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// InterceptType is the type of a message intercepted by an RPC middleware.
+type InterceptType uint8
+
+const (
+	// InterceptTypeStreamAuth indicates that a client is about to open a
+	// streaming RPC. Only the macaroon and method of the stream are known
+	// at this point.
+	InterceptTypeStreamAuth InterceptType = iota
+
+	// InterceptTypeRequest indicates that a request message of a client is
+	// intercepted before it is processed by lnd.
+	InterceptTypeRequest
+
+	// InterceptTypeResponse indicates that a response message of lnd is
+	// intercepted before it is sent to the client.
+	InterceptTypeResponse
+)
+
+// String returns a human readable representation of the intercept type.
+func (t InterceptType) String() string {
+	switch t {
+	case InterceptTypeStreamAuth:
+		return "StreamAuth"
+
+	case InterceptTypeRequest:
+		return "Request"
+
+	case InterceptTypeResponse:
+		return "Response"
+
+	default:
+		return "Unknown"
+	}
+}
+
+// InterceptRequest is a single message intercepted by an RPC middleware.
+type InterceptRequest struct {
+	// RequestID is the ID of the client request the message belongs to.
+	// All messages of the same stream share the same request ID.
+	RequestID uint64
+
+	// RawMacaroon is the macaroon the client used for the request.
+	RawMacaroon []byte
+
+	// CustomCaveatCondition is the condition of the middleware's custom
+	// caveat, if the macaroon contains one.
+	CustomCaveatCondition string
+
+	// Type is the type of the intercepted message.
+	Type InterceptType
+
+	// FullURI is the full URI of the RPC method, for example
+	// /lnrpc.Lightning/GetInfo.
+	FullURI string
+
+	// StreamRPC indicates whether the method is a streaming RPC. It is
+	// not set for stream auth messages.
+	StreamRPC bool
+
+	// TypeName is the full name of the protobuf type of the serialized
+	// message. It is not set for stream auth messages.
+	TypeName string
+
+	// Serialized is the protobuf serialized message. It is not set for
+	// stream auth messages.
+	Serialized []byte
+}
+
+// InterceptDecision is the decision of an RPC middleware about an intercepted
+// message.
+type InterceptDecision uint8
+
+const (
+	// InterceptAccept lets the intercepted message pass unchanged.
+	InterceptAccept InterceptDecision = iota
+
+	// InterceptDeny rejects the intercepted message. The client receives
+	// the error of the intercept response instead.
+	InterceptDeny
+
+	// InterceptReplace replaces the intercepted message with the
+	// replacement of the intercept response. Only response messages can
+	// be replaced.
+	InterceptReplace
+)
+
+// InterceptResponse is the answer of an RPC middleware to an intercepted
+// message.
+type InterceptResponse struct {
+	// Decision is what should happen to the intercepted message.
+	Decision InterceptDecision
+
+	// Error is the error returned to the client if the message is denied.
+	Error string
+
+	// Replacement is the protobuf serialized message that replaces the
+	// intercepted message. It must be of the same type as the intercepted
+	// message.
+	Replacement []byte
+}
+
+// MiddlewareHandler is the function an RPC middleware uses to decide about
+// intercepted messages. If it returns an error, the message is denied with
+// that error. A nil response accepts the message.
+type MiddlewareHandler func(context.Context, *InterceptRequest) (
+	*InterceptResponse, error)
+
+// defaultMiddlewareTimeout is the default time the handler of an RPC
+// middleware has to decide about a message.
+const defaultMiddlewareTimeout = 2 * time.Second
+
+// RPCMiddlewareConfig holds the configuration of an RPCMiddleware.
+type RPCMiddlewareConfig struct {
+	// Client is the client used to register the middleware with lnd.
+	Client LightningClient
+
+	// Name is the unique name of the middleware.
+	Name string
+
+	// CustomCaveatName is the name of the custom macaroon caveat the
+	// middleware is responsible for. Requests with macaroons that contain
+	// the caveat are intercepted. It must be empty for read-only
+	// middlewares.
+	CustomCaveatName string
+
+	// ReadOnly registers the middleware in read-only mode, which
+	// intercepts all requests but can't deny or replace them.
+	ReadOnly bool
+
+	// Timeout is the time the handler has to decide about a message. If
+	// zero, a default is used.
+	Timeout time.Duration
+
+	// Handler decides about all intercepted messages.
+	Handler MiddlewareHandler
+
+	// InitialBackoff is the time to wait before re-registering the
+	// middleware after its stream failed. If zero, a default is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper bound of the time to wait between attempts
+	// to re-register. If zero, a default is used.
+	MaxBackoff time.Duration
+}
+
+// RPCMiddleware keeps an RPC middleware registered with lnd. If the
+// interception stream fails, for example because lnd restarted, the
+// middleware is registered again with an exponential backoff until it is
+// stopped.
+type RPCMiddleware struct {
+	cfg *RPCMiddlewareConfig
+
+	cancel context.CancelFunc
+	mu     sync.Mutex
+
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewRPCMiddleware creates a new RPC middleware. Start must be called to
+// register it with lnd.
+func NewRPCMiddleware(cfg *RPCMiddlewareConfig) *RPCMiddleware {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultMiddlewareTimeout
+	}
+
+	return &RPCMiddleware{
+		cfg: cfg,
+	}
+}
+
+// Start registers the middleware with lnd. An error is returned if the
+// initial registration fails. After that, the middleware is kept registered
+// until Stop is called or the given context is canceled.
+func (m *RPCMiddleware) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return errors.New("rpc middleware already started")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	errChan, err := m.register(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.run(ctx, errChan)
+
+	return nil
+}
+
+// Stop deregisters the middleware and waits for its goroutine to exit.
+func (m *RPCMiddleware) Stop() {
+	m.stopOnce.Do(func() {
+		m.mu.Lock()
+		if m.cancel != nil {
+			m.cancel()
+		}
+		m.mu.Unlock()
+
+		m.wg.Wait()
+	})
+}
+
+// register registers the middleware with lnd once.
+func (m *RPCMiddleware) register(ctx context.Context) (chan error, error) {
+	return m.cfg.Client.RegisterRPCMiddleware(
+		ctx, m.cfg.Name, m.cfg.CustomCaveatName, m.cfg.ReadOnly,
+		m.cfg.Timeout, m.intercept,
+	)
+}
+
+// run waits for the interception stream to fail and registers the middleware
+// again until the context is canceled.
+func (m *RPCMiddleware) run(ctx context.Context, errChan chan error) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case err := <-errChan:
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Warnf("RPC middleware %v failed, "+
+				"re-registering: %v", m.cfg.Name, err)
+
+		case <-ctx.Done():
+			return
+		}
+
+		var err error
+		errChan, err = m.reRegister(ctx)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// reRegister tries to register the middleware with an exponential backoff
+// until it succeeds or the context is canceled.
+func (m *RPCMiddleware) reRegister(ctx context.Context) (chan error, error) {
+	b := newBackoff(m.cfg.InitialBackoff, m.cfg.MaxBackoff)
+	for {
+		if !b.wait(ctx) {
+			return nil, ctx.Err()
+		}
+
+		errChan, err := m.register(ctx)
+		if err == nil {
+			return errChan, nil
+		}
+
+		log.Warnf("Unable to re-register RPC middleware %v, retrying "+
+			"in %v: %v", m.cfg.Name, b.next, err)
+	}
+}
+
+// intercept translates an intercepted rpc message for the handler and its
+// decision back into an rpc response.
+func (m *RPCMiddleware) intercept(ctx context.Context,
+	rpcReq *lnrpc.RPCMiddlewareRequest) (*lnrpc.RPCMiddlewareResponse,
+	error) {
+
+	rpcResp := &lnrpc.RPCMiddlewareResponse{
+		RefMsgId: rpcReq.MsgId,
+	}
+
+	// We fail closed: a message we can't parse, a handler error or an
+	// invalid decision denies the intercepted message. Returning an error
+	// instead would tear down the interception stream.
+	req, err := unmarshallInterceptRequest(rpcReq)
+	if err != nil {
+		rpcResp.MiddlewareMessage = denyFeedback(err.Error())
+		return rpcResp, nil
+	}
+
+	feedback := &lnrpc.InterceptFeedback{}
+	resp, err := m.cfg.Handler(ctx, req)
+	switch {
+	case err != nil:
+		feedback.Error = err.Error()
+
+	case resp == nil || resp.Decision == InterceptAccept:
+
+	case resp.Decision == InterceptDeny:
+		feedback.Error = resp.Error
+
+	case resp.Decision == InterceptReplace &&
+		req.Type == InterceptTypeResponse:
+
+		feedback.ReplaceResponse = true
+		feedback.ReplacementSerialized = resp.Replacement
+
+	default:
+		feedback.Error = fmt.Sprintf("invalid middleware decision %v "+
+			"for message of type %v", resp.Decision, req.Type)
+	}
+
+	rpcResp.MiddlewareMessage = &lnrpc.RPCMiddlewareResponse_Feedback{
+		Feedback: feedback,
+	}
+
+	return rpcResp, nil
+}
+
+// denyFeedback returns the feedback that denies an intercepted message with
+// the given error.
+func denyFeedback(errMsg string) *lnrpc.RPCMiddlewareResponse_Feedback {
+	return &lnrpc.RPCMiddlewareResponse_Feedback{
+		Feedback: &lnrpc.InterceptFeedback{
+			Error: errMsg,
+		},
+	}
+}
+
+// unmarshallInterceptRequest converts an rpc middleware request to an
+// InterceptRequest.
+func unmarshallInterceptRequest(rpcReq *lnrpc.RPCMiddlewareRequest) (
+	*InterceptRequest, error) {
+
+	req := &InterceptRequest{
+		RequestID:             rpcReq.RequestId,
+		RawMacaroon:           rpcReq.RawMacaroon,
+		CustomCaveatCondition: rpcReq.CustomCaveatCondition,
+	}
+
+	var msg *lnrpc.RPCMessage
+	switch intercept := rpcReq.InterceptType.(type) {
+	case *lnrpc.RPCMiddlewareRequest_StreamAuth:
+		req.Type = InterceptTypeStreamAuth
+		req.FullURI = intercept.StreamAuth.MethodFullUri
+
+		return req, nil
+
+	case *lnrpc.RPCMiddlewareRequest_Request:
+		req.Type = InterceptTypeRequest
+		msg = intercept.Request
+
+	case *lnrpc.RPCMiddlewareRequest_Response:
+		req.Type = InterceptTypeResponse
+		msg = intercept.Response
+
+	default:
+		return nil, fmt.Errorf("unknown intercept type: %T",
+			rpcReq.InterceptType)
+	}
+
+	req.FullURI = msg.MethodFullUri
+	req.StreamRPC = msg.StreamRpc
+	req.TypeName = msg.TypeName
+	req.Serialized = msg.Serialized
+
+	return req, nil
+}
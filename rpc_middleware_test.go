@@ -0,0 +1,247 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMiddlewareRequest creates an intercepted request or response message.
+func newTestMiddlewareRequest(response bool) *lnrpc.RPCMiddlewareRequest {
+	msg := &lnrpc.RPCMessage{
+		MethodFullUri: "/lnrpc.Lightning/GetInfo",
+	}
+
+	if response {
+		return &lnrpc.RPCMiddlewareRequest{
+			MsgId: 7,
+			InterceptType: &lnrpc.RPCMiddlewareRequest_Response{
+				Response: msg,
+			},
+		}
+	}
+
+	return &lnrpc.RPCMiddlewareRequest{
+		MsgId: 7,
+		InterceptType: &lnrpc.RPCMiddlewareRequest_Request{
+			Request: msg,
+		},
+	}
+}
+
+// TestRPCMiddlewareIntercept tests that the decisions of the handler are
+// translated into the right feedback, and that everything that isn't a valid
+// decision denies the intercepted message.
+func TestRPCMiddlewareIntercept(t *testing.T) {
+	tests := []struct {
+		name     string
+		response bool
+		resp     *InterceptResponse
+		err      error
+		expected *lnrpc.InterceptFeedback
+	}{
+		{
+			name:     "nil response accepts",
+			expected: &lnrpc.InterceptFeedback{},
+		},
+		{
+			name: "accept",
+			resp: &InterceptResponse{
+				Decision: InterceptAccept,
+			},
+			expected: &lnrpc.InterceptFeedback{},
+		},
+		{
+			name: "deny",
+			resp: &InterceptResponse{
+				Decision: InterceptDeny,
+				Error:    "denied",
+			},
+			expected: &lnrpc.InterceptFeedback{
+				Error: "denied",
+			},
+		},
+		{
+			name:     "replace response",
+			response: true,
+			resp: &InterceptResponse{
+				Decision:    InterceptReplace,
+				Replacement: []byte{1, 2, 3},
+			},
+			expected: &lnrpc.InterceptFeedback{
+				ReplaceResponse:       true,
+				ReplacementSerialized: []byte{1, 2, 3},
+			},
+		},
+		{
+			name: "replace request denies",
+			resp: &InterceptResponse{
+				Decision:    InterceptReplace,
+				Replacement: []byte{1, 2, 3},
+			},
+			expected: &lnrpc.InterceptFeedback{
+				Error: "invalid middleware decision 2 for " +
+					"message of type Request",
+			},
+		},
+		{
+			name: "unknown decision denies",
+			resp: &InterceptResponse{
+				Decision: 99,
+			},
+			expected: &lnrpc.InterceptFeedback{
+				Error: "invalid middleware decision 99 for " +
+					"message of type Request",
+			},
+		},
+		{
+			name: "handler error denies",
+			err:  errors.New("handler failed"),
+			expected: &lnrpc.InterceptFeedback{
+				Error: "handler failed",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			m := NewRPCMiddleware(&RPCMiddlewareConfig{
+				Handler: func(context.Context,
+					*InterceptRequest) (*InterceptResponse,
+					error) {
+
+					return test.resp, test.err
+				},
+			})
+
+			rpcReq := newTestMiddlewareRequest(test.response)
+			rpcResp, err := m.intercept(
+				context.Background(), rpcReq,
+			)
+			require.NoError(t, err)
+			require.Equal(t, uint64(7), rpcResp.RefMsgId)
+			require.Equal(t, test.expected, rpcResp.GetFeedback())
+		})
+	}
+}
+
+// TestRPCMiddlewareUnknownIntercept tests that a message of an unknown
+// intercept type is denied without calling the handler, instead of tearing
+// down the interception stream.
+func TestRPCMiddlewareUnknownIntercept(t *testing.T) {
+	m := NewRPCMiddleware(&RPCMiddlewareConfig{
+		Handler: func(context.Context,
+			*InterceptRequest) (*InterceptResponse, error) {
+
+			t.Fatal("handler called")
+			return nil, nil
+		},
+	})
+
+	rpcResp, err := m.intercept(
+		context.Background(), &lnrpc.RPCMiddlewareRequest{
+			MsgId: 3,
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), rpcResp.RefMsgId)
+
+	feedback := rpcResp.GetFeedback()
+	require.NotNil(t, feedback)
+	require.NotEmpty(t, feedback.Error)
+	require.False(t, feedback.ReplaceResponse)
+}
+
+// mockMiddlewareClient is a lightning client whose middleware registrations
+// fail a given number of times before they succeed.
+type mockMiddlewareClient struct {
+	LightningClient
+
+	failures int
+
+	attempts []time.Time
+	timeout  time.Duration
+	errChans chan chan error
+	mu       sync.Mutex
+}
+
+func (m *mockMiddlewareClient) RegisterRPCMiddleware(_ context.Context,
+	_, _ string, _ bool, timeout time.Duration, _ InterceptFunction) (
+	chan error, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attempts = append(m.attempts, time.Now())
+	m.timeout = timeout
+
+	// The initial registration always succeeds.
+	if len(m.attempts) > 1 && m.failures > 0 {
+		m.failures--
+		return nil, errors.New("registration failed")
+	}
+
+	errChan := make(chan error, 1)
+	m.errChans <- errChan
+
+	return errChan, nil
+}
+
+// TestRPCMiddlewareReRegister tests that the middleware is registered again
+// with an exponential backoff after its stream failed.
+func TestRPCMiddlewareReRegister(t *testing.T) {
+	const (
+		initialBackoff = 20 * time.Millisecond
+		maxBackoff     = 50 * time.Millisecond
+	)
+
+	client := &mockMiddlewareClient{
+		failures: 3,
+		errChans: make(chan chan error, 2),
+	}
+	m := NewRPCMiddleware(&RPCMiddlewareConfig{
+		Client:         client,
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+	})
+	require.NoError(t, m.Start(context.Background()))
+	defer m.Stop()
+
+	errChan := <-client.errChans
+	errChan <- errors.New("stream failed")
+
+	select {
+	case <-client.errChans:
+	case <-time.After(testTimeout):
+		t.Fatal("middleware not registered again")
+	}
+
+	client.mu.Lock()
+	attempts := client.attempts
+	timeout := client.timeout
+	client.mu.Unlock()
+
+	// Without a configured timeout, handlers get the default one instead
+	// of an expired context.
+	require.Equal(t, defaultMiddlewareTimeout, timeout)
+
+	// The initial registration, three failed attempts and the successful
+	// one. The wait time doubles after each failure, up to the maximum.
+	require.Len(t, attempts, 5)
+	expectedBackoffs := []time.Duration{
+		2 * initialBackoff, maxBackoff, maxBackoff,
+	}
+	for i, backoff := range expectedBackoffs {
+		require.GreaterOrEqual(
+			t, int64(attempts[i+2].Sub(attempts[i+1])),
+			int64(backoff),
+		)
+	}
+}
@@ -2,11 +2,14 @@ package lndclient
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
@@ -24,8 +27,8 @@ import (
 type WalletKitClient interface {
 	// ListUnspent returns a list of all utxos spendable by the wallet with
 	// a number of confirmations between the specified minimum and maximum.
-	ListUnspent(ctx context.Context, minConfs, maxConfs int32) (
-		[]*lnwallet.Utxo, error)
+	ListUnspent(ctx context.Context, minConfs, maxConfs int32,
+		opts ...ListUnspentOption) ([]*lnwallet.Utxo, error)
 
 	// LeaseOutput locks an output to the given ID for the lease time
 	// provided, preventing it from being available for any future coin
@@ -42,13 +45,24 @@ type WalletKitClient interface {
 	ReleaseOutput(ctx context.Context, lockID wtxmgr.LockID,
 		op wire.OutPoint) error
 
+	// ListLeases returns all outputs that are currently leased, by us or
+	// by any other user of the wallet.
+	ListLeases(ctx context.Context) ([]OutputLease, error)
+
+	// DeriveNextKey derives the next key of the given key family and
+	// returns it along with its locator.
 	DeriveNextKey(ctx context.Context, family int32) (
 		*keychain.KeyDescriptor, error)
 
+	// DeriveKey derives the key at the given locator.
 	DeriveKey(ctx context.Context, locator *keychain.KeyLocator) (
 		*keychain.KeyDescriptor, error)
 
-	NextAddr(ctx context.Context) (btcutil.Address, error)
+	// NextAddr returns the next unused address of the wallet. By default,
+	// a p2wkh receive address of the default account is returned, which
+	// can be changed with options.
+	NextAddr(ctx context.Context, opts ...NextAddrOption) (btcutil.Address,
+		error)
 
 	PublishTransaction(ctx context.Context, tx *wire.MsgTx,
 		label string) error
@@ -74,15 +88,33 @@ type WalletKitClient interface {
 
 	// ListAccounts retrieves all accounts belonging to the wallet by default.
 	// Optional name and addressType can be provided to filter through all of the
-	// wallet accounts and return only those matching.
+	// wallet accounts and return only those matching. Each account holds
+	// its name, address type, extended public key, master key fingerprint,
+	// derivation path, number of derived keys and whether it is watch-only.
 	ListAccounts(ctx context.Context, name string,
 		addressType walletrpc.AddressType) ([]*walletrpc.Account, error)
+
+	// ImportAccount imports an account backed by an extended public key
+	// as watch-only account. The master key fingerprint is optional and
+	// only needed for signing with an external signer. In a dry run, the
+	// account isn't imported, but the addresses it would derive are
+	// returned.
+	ImportAccount(ctx context.Context, name, xpub string,
+		masterKeyFingerprint []byte, addrType walletrpc.AddressType,
+		dryRun bool) (*ImportedAccount, error)
+
+	// ImportPublicKey imports a public key as watch-only key of the given
+	// address type, so that funds sent to its address are tracked by the
+	// wallet.
+	ImportPublicKey(ctx context.Context, pubKey *btcec.PublicKey,
+		addrType walletrpc.AddressType) error
 }
 
 type walletKitClient struct {
 	client       walletrpc.WalletKitClient
 	walletKitMac serializedMacaroon
 	timeout      time.Duration
+	params       *chaincfg.Params
 }
 
 // A compile-time constraint to ensure walletKitclient satisfies the
@@ -90,28 +122,47 @@ type walletKitClient struct {
 var _ WalletKitClient = (*walletKitClient)(nil)
 
 func newWalletKitClient(conn grpc.ClientConnInterface,
-	walletKitMac serializedMacaroon, timeout time.Duration) *walletKitClient {
+	walletKitMac serializedMacaroon, timeout time.Duration,
+	params *chaincfg.Params) *walletKitClient {
 
 	return &walletKitClient{
 		client:       walletrpc.NewWalletKitClient(conn),
 		walletKitMac: walletKitMac,
 		timeout:      timeout,
+		params:       params,
+	}
+}
+
+// ListUnspentOption is a functional option that adds a server-side filter to
+// a ListUnspent request.
+type ListUnspentOption func(r *walletrpc.ListUnspentRequest)
+
+// WithUnspentAccount is an option for only listing the utxos of the given
+// wallet account.
+func WithUnspentAccount(account string) ListUnspentOption {
+	return func(r *walletrpc.ListUnspentRequest) {
+		r.Account = account
 	}
 }
 
 // ListUnspent returns a list of all utxos spendable by the wallet with a number
 // of confirmations between the specified minimum and maximum.
 func (m *walletKitClient) ListUnspent(ctx context.Context, minConfs,
-	maxConfs int32) ([]*lnwallet.Utxo, error) {
+	maxConfs int32, opts ...ListUnspentOption) ([]*lnwallet.Utxo, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
-	rpcCtx = m.walletKitMac.WithMacaroonAuth(rpcCtx)
-	resp, err := m.client.ListUnspent(rpcCtx, &walletrpc.ListUnspentRequest{
+	req := &walletrpc.ListUnspentRequest{
 		MinConfs: minConfs,
 		MaxConfs: maxConfs,
-	})
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	rpcCtx = m.walletKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := m.client.ListUnspent(rpcCtx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -181,6 +232,25 @@ func (m *walletKitClient) LeaseOutput(ctx context.Context, lockID wtxmgr.LockID,
 	return time.Unix(int64(resp.Expiration), 0), nil
 }
 
+// NewLeaseID derives a lease ID from the name of an application, so that all
+// processes of the application use the same ID and can tell their leases from
+// the leases of other users of the wallet.
+func NewLeaseID(app string) wtxmgr.LockID {
+	return wtxmgr.LockID(sha256.Sum256([]byte(app)))
+}
+
+// OutputLease is a lease that locks an output for coin selection.
+type OutputLease struct {
+	// LockID is the ID the output was leased with.
+	LockID wtxmgr.LockID
+
+	// Outpoint is the leased output.
+	Outpoint wire.OutPoint
+
+	// Expiration is the time the lease expires.
+	Expiration time.Time
+}
+
 // ReleaseOutput unlocks an output, allowing it to be available for coin
 // selection if it remains unspent. The ID should match the one used to
 // originally lock the output.
@@ -201,6 +271,54 @@ func (m *walletKitClient) ReleaseOutput(ctx context.Context,
 	return err
 }
 
+// ListLeases returns all outputs that are currently leased.
+func (m *walletKitClient) ListLeases(ctx context.Context) ([]OutputLease,
+	error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	rpcCtx = m.walletKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := m.client.ListLeases(
+		rpcCtx, &walletrpc.ListLeasesRequest{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]OutputLease, len(resp.LockedUtxos))
+	for i, lease := range resp.LockedUtxos {
+		if lease.Outpoint == nil {
+			return nil, errors.New("lease without outpoint")
+		}
+
+		var lockID wtxmgr.LockID
+		if len(lease.Id) != len(lockID) {
+			return nil, fmt.Errorf("invalid lease id length: %v",
+				len(lease.Id))
+		}
+		copy(lockID[:], lease.Id)
+
+		hash, err := chainhash.NewHash(lease.Outpoint.TxidBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		leases[i] = OutputLease{
+			LockID: lockID,
+			Outpoint: wire.OutPoint{
+				Hash:  *hash,
+				Index: lease.Outpoint.OutputIndex,
+			},
+			Expiration: time.Unix(int64(lease.Expiration), 0),
+		}
+	}
+
+	return leases, nil
+}
+
+// DeriveNextKey derives the next key of the given key family and returns it
+// along with its locator.
 func (m *walletKitClient) DeriveNextKey(ctx context.Context, family int32) (
 	*keychain.KeyDescriptor, error) {
 
@@ -229,6 +347,7 @@ func (m *walletKitClient) DeriveNextKey(ctx context.Context, family int32) (
 	}, nil
 }
 
+// DeriveKey derives the key at the given locator.
 func (m *walletKitClient) DeriveKey(ctx context.Context, in *keychain.KeyLocator) (
 	*keychain.KeyDescriptor, error) {
 
@@ -255,19 +374,53 @@ func (m *walletKitClient) DeriveKey(ctx context.Context, in *keychain.KeyLocator
 	}, nil
 }
 
-func (m *walletKitClient) NextAddr(ctx context.Context) (
-	btcutil.Address, error) {
+// NextAddrOption is a functional option that configures which address is
+// returned by NextAddr.
+type NextAddrOption func(r *walletrpc.AddrRequest)
+
+// WithAddrAccount is an option for deriving the address from the given wallet
+// account instead of the default account.
+func WithAddrAccount(account string) NextAddrOption {
+	return func(r *walletrpc.AddrRequest) {
+		r.Account = account
+	}
+}
+
+// WithAddrType is an option for deriving an address of the given type. It
+// must match the type of the account the address is derived from.
+func WithAddrType(addrType walletrpc.AddressType) NextAddrOption {
+	return func(r *walletrpc.AddrRequest) {
+		r.Type = addrType
+	}
+}
+
+// WithChangeAddr is an option for deriving a change address instead of a
+// receive address.
+func WithChangeAddr() NextAddrOption {
+	return func(r *walletrpc.AddrRequest) {
+		r.Change = true
+	}
+}
+
+// NextAddr returns the next unused address of the wallet.
+func (m *walletKitClient) NextAddr(ctx context.Context,
+	opts ...NextAddrOption) (btcutil.Address, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
+	req := &walletrpc.AddrRequest{}
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	rpcCtx = m.walletKitMac.WithMacaroonAuth(rpcCtx)
-	resp, err := m.client.NextAddr(rpcCtx, &walletrpc.AddrRequest{})
+	resp, err := m.client.NextAddr(rpcCtx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	addr, err := btcutil.DecodeAddress(resp.Addr, nil)
+	addr, err := btcutil.DecodeAddress(resp.Addr, m.params)
 	if err != nil {
 		return nil, err
 	}
@@ -415,3 +568,91 @@ func (m *walletKitClient) ListAccounts(ctx context.Context, name string,
 
 	return resp.GetAccounts(), nil
 }
+
+// ImportedAccount is the result of an account import.
+type ImportedAccount struct {
+	// Account is the imported account.
+	Account *walletrpc.Account
+
+	// DryRunExternalAddrs holds the first external addresses of the
+	// account. It is only set for dry runs.
+	DryRunExternalAddrs []btcutil.Address
+
+	// DryRunInternalAddrs holds the first internal (change) addresses of
+	// the account. It is only set for dry runs.
+	DryRunInternalAddrs []btcutil.Address
+}
+
+// ImportAccount imports an account backed by an extended public key as
+// watch-only account.
+func (m *walletKitClient) ImportAccount(ctx context.Context, name,
+	xpub string, masterKeyFingerprint []byte,
+	addrType walletrpc.AddressType, dryRun bool) (*ImportedAccount, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	resp, err := m.client.ImportAccount(
+		m.walletKitMac.WithMacaroonAuth(rpcCtx),
+		&walletrpc.ImportAccountRequest{
+			Name:                 name,
+			ExtendedPublicKey:    xpub,
+			MasterKeyFingerprint: masterKeyFingerprint,
+			AddressType:          addrType,
+			DryRun:               dryRun,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	external, err := m.decodeAddresses(resp.DryRunExternalAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	internal, err := m.decodeAddresses(resp.DryRunInternalAddrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImportedAccount{
+		Account:             resp.Account,
+		DryRunExternalAddrs: external,
+		DryRunInternalAddrs: internal,
+	}, nil
+}
+
+// ImportPublicKey imports a public key as watch-only key of the given address
+// type.
+func (m *walletKitClient) ImportPublicKey(ctx context.Context,
+	pubKey *btcec.PublicKey, addrType walletrpc.AddressType) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.client.ImportPublicKey(
+		m.walletKitMac.WithMacaroonAuth(rpcCtx),
+		&walletrpc.ImportPublicKeyRequest{
+			PublicKey:   pubKey.SerializeCompressed(),
+			AddressType: addrType,
+		},
+	)
+	return err
+}
+
+// decodeAddresses decodes a list of addresses for our network.
+func (m *walletKitClient) decodeAddresses(addrs []string) ([]btcutil.Address,
+	error) {
+
+	decoded := make([]btcutil.Address, len(addrs))
+	for i, addr := range addrs {
+		var err error
+		decoded[i], err = btcutil.DecodeAddress(addr, m.params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return decoded, nil
+}